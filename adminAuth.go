@@ -0,0 +1,210 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// AdminAuthChallenge is the WWW-Authenticate challenge for every request that fails
+	// AdminAuth. Basic is used, rather than Bearer, since OAuth clients authenticate to
+	// the token endpoint with client_secret_basic, and the admin token is accepted as
+	// the basic auth password everywhere.
+	AdminAuthChallenge = `Basic realm="utu"`
+)
+
+// AdminAuth authenticates administrative requests.
+//
+// A request is authenticated if it presents the configured admin token, either
+// as a bearer token or as the password of HTTP basic auth, or if it presents a
+// verified TLS client certificate whose fingerprint is on the admin allowlist.
+// Merely presenting a certificate signed by the client CA grants no access.
+//
+// When the admin token is read from a file, the file is periodically reread so
+// that the token can be rotated without a restart.
+type AdminAuth struct {
+	logger *zap.Logger
+	token  atomic.Pointer[[]byte]
+
+	// clientCerts holds the SHA-256 fingerprints of the admin client certificates.
+	clientCerts map[[sha256.Size]byte]bool
+
+	tokenFile string
+	refresh   time.Duration
+	cancel    context.CancelFunc
+}
+
+func NewAdminAuth(l *zap.Logger, cli CLI, lc fx.Lifecycle) (aa *AdminAuth, err error) {
+	aa = &AdminAuth{
+		logger:      l,
+		clientCerts: make(map[[sha256.Size]byte]bool, len(cli.AdminClientCert)),
+		tokenFile:   cli.AdminTokenFile,
+		refresh:     cli.AdminTokenRefresh,
+	}
+
+	for i := 0; err == nil && i < len(cli.AdminClientCert); i++ {
+		var fingerprint [sha256.Size]byte
+		if fingerprint, err = parseFingerprint(cli.AdminClientCert[i]); err == nil {
+			aa.clientCerts[fingerprint] = true
+		}
+	}
+
+	token := []byte(cli.AdminToken)
+	if err == nil && len(aa.tokenFile) > 0 {
+		// the file takes precedence over the flag
+		token, err = readAdminToken(aa.tokenFile)
+	}
+
+	if err != nil {
+		return
+	}
+
+	aa.token.Store(&token)
+	if len(aa.tokenFile) > 0 && aa.refresh > 0 {
+		lc.Append(fx.StartStopHook(aa.start, aa.stop))
+	}
+
+	aa.logger.Info("admin auth",
+		zap.Bool("token", len(token) > 0),
+		zap.Int("clientCerts", len(aa.clientCerts)),
+		zap.String("tokenFile", aa.tokenFile),
+		zap.Duration("refresh", aa.refresh),
+	)
+
+	return
+}
+
+// parseFingerprint parses a hex SHA-256 certificate fingerprint. Colons between
+// bytes, as printed by openssl, are allowed.
+func parseFingerprint(v string) (fingerprint [sha256.Size]byte, err error) {
+	var decoded []byte
+	decoded, err = hex.DecodeString(strings.ReplaceAll(v, ":", ""))
+	switch {
+	case err != nil:
+		err = fmt.Errorf("invalid admin client certificate fingerprint [%s]: %w", v, err)
+
+	case len(decoded) != sha256.Size:
+		err = fmt.Errorf("invalid admin client certificate fingerprint [%s]: must be %d bytes", v, sha256.Size)
+
+	default:
+		copy(fingerprint[:], decoded)
+	}
+
+	return
+}
+
+// readAdminToken reads the admin token from a file, ignoring surrounding whitespace
+// such as a trailing newline.
+func readAdminToken(path string) (token []byte, err error) {
+	token, err = os.ReadFile(path)
+	if err == nil {
+		token = bytes.TrimSpace(token)
+		if len(token) == 0 {
+			err = errors.New("the admin token file is empty")
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("unable to read admin token file [%s]: %w", path, err)
+	}
+
+	return
+}
+
+// reload rereads the admin token file. If the file can't be read, the current
+// token stays in effect.
+func (aa *AdminAuth) reload() {
+	token, err := readAdminToken(aa.tokenFile)
+	switch {
+	case err != nil:
+		aa.logger.Warn("unable to reload admin token, keeping the current token", zap.Error(err))
+
+	case !bytes.Equal(token, *aa.token.Load()):
+		aa.token.Store(&token)
+		aa.logger.Info("reloaded admin token", zap.String("tokenFile", aa.tokenFile))
+	}
+}
+
+// start begins rereading the admin token file on the refresh interval.
+func (aa *AdminAuth) start() {
+	var ctx context.Context
+	ctx, aa.cancel = context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(aa.refresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+				aa.reload()
+			}
+		}
+	}()
+}
+
+func (aa *AdminAuth) stop() {
+	aa.cancel()
+}
+
+// checkToken performs a constant time comparison of the given value against the admin token.
+func (aa *AdminAuth) checkToken(value string) bool {
+	token := *aa.token.Load()
+	return len(token) > 0 && subtle.ConstantTimeCompare(token, []byte(value)) == 1
+}
+
+// Authenticate tests if the given request carries valid admin credentials.
+func (aa *AdminAuth) Authenticate(request *http.Request) bool {
+	if request.TLS != nil && len(request.TLS.VerifiedChains) > 0 && len(request.TLS.VerifiedChains[0]) > 0 {
+		if aa.clientCerts[sha256.Sum256(request.TLS.VerifiedChains[0][0].Raw)] {
+			return true
+		}
+	}
+
+	if _, password, ok := request.BasicAuth(); ok {
+		return aa.checkToken(password)
+	}
+
+	if token, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer "); ok {
+		return aa.checkToken(strings.TrimSpace(token))
+	}
+
+	return false
+}
+
+// Then decorates the given handler so that only authenticated requests reach it.
+// Unauthenticated requests receive a 401.
+func (aa *AdminAuth) Then(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if aa.Authenticate(request) {
+			next.ServeHTTP(response, request)
+		} else {
+			response.Header().Set("WWW-Authenticate", AdminAuthChallenge)
+			response.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+}
+
+func ProvideAdminAuth() fx.Option {
+	return fx.Provide(
+		NewAdminAuth,
+	)
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx/fxtest"
+	"go.uber.org/zap"
+)
+
+// bearerRequest creates a request that presents the given bearer token.
+func bearerRequest(token string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	return request
+}
+
+// writeAdminToken writes the given contents to the admin token file at path.
+func writeAdminToken(t *testing.T, path, contents string) {
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+}
+
+func TestNewAdminAuthTokenFile(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// tokenFile indicates whether an admin token file with the given
+		// contents is configured.
+		tokenFile bool
+		contents  string
+		accepted  []string
+		rejected  []string
+		expectErr bool
+	}{
+		{
+			description: "flag",
+			args:        []string{"--admin-token=flag"},
+			accepted:    []string{"flag"},
+			rejected:    []string{"file"},
+		},
+		{
+			description: "file takes precedence",
+			args:        []string{"--admin-token=flag"},
+			tokenFile:   true,
+			contents:    "file",
+			accepted:    []string{"file"},
+			rejected:    []string{"flag"},
+		},
+		{
+			description: "surrounding whitespace",
+			tokenFile:   true,
+			contents:    "  file\n",
+			accepted:    []string{"file"},
+		},
+		{
+			description: "empty file",
+			args:        []string{"--admin-token=flag"},
+			tokenFile:   true,
+			contents:    " \n",
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			args := tc.args
+			if tc.tokenFile {
+				path := filepath.Join(t.TempDir(), "token")
+				writeAdminToken(t, path, tc.contents)
+				args = append(args, "--admin-token-file="+path)
+			}
+
+			aa, err := NewAdminAuth(zap.NewNop(), newTestCLI(t, args...), fxtest.NewLifecycle(t))
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+
+			require.NoError(t, err)
+			for _, token := range tc.accepted {
+				assert.True(aa.Authenticate(bearerRequest(token)), token)
+			}
+
+			for _, token := range tc.rejected {
+				assert.False(aa.Authenticate(bearerRequest(token)), token)
+			}
+		})
+	}
+}
+
+func TestAdminAuthReload(t *testing.T) {
+	tests := []struct {
+		description string
+
+		// contents replace the admin token file after startup.
+		contents string
+		accepted string
+		rejected string
+	}{
+		{
+			description: "rotated token",
+			contents:    "second\n",
+			accepted:    "second",
+			rejected:    "first",
+		},
+		{
+			description: "emptied file keeps the current token",
+			contents:    "",
+			accepted:    "first",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "token")
+			writeAdminToken(t, path, "first")
+
+			var aa *AdminAuth
+			startTestApp(t, []string{"--admin-token-file=" + path, "--admin-token-refresh=10ms"}, &aa)
+			require.True(t, aa.Authenticate(bearerRequest("first")))
+
+			writeAdminToken(t, path, tc.contents)
+			if len(tc.rejected) > 0 {
+				assert.Eventually(t, func() bool {
+					return !aa.Authenticate(bearerRequest(tc.rejected))
+				}, 5*time.Second, 5*time.Millisecond)
+			} else {
+				// give the reload a few chances to run
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			assert.True(t, aa.Authenticate(bearerRequest(tc.accepted)))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Algorithms describes the signing algorithms and key types this issuer uses.
+type Algorithms struct {
+	SigningAlgorithms []string `json:"signing_algorithms"`
+	KeyTypes          []string `json:"key_types"`
+}
+
+// AlgorithmsHandlerIn defines the dependencies necessary to create an AlgorithmsHandler.
+type AlgorithmsHandlerIn struct {
+	fx.In
+
+	Logger        *zap.Logger
+	KeyGenerator  *KeyGenerator
+	KeyAccessor   *KeyAccessor
+	KeyStore      KeyStore
+	MultiSignKeys MultiSignKeys `optional:"true"`
+	SignKey       *SignKey      `optional:"true"`
+	IssueKeys     IssueKeys     `optional:"true"`
+}
+
+// AlgorithmsHandler advertises the active signing algorithms and key types, so that
+// clients need not parse keys to discover them.
+type AlgorithmsHandler struct {
+	logger     *zap.Logger
+	generators []*KeyGenerator
+	accessors  []*KeyAccessor
+	keyStore   KeyStore
+}
+
+func NewAlgorithmsHandler(in AlgorithmsHandlerIn) *AlgorithmsHandler {
+	ah := &AlgorithmsHandler{
+		logger:     in.Logger,
+		generators: []*KeyGenerator{in.KeyGenerator},
+		accessors:  []*KeyAccessor{in.KeyAccessor},
+		keyStore:   in.KeyStore,
+	}
+
+	for _, ak := range in.MultiSignKeys {
+		ah.generators = append(ah.generators, ak.KeyGenerator)
+		ah.accessors = append(ah.accessors, ak.KeyAccessor)
+	}
+
+	if in.SignKey != nil {
+		ah.generators = append(ah.generators, in.SignKey.KeyGenerator)
+		ah.accessors = append(ah.accessors, in.SignKey.KeyAccessor)
+	}
+
+	for _, ak := range in.IssueKeys {
+		ah.generators = append(ah.generators, ak.KeyGenerator)
+		ah.accessors = append(ah.accessors, ak.KeyAccessor)
+	}
+
+	return ah
+}
+
+// algorithms derives the Algorithms from the configured generators, the current keys,
+// and the published key set. Keys in the key set that are still valid may use
+// algorithms no longer configured, e.g. after a restart with a new key type.
+func (ah *AlgorithmsHandler) algorithms() (a Algorithms, err error) {
+	var keys []Key
+	for _, ka := range ah.accessors {
+		pool, _ := ka.LoadAll()
+		keys = append(keys, pool...)
+	}
+
+	var published []Key
+	published, err = ah.keyStore.LoadAll()
+	keys = append(keys, published...)
+
+	var algs, ktys []string
+	for _, kg := range ah.generators {
+		algs = append(algs, kg.Alg().String())
+	}
+
+	for _, k := range keys {
+		if k.ForEncryption() {
+			continue
+		}
+
+		if k.Alg != nil {
+			algs = append(algs, k.Alg.String())
+		}
+
+		if k.Key != nil {
+			ktys = append(ktys, k.Key.KeyType().String())
+		}
+	}
+
+	slices.Sort(algs)
+	slices.Sort(ktys)
+	a.SigningAlgorithms = slices.Compact(algs)
+	a.KeyTypes = slices.Compact(ktys)
+	return
+}
+
+func (ah *AlgorithmsHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	var data []byte
+	a, err := ah.algorithms()
+	if err == nil {
+		data, err = json.Marshal(a)
+	}
+
+	if err == nil {
+		writeBody(response, "application/json", data)
+	} else {
+		ah.logger.Error("unable to render algorithms", zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func ProvideAlgorithms() fx.Option {
+	return fx.Provide(
+		NewAlgorithmsHandler,
+	)
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlgorithmsHandler(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expected    Algorithms
+	}{
+		{
+			description: "default",
+			expected: Algorithms{
+				SigningAlgorithms: []string{"ES256"},
+				KeyTypes:          []string{"EC"},
+			},
+		},
+		{
+			description: "RSA",
+			args:        []string{"--key-type=RSA"},
+			expected: Algorithms{
+				SigningAlgorithms: []string{"RS256"},
+				KeyTypes:          []string{"RSA"},
+			},
+		},
+		{
+			description: "multi-sign",
+			args:        []string{"--multi-sign=RSA"},
+			expected: Algorithms{
+				SigningAlgorithms: []string{"ES256", "RS256"},
+				KeyTypes:          []string{"EC", "RSA"},
+			},
+		},
+		{
+			description: "separate sign key",
+			args:        []string{"--sign-key-type=OKP"},
+			expected: Algorithms{
+				SigningAlgorithms: []string{"ES256", "EdDSA"},
+				KeyTypes:          []string{"EC", "OKP"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newTestServer(t, tc.args...)
+			response := serve(h, http.MethodGet, "/algorithms", nil)
+			require.Equal(t, http.StatusOK, response.Code)
+			assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+			var a Algorithms
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &a))
+			assert.Equal(t, tc.expected, a)
+		})
+	}
+}
+
+func TestAlgorithmsHandlerPublishedKeys(t *testing.T) {
+	// a key that is still published, but whose type is no longer configured
+	var (
+		s  *http.Server
+		ks KeyStore
+	)
+
+	startTestApp(t, nil, &s, &ks)
+	require.NoError(t, ks.Store(newTestKey(t, "--key-type=RSA")))
+
+	response := serve(s.Handler, http.MethodGet, "/algorithms", nil)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var a Algorithms
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &a))
+	assert.Equal(t, Algorithms{
+		SigningAlgorithms: []string{"ES256", "RS256"},
+		KeyTypes:          []string{"EC", "RSA"},
+	}, a)
+}
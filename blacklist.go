@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// BlacklistStore records identifiers, such as revoked jti or sid values and
+// already-used nonces, until they expire. Implementations may be shared
+// across replicas.
+type BlacklistStore interface {
+	// Add records the given id until the given expiry. Adding an id that
+	// has already expired has no effect.
+	Add(id string, expires time.Time) error
+
+	// Contains tests if the given id has been added and has not yet expired.
+	Contains(id string) (bool, error)
+
+	// AddIfAbsent atomically records the given id until the given expiry, unless the id
+	// has already been added and has not yet expired. This method returns true if the id
+	// was added. Single-use values, such as nonces, use this method so that concurrent
+	// uses cannot both succeed.
+	AddIfAbsent(id string, expires time.Time) (bool, error)
+
+	// AddedAt returns when the given id was last added, to the second. If the id
+	// hasn't been added or has expired, ok is false.
+	AddedAt(id string) (added time.Time, ok bool, err error)
+}
+
+// blacklistEntry is an id recorded by an InMemoryBlacklistStore.
+type blacklistEntry struct {
+	added   time.Time
+	expires time.Time
+}
+
+// InMemoryBlacklistStore is a BlacklistStore that uses a simple map guarded by
+// a read/write mutex. Instances must be created with NewInMemoryBlacklistStore.
+type InMemoryBlacklistStore struct {
+	lock sync.RWMutex
+	now  func() time.Time
+	ids  map[string]blacklistEntry
+}
+
+func NewInMemoryBlacklistStore() *InMemoryBlacklistStore {
+	return &InMemoryBlacklistStore{
+		now: time.Now,
+		ids: make(map[string]blacklistEntry),
+	}
+}
+
+// unsafeSweep removes all expired ids. This method must be executed under the write lock.
+func (s *InMemoryBlacklistStore) unsafeSweep(now time.Time) {
+	for id, entry := range s.ids {
+		if !now.Before(entry.expires) {
+			delete(s.ids, id)
+		}
+	}
+}
+
+func (s *InMemoryBlacklistStore) Add(id string, expires time.Time) error {
+	now := s.now()
+	s.lock.Lock()
+	s.unsafeSweep(now)
+	if now.Before(expires) {
+		s.ids[id] = blacklistEntry{
+			added:   now.Truncate(time.Second),
+			expires: expires,
+		}
+	}
+
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *InMemoryBlacklistStore) AddIfAbsent(id string, expires time.Time) (added bool, err error) {
+	now := s.now()
+	s.lock.Lock()
+	s.unsafeSweep(now)
+	if _, exists := s.ids[id]; !exists {
+		added = true
+		if now.Before(expires) {
+			s.ids[id] = blacklistEntry{
+				added:   now.Truncate(time.Second),
+				expires: expires,
+			}
+		}
+	}
+
+	s.lock.Unlock()
+	return
+}
+
+func (s *InMemoryBlacklistStore) Contains(id string) (bool, error) {
+	_, ok, err := s.AddedAt(id)
+	return ok, err
+}
+
+func (s *InMemoryBlacklistStore) AddedAt(id string) (time.Time, bool, error) {
+	s.lock.RLock()
+	entry, exists := s.ids[id]
+	s.lock.RUnlock()
+
+	if exists && s.now().Before(entry.expires) {
+		return entry.added, true, nil
+	}
+
+	return time.Time{}, false, nil
+}
+
+// NewBlacklistStore creates the BlacklistStore selected on the command line.
+func NewBlacklistStore(l *zap.Logger, cli CLI) (bs BlacklistStore, err error) {
+	switch cli.Blacklist {
+	case "memory":
+		bs = NewInMemoryBlacklistStore()
+
+	case "redis":
+		bs = NewRedisBlacklistStore(cli.RedisAddress, cli.RedisPrefix)
+
+	default:
+		err = fmt.Errorf("unsupported blacklist store: %s", cli.Blacklist)
+	}
+
+	if err == nil {
+		l.Info("blacklist store", zap.String("type", cli.Blacklist))
+	}
+
+	return
+}
+
+func ProvideBlacklistStore() fx.Option {
+	return fx.Provide(
+		NewBlacklistStore,
+	)
+}
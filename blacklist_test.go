@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestInMemoryBlacklistStoreContains(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		description string
+		expires     time.Duration
+		elapsed     time.Duration
+		expected    bool
+	}{
+		{
+			description: "not yet expired",
+			expires:     time.Minute,
+			elapsed:     59 * time.Second,
+			expected:    true,
+		},
+		{
+			description: "expired",
+			expires:     time.Minute,
+			elapsed:     time.Minute,
+		},
+		{
+			description: "already expired when added",
+			expires:     -time.Second,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			now := start
+			s := NewInMemoryBlacklistStore()
+			s.now = func() time.Time { return now }
+
+			require.NoError(t, s.Add("id", start.Add(tc.expires)))
+			now = start.Add(tc.elapsed)
+
+			contains, err := s.Contains("id")
+			assert.NoError(err)
+			assert.Equal(tc.expected, contains)
+
+			contains, err = s.Contains("other")
+			assert.NoError(err)
+			assert.False(contains)
+
+			// adding any id sweeps expired ids
+			require.NoError(t, s.Add("sweep", now.Add(time.Hour)))
+			_, exists := s.ids["id"]
+			assert.Equal(tc.expected, exists)
+		})
+	}
+}
+
+func TestRedisBlacklistStoreExpired(t *testing.T) {
+	// an unreachable address proves that expired ids never reach redis
+	s := NewRedisBlacklistStore("127.0.0.1:1", "test:")
+	defer s.client.Close()
+
+	assert.NoError(t, s.Add("id", time.Now().Add(-time.Second)))
+
+	_, err := s.Contains("id")
+	assert.Error(t, err)
+}
+
+func TestNewBlacklistStore(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expected    any
+	}{
+		{
+			description: "memory",
+			expected:    (*InMemoryBlacklistStore)(nil),
+		},
+		{
+			description: "redis",
+			args:        []string{"--blacklist=redis", "--redis-address=127.0.0.1:1"},
+			expected:    (*RedisBlacklistStore)(nil),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			bs, err := NewBlacklistStore(zap.NewNop(), newTestCLI(t, tc.args...))
+			require.NoError(t, err)
+			assert.IsType(t, tc.expected, bs)
+		})
+	}
+}
+
+func TestInMemoryBlacklistStoreAddedAt(t *testing.T) {
+	start := time.Unix(1700000000, 0).Add(500 * time.Millisecond)
+	tests := []struct {
+		description   string
+		elapsed       time.Duration
+		expectedAdded time.Time
+		expectedOK    bool
+	}{
+		{
+			description:   "revoked",
+			elapsed:       time.Second,
+			expectedAdded: time.Unix(1700000000, 0),
+			expectedOK:    true,
+		},
+		{
+			description: "expired",
+			elapsed:     time.Hour,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			now := start
+			s := NewInMemoryBlacklistStore()
+			s.now = func() time.Time { return now }
+
+			require.NoError(t, s.Add("id", start.Add(time.Minute)))
+			now = start.Add(tc.elapsed)
+
+			added, ok, err := s.AddedAt("id")
+			assert.NoError(err)
+			assert.Equal(tc.expectedOK, ok)
+			assert.True(tc.expectedAdded.Equal(added), "added at %s", added)
+		})
+	}
+}
+
+func TestInMemoryBlacklistStoreAddIfAbsent(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		description string
+
+		// elapsed is the time between the first and second add.
+		elapsed       time.Duration
+		expectedAdded bool
+	}{
+		{
+			description: "still present",
+			elapsed:     59 * time.Second,
+		},
+		{
+			description:   "expired",
+			elapsed:       time.Minute,
+			expectedAdded: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			now := start
+			s := NewInMemoryBlacklistStore()
+			s.now = func() time.Time { return now }
+
+			added, err := s.AddIfAbsent("id", start.Add(time.Minute))
+			require.NoError(t, err)
+			assert.True(added)
+
+			now = start.Add(tc.elapsed)
+			added, err = s.AddIfAbsent("id", now.Add(time.Minute))
+			require.NoError(t, err)
+			assert.Equal(tc.expectedAdded, added)
+		})
+	}
+}
+
+func TestInMemoryBlacklistStoreAddIfAbsentConcurrent(t *testing.T) {
+	const workers = 16
+	var (
+		s       = NewInMemoryBlacklistStore()
+		wg      sync.WaitGroup
+		added   atomic.Int32
+		expires = time.Now().Add(time.Minute)
+	)
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, err := s.AddIfAbsent("id", expires); assert.NoError(t, err) && ok {
+				added.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, int32(1), added.Load())
+}
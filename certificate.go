@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+
+	"github.com/lestrrat-go/jwx/v3/cert"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+)
+
+// serialNumberBits is the size of the random serial numbers of self-signed certificates.
+const serialNumberBits = 128
+
+// newSelfSignedCertificate creates a self-signed, DER-encoded x509 certificate for
+// the given key. The certificate is valid over the lifetime of the key.
+func newSelfSignedCertificate(random io.Reader, k Key, signer crypto.Signer) (der []byte, err error) {
+	var serialNumber *big.Int
+	serialNumber, err = randomSerialNumber(random)
+	if err == nil {
+		template := &x509.Certificate{
+			SerialNumber:          serialNumber,
+			Subject:               pkix.Name{CommonName: k.KID},
+			NotBefore:             k.Created,
+			NotAfter:              k.Expires,
+			KeyUsage:              x509.KeyUsageDigitalSignature,
+			BasicConstraintsValid: true,
+		}
+
+		der, err = x509.CreateCertificate(random, template, template, signer.Public(), signer)
+	}
+
+	return
+}
+
+// randomSerialNumber produces a random, non-negative certificate serial number.
+func randomSerialNumber(random io.Reader) (*big.Int, error) {
+	return rand.Int(random, new(big.Int).Lsh(big.NewInt(1), serialNumberBits))
+}
+
+// setSelfSignedCertificate generates a self-signed certificate for the given key and
+// embeds it as the key's x5c member. Keys that cannot sign certificates, such as
+// symmetric keys, are left unchanged.
+func setSelfSignedCertificate(random io.Reader, k Key, raw any) (err error) {
+	signer, ok := raw.(crypto.Signer)
+	if !ok {
+		return
+	}
+
+	var der []byte
+	der, err = newSelfSignedCertificate(random, k, signer)
+	if err == nil {
+		var chain cert.Chain
+		chain.AddString(base64.StdEncoding.EncodeToString(der))
+		err = k.Key.Set(jwk.X509CertChainKey, &chain)
+	}
+
+	return
+}
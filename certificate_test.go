@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/cert"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfSignedCertificate(t *testing.T) {
+	tests := []struct {
+		description string
+		keyType     string
+		expectX5C   bool
+	}{
+		{description: "EC", keyType: "EC", expectX5C: true},
+		{description: "RSA", keyType: "RSA", expectX5C: true},
+		{description: "OKP", keyType: "OKP", expectX5C: true},
+		{description: "oct", keyType: "oct"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			k := newTestKey(t, "--self-signed-x5c", "--key-type="+tc.keyType)
+
+			var chain *cert.Chain
+			err := k.Key.Get(jwk.X509CertChainKey, &chain)
+			if !tc.expectX5C {
+				assert.Error(err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, 1, chain.Len())
+
+			encoded, _ := chain.Get(0)
+			der, err := base64.StdEncoding.DecodeString(string(encoded))
+			require.NoError(t, err)
+
+			c, err := x509.ParseCertificate(der)
+			require.NoError(t, err)
+			assert.Equal(k.KID, c.Subject.CommonName)
+			assert.True(k.Created.Truncate(time.Second).Equal(c.NotBefore), "NotBefore %s", c.NotBefore)
+			assert.True(k.Expires.Truncate(time.Second).Equal(c.NotAfter), "NotAfter %s", c.NotAfter)
+			assert.NoError(c.CheckSignature(c.SignatureAlgorithm, c.RawTBSCertificate, c.Signature))
+
+			// the certificate certifies the published key
+			published, err := k.PublicJWK()
+			require.NoError(t, err)
+			certified, err := jwk.Import(c.PublicKey)
+			require.NoError(t, err)
+			assert.True(jwk.Equal(published, certified))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+var (
+	// ErrInvalidClaims indicates that the assembled claims of a token did not
+	// validate against the configured claims schema.
+	ErrInvalidClaims = errors.New("the token claims do not satisfy the claims schema")
+)
+
+// ClaimsSchema validates the complete claim set of a token, as it will be signed,
+// against a JSON schema.
+type ClaimsSchema struct {
+	schema *jsonschema.Schema
+}
+
+// NewClaimsSchema compiles the JSON schema in the given file.
+func NewClaimsSchema(path string) (cs *ClaimsSchema, err error) {
+	var schema *jsonschema.Schema
+	schema, err = jsonschema.NewCompiler().Compile(path)
+	if err == nil {
+		cs = &ClaimsSchema{schema: schema}
+	} else {
+		err = fmt.Errorf("unable to compile claims schema [%s]: %w", path, err)
+	}
+
+	return
+}
+
+// Validate checks the claims of the given token. Any error returned by this
+// method wraps ErrInvalidClaims.
+func (cs *ClaimsSchema) Validate(t jwt.Token) (err error) {
+	var (
+		data   []byte
+		claims any
+	)
+
+	data, err = json.Marshal(t)
+	if err == nil {
+		claims, err = jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	}
+
+	if err == nil {
+		err = cs.schema.Validate(claims)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidClaims, err)
+	}
+
+	return
+}
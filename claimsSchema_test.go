@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClaimsSchema requires a tenant claim that is a short lowercase string.
+const testClaimsSchema = `{
+	"type": "object",
+	"required": ["iss", "tenant"],
+	"properties": {
+		"tenant": {"type": "string", "pattern": "^[a-z]{1,8}$"}
+	}
+}`
+
+// writeClaimsSchema writes the given JSON schema to a temporary file and returns its path.
+func writeClaimsSchema(t *testing.T, schema string) string {
+	path := filepath.Join(t.TempDir(), "claims.json")
+	require.NoError(t, os.WriteFile(path, []byte(schema), 0o600))
+	return path
+}
+
+func TestNewClaimsSchema(t *testing.T) {
+	tests := []struct {
+		description string
+		schema      string
+		expectErr   bool
+	}{
+		{
+			description: "valid schema",
+			schema:      testClaimsSchema,
+		},
+		{
+			description: "malformed JSON",
+			schema:      `{"type":`,
+			expectErr:   true,
+		},
+		{
+			description: "invalid schema",
+			schema:      `{"type": 42}`,
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			cs, err := NewClaimsSchema(writeClaimsSchema(t, tc.schema))
+			if tc.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, cs)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, cs)
+			}
+		})
+	}
+}
+
+func TestIssueHandlerClaimsSchema(t *testing.T) {
+	tests := []struct {
+		description    string
+		headers        []string
+		expectedStatus int
+	}{
+		{
+			description:    "satisfies the schema",
+			headers:        []string{"X-Tenant: acme"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "missing required claim",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			description:    "claim does not match",
+			headers:        []string{"X-Tenant: NOT-A-TENANT"},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	h := newTestServer(t,
+		"--admin-token="+testAdminToken,
+		"--claims-schema="+writeClaimsSchema(t, testClaimsSchema),
+		"--header-claim=X-Tenant=tenant",
+	)
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			response := serve(h, http.MethodPost, "/issue", strings.NewReader(""), tc.headers...)
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal("acme", tokenClaims(t, response.Body.String())["tenant"])
+			} else {
+				assert.Contains(response.Body.String(), ErrInvalidClaims.Error())
+			}
+
+			// the token endpoint reports the same failure as an OAuth error
+			response = serve(h, http.MethodPost, "/token", strings.NewReader("grant_type=client_credentials"), append(tc.headers, testAdminAuth)...)
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(http.StatusOK, response.Code)
+			} else {
+				require.Equal(t, http.StatusBadRequest, response.Code)
+
+				var oe OAuthError
+				require.NoError(t, json.Unmarshal(response.Body.Bytes(), &oe))
+				assert.Equal("invalid_request", oe.Error)
+			}
+		})
+	}
+}
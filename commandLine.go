@@ -4,27 +4,318 @@
 package main
 
 import (
+	"fmt"
+	"net/url"
+	"slices"
 	"time"
 
 	"github.com/alecthomas/kong"
 )
 
+const (
+	// MaxExpires is the longest token lifetime that may be configured.
+	MaxExpires = 366 * 24 * time.Hour
+
+	// MaxKeyRotate is the longest key rotation interval that may be configured.
+	MaxKeyRotate = 366 * 24 * time.Hour
+)
+
 type CLI struct {
 	Debug   bool   `help:"turns on debugging"`
 	Network string `default:"tcp" enum:"tcp,tcp4,tcp6" help:"the network for the server to bind on"`
 	Address string `default:":8080" help:"the bind address for the server"`
 
+	TLSCertFile string `name:"tls-cert-file" optional:"" type:"existingfile" help:"the PEM certificate file for serving HTTPS.  requires --tls-key-file."`
+	TLSKeyFile  string `name:"tls-key-file" optional:"" type:"existingfile" help:"the PEM private key file for serving HTTPS.  requires --tls-cert-file."`
+
+	TLSClientCAFile string `name:"tls-client-ca-file" optional:"" type:"existingfile" help:"the PEM CA certificates that verify client certificates.  when set, clients may present certificates (mTLS), which bind tokens and, with --admin-client-cert, authenticate administrative requests.  requires --tls-cert-file."`
+
+	BindClientCert bool `help:"binds tokens issued to clients that present a certificate to that certificate, via the RFC 8705 cnf x5t#S256 claim"`
+
+	RouteTimeout map[string]time.Duration `optional:"" help:"per-route request timeouts, keyed by route path, e.g. /sign=10s or /keys=1s.  requests that exceed their route's timeout receive a 503."`
+
+	TrailingSlash string `default:"serve" enum:"serve,redirect" help:"whether the trailing-slash variants of routes, such as /keys/, are served like the routes themselves or redirected to them"`
+
+	ExternalURL string `help:"the externally visible base URL of this server, e.g. https://utu.example.com.  when set, signed tokens carry a jku header that points at this server's key set.  must be set behind a proxy for DPoP proofs, whose htu is otherwise derived from each request, to validate."`
+
+	MetadataMaxAge time.Duration `default:"1h" help:"the max-age that clients may cache discovery documents, such as /.well-known/oauth-authorization-server, for.  each document also carries an ETag for conditional requests.  zero requires clients to revalidate every time."`
+
+	AdminToken string `help:"the shared secret that authenticates administrative and OAuth client requests.  when unset, token-authenticated requests are always rejected."`
+
+	AdminTokenFile    string        `optional:"" type:"existingfile" help:"a file holding the admin token, which keeps the token out of process listings.  surrounding whitespace is ignored, and this takes precedence over --admin-token."`
+	AdminTokenRefresh time.Duration `default:"30s" help:"how often the --admin-token-file is reread, so that the token can be rotated without a restart.  zero disables rereading."`
+
+	AdminClientCert []string `optional:"" help:"the SHA-256 fingerprint, in hex with optional colons, of a TLS client certificate that authenticates administrative and OAuth client requests.  may be repeated.  a verified client certificate that isn't listed grants no access.  requires --tls-client-ca-file."`
+
 	Type     string            `short:"t" default:"JWT" help:"the type of JWT tokens to issue.  The recommended value is JWT, in all caps, which is the default."`
 	Issuer   string            `short:"i" default:"utu" help:"the issuer for issued JWTs (iss)"`
 	Subject  string            `short:"s" default:"utu" help:"the subject for issued JWTs (sub)"`
 	Expires  time.Duration     `short:"e" default:"15m" help:"how long until issued JWTs expire.  used to compute the exp claim."`
 	Audience []string          `short:"a" optional:"" help:"the audience (aud) for issued JWTs"`
 	Claims   map[string]string `short:"c" optional:"" help:"the set of arbitrary claims for issued JWTs"`
+	ClaimMap map[string]string `optional:"" help:"renames claims in issued JWTs, e.g. sub=user_id.  applies to both registered and custom claims."`
+
+	IATSkew time.Duration `name:"iat-skew" default:"0s" help:"how far to backdate the iat claim, so that clients whose clocks run behind don't reject fresh tokens.  exp is still computed from the actual time of issue."`
+
+	MaxRequestExpires time.Duration `name:"max-expires" default:"1h" help:"the longest lifetime a request may ask for with the expires_in parameter.  longer requests are clamped to this value."`
+
+	ScopeExpires map[string]time.Duration `optional:"" help:"token lifetimes for scopes, e.g. admin=5m.  a token requesting several of these scopes gets the shortest lifetime."`
+
+	IssuePostOnly bool `help:"restricts /issue to POST, so that issue parameters never appear in URLs.  GET requests are rejected with 405, and query parameters of POST requests are ignored."`
+
+	ResourceAudience map[string]string `optional:"" help:"maps RFC 8707 resource parameters onto audiences, e.g. https://api.example.com=api.  requests for any other resource are rejected."`
+
+	AudOverrideMode string `default:"replace" enum:"replace,merge" help:"whether aud parameters replace the configured --audience or are merged with it"`
+
+	MaxAudiences int `default:"10" help:"the maximum number of aud parameters a single issue request may supply"`
+
+	MaxCustomClaims int `default:"16" help:"the maximum number of custom claim parameters, as name=value, a single issue request may supply.  registered claims don't count toward this limit."`
+
+	MaxClaimValueBytes int `default:"0" help:"the maximum size, in bytes, of a single claim value supplied by an issue request, either as a claim parameter or through --header-claim.  larger values are rejected with 400.  zero disables this limit."`
+
+	ClaimsAllowlist []string `optional:"" help:"the custom claims that issue requests may supply when --strict-claims is set"`
+	StrictClaims    bool     `help:"rejects issue requests that supply custom claims missing from --claims-allowlist with 400.  otherwise, any custom claim is accepted."`
+
+	OIDC bool `name:"oidc" help:"issues OIDC id_tokens from /issue, which then requires a nonce parameter.  a nonce parameter is always echoed in the nonce claim."`
+
+	RequireAudience bool `help:"rejects issue requests with a 422, rather than issuing a token without an aud, when neither the request nor --audience supplies an audience"`
+
+	MinimalToken bool `help:"omits the iss and aud claims from issued JWTs, for the smallest possible internal-only tokens.  such tokens are not OIDC compliant."`
+
+	RequireURLIssuer bool `name:"require-url-issuer" help:"requires the --issuer to be an absolute http or https URL, as OIDC expects"`
+
+	InheritClaim []string `optional:"" help:"the claims that issued tokens inherit from a subject_token parameter, which must verify against the published key set.  registered claims can't be inherited.  when unset, subject_token is rejected."`
+
+	JTICollisionWindow int `name:"jti-collision-window" default:"0" help:"the number of recently issued jtis remembered so that a colliding jti is regenerated before it is issued.  zero disables collision detection."`
+
+	GenerateSID bool `name:"generate-sid" help:"generates a unique sid claim for each issued token that doesn't request one with the sid parameter"`
+
+	ClientIPClaim string `name:"client-ip-claim" optional:"" help:"the claim that records the address of the client that requested the token.  when unset, no such claim is issued."`
+
+	TrustedProxies []string `optional:"" help:"the CIDRs of proxies whose X-Forwarded-* headers are honored, e.g. 10.0.0.0/8.  forwarding headers from any other peer are ignored."`
+
+	ClaimsSchema string `optional:"" type:"existingfile" help:"a JSON schema file that the complete claim set of every issued token must satisfy.  tokens that don't are rejected with 422."`
+
+	DeprecatedClaim     map[string]string `optional:"" help:"deprecated claim names and their replacements, e.g. user=sub, or grp= for no replacement.  requests for these claims get a Warning header, or are rejected, depending on --deprecated-claim-mode."`
+	DeprecatedClaimMode string            `default:"warn" enum:"warn,reject" help:"whether requests for deprecated claims are issued with a Warning header or rejected with 400"`
+
+	MaxTokenBytes     int    `default:"0" help:"the size budget, in bytes, of a token as sent to the client, which keeps tokens within the header limits of gateways.  zero disables the budget."`
+	MaxTokenBytesMode string `default:"warn" enum:"warn,reject" help:"whether tokens over --max-token-bytes are issued with a Warning header or rejected with 400"`
+
+	HeaderClaim map[string]string `optional:"" help:"copies request headers into claims, e.g. X-Tenant-ID=tenant.  only headers listed here are ever copied."`
+
+	Recipients string `optional:"" type:"existingfile" help:"a JWK set file of recipient public keys.  when set, issued tokens are encrypted to every recipient as a JWE JSON serialization."`
+
+	RandomFile string `optional:"" type:"existingfile" help:"a file, such as a hardware RNG device, that supplies randomness for ids and keys instead of the system random source.  POST /admin/random reopens this file at runtime."`
 
 	KeyRotate time.Duration `default:"24h" help:"how often the current signing key is rotated."`
-	KeyType   string        `enum:"EC,RSA" default:"EC" help:"the key type (kty) used to sign and verify JWTs"`
-	KeySize   int           `default:"2048" help:"the bit length for keys. used only for RSA keys."`
+	KeyType   string        `enum:"EC,RSA,OKP,oct" default:"EC" help:"the key type (kty) used to sign and verify JWTs.  OKP keys are Ed25519 keys that sign with EdDSA.  oct keys are secrets that are never published, so their tokens can't be verified from /keys or /verify."`
+	KeySize   int           `default:"2048" help:"the bit length for keys. used only for RSA and oct keys."`
 	KeyCurve  string        `default:"P-256" enum:"P-256,P-384,P-521" help:"the elliptic curve for key generation. used only for EC keys."`
+	HMACAlg   string        `name:"hmac-alg" default:"HS256" enum:"HS256,HS384,HS512" help:"the HMAC algorithm for symmetric keys. used only for oct keys."`
+
+	RotationOverdueFactor float64 `default:"2" help:"the multiple of --key-rotate after which a key rotation is considered overdue.  overdue rotations are reported via /metrics and fail /readyz.  zero disables overdue detection."`
+
+	HealthAttestation time.Duration `default:"0s" help:"the lifetime of a signed token, sent in the X-Health-Attestation header of /readyz responses, that attests the reported status.  gateways verify it against /keys so that a healthy status can't be spoofed.  the token's aud is utu-health, and each token is reused for half its lifetime.  zero disables attestation."`
+
+	RotationOverdueWebhook []string `optional:"" help:"URLs that are POSTed an alert, with the last rotation time and current kid, when key rotation becomes overdue.  an alert fires once each time rotation becomes overdue."`
+
+	MissingCurrentKey string `default:"rotate" enum:"rotate,fail,ignore" help:"what signing does when the current key has been deleted from the key store, so that its tokens couldn't be verified against the published key set.  rotate rotates to a new current key first, fail refuses to sign, and ignore signs without checking the key store."`
+
+	SigningPoolSize int `default:"1" help:"the number of current signing keys.  signing selects keys from this pool in round-robin order, and each rotation replaces the entire pool."`
+
+	KeyGenerationWorkers int `default:"1" help:"the number of signing keys generated in parallel when filling the signing pool, which speeds up startup and rotation of large pools of RSA keys on multi-core machines."`
+
+	SelfSignedX5C bool `name:"self-signed-x5c" help:"generates a self-signed x509 certificate for each asymmetric key, published as the x5c of its JWK and sent in the x5c header of signed tokens"`
+
+	KIDFormat string `name:"kid-format" optional:"" help:"a regular expression that every generated kid must match in its entirety, e.g. [A-Za-z0-9_-]{22}.  generating a key whose kid doesn't match fails."`
+
+	KeyFallback []string `optional:"" enum:"EC,RSA,OKP,oct" help:"an ordered list of key types to fall back to when generating a key of the primary key type fails.  tokens signed by an oct fallback key can't be verified from /keys."`
+
+	KeyStoreType string `name:"keystore" default:"memory" enum:"memory,file" help:"the storage for published keys.  file keeps public keys in --keystore-dir, so tokens signed before a restart still verify after it."`
+	KeyStoreDir  string `name:"keystore-dir" optional:"" type:"existingdir" help:"the directory that holds a JWK file for each published key.  required for file storage."`
+
+	KeyCacheTTL time.Duration `default:"0s" help:"how long key lookups by kid are cached in memory.  zero disables the cache."`
+
+	KeyMissCacheTTL  time.Duration `default:"0s" help:"how long a kid that wasn't found is remembered as missing, so repeated lookups skip the key store.  zero disables the cache."`
+	KeyMissCacheSize int           `default:"1024" help:"the maximum number of missing kids remembered.  used only when --key-miss-cache-ttl is set."`
+
+	RekeyKeepKID bool `name:"rekey-keep-kid" help:"rotates the key material of the current keys while keeping their kids.  verifiers that cache keys by kid will reject tokens until they refresh, and tokens signed before a rotation stop verifying."`
+
+	KeyActivationDelay time.Duration `default:"0s" help:"how long rotated signing keys are published in /keys before they sign anything, so that verifiers across a fleet can fetch them first.  keys generated at startup are used immediately.  zero activates rotated keys immediately."`
+
+	KeyDeleteGrace time.Duration `default:"0s" help:"how long a deleted key is still served in /keys, so verifiers with a cached key set can still verify its tokens.  zero removes deleted keys immediately."`
+
+	RotationLock bool `help:"coordinates scheduled rotations through the key store, so that only one replica sharing the store rotates per interval.  requires --keystore=file, with the directory shared by the replicas.  replicas that lose the lock adopt the key persisted by the replica that won it."`
+
+	ReuseCurrentKey bool `help:"reuses a persisted current key on startup if it is not yet due for rotation.  requires --keystore=file."`
+
+	IssueKeyTypes []string `optional:"" enum:"EC,RSA,OKP" help:"additional key types whose current keys sign issued tokens when a request selects them with the key_type parameter.  the primary --key-type is always selectable."`
+
+	EncryptionKeyType string `default:"" enum:",EC,RSA" help:"the key type of an encryption key, published in /keys with a use of enc, that clients use to encrypt content for this server.  it rotates along with the signing keys.  when unset, no encryption key is generated."`
+
+	SignKeyType string `default:"" enum:",EC,RSA,OKP" help:"the key type of a separate current key that signs /sign payloads.  when unset, /sign uses the same keys as /issue."`
+
+	SignFullCTY bool `name:"sign-full-cty" help:"uses the full Content-Type of /sign requests as the cty header, e.g. application/json rather than json"`
+
+	MultiSign []string `optional:"" enum:"EC,RSA,OKP" help:"additional key types whose current keys also sign /sign payloads.  when set, /sign produces a JWS JSON serialization with one signature per key."`
+
+	CosignerURL     string        `name:"cosigner-url" optional:"" help:"the URL of an external co-signer that adds a second signature to every /sign payload.  when set, /sign produces a JWS JSON serialization, and fails with 502 if the co-signer fails."`
+	CosignerTimeout time.Duration `default:"5s" help:"how long to wait for the co-signer. used only when --cosigner-url is set."`
+
+	CosignerSignatureFormat string `default:"raw" enum:"raw,der" help:"the form of the co-signer's ECDSA signatures.  der converts the ASN.1 DER signatures that HSMs and key management services produce into the raw R||S form that JOSE requires."`
+
+	AllowEmptyPayload bool `help:"signs empty /sign payloads instead of rejecting them with 400"`
+
+	DeterministicECDSA bool `help:"uses RFC 6979 deterministic nonces for EC signatures instead of random nonces"`
+
+	LowSECDSA bool `name:"low-s-ecdsa" help:"normalizes EC signatures so that S is in the lower half of the curve order, for verifiers that reject high-S signatures"`
+
+	Blacklist    string `default:"memory" enum:"memory,redis" help:"the storage for revocations and single-use nonces"`
+	RedisAddress string `default:"localhost:6379" help:"the redis server address. used only for redis storage."`
+	RedisPrefix  string `default:"utu:blacklist:" help:"the prefix for all redis keys. used only for redis storage."`
+
+	VerifyConcurrency  int           `default:"0" help:"the maximum number of /verify requests that verify tokens concurrently, so that CPU-bound verification can't starve issuance.  zero means unlimited."`
+	VerifyQueueTimeout time.Duration `default:"0s" help:"how long a /verify request waits for a free slot when --verify-concurrency is reached before receiving a 429.  zero rejects such requests immediately."`
+
+	MaxTokenAge time.Duration `default:"0s" help:"the oldest token, by its iat, that /verify accepts, regardless of its exp.  zero disables the check."`
+
+	KeySetWebhook []string `optional:"" help:"URLs that are POSTed the public key set after every key rotation, e.g. key set aggregators.  POST /admin/publish resends the key set on demand."`
+
+	LogoutNotify []string `optional:"" help:"the back-channel logout URLs of relying parties that are sent a logout token whenever tokens are revoked via /logout"`
+
+	StrictStartup bool `help:"verifies a freshly issued token against the published key set on startup, failing startup on any mismatch"`
+
+	SelfTestRetries int `default:"0" help:"the number of times a failed startup self test is retried, with exponential backoff starting at --self-test-backoff, before startup fails.  the self test runs in-process against the key store and never goes through the HTTP listener, so retries only cover transient key store errors, not server readiness.  used only with --strict-startup."`
+
+	SelfTestBackoff time.Duration `default:"100ms" help:"the delay before the first self test retry.  each subsequent retry waits twice as long as the previous one."`
+}
+
+// isURLIssuer tests if the given issuer is an absolute http or https URL.
+func isURLIssuer(iss string) bool {
+	u, err := url.Parse(iss)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && len(u.Host) > 0
+}
+
+// Validate performs the checks that kong's struct tags can't express. Kong
+// invokes this method after parsing.
+func (cli CLI) Validate() error {
+	switch {
+	case cli.RequireURLIssuer && !isURLIssuer(cli.Issuer):
+		return fmt.Errorf("--issuer must be an http or https URL when --require-url-issuer is set: %s", cli.Issuer)
+
+	case cli.MinimalToken && cli.RequireURLIssuer:
+		return fmt.Errorf("--minimal-token omits the issuer, and cannot be used with --require-url-issuer")
+
+	case cli.MinimalToken && cli.RequireAudience:
+		return fmt.Errorf("--minimal-token omits the audience, and cannot be used with --require-audience")
+
+	case (len(cli.TLSCertFile) > 0) != (len(cli.TLSKeyFile) > 0):
+		return fmt.Errorf("--tls-cert-file and --tls-key-file must be used together")
+
+	case len(cli.TLSClientCAFile) > 0 && len(cli.TLSCertFile) == 0:
+		return fmt.Errorf("--tls-client-ca-file requires --tls-cert-file")
+
+	case len(cli.AdminClientCert) > 0 && len(cli.TLSClientCAFile) == 0:
+		return fmt.Errorf("--admin-client-cert requires --tls-client-ca-file")
+
+	case cli.Expires <= 0 || cli.Expires > MaxExpires:
+		return fmt.Errorf("--expires must be positive and at most %s", MaxExpires)
+
+	case cli.MaxRequestExpires <= 0 || cli.MaxRequestExpires > MaxExpires:
+		return fmt.Errorf("--max-expires must be positive and at most %s", MaxExpires)
+
+	case cli.IATSkew < 0 || cli.IATSkew >= cli.Expires:
+		return fmt.Errorf("--iat-skew may not be negative, and must be less than --expires")
+
+	case cli.MaxAudiences <= 0:
+		return fmt.Errorf("--max-audiences must be positive")
+
+	case cli.SelfTestRetries < 0:
+		return fmt.Errorf("--self-test-retries may not be negative")
+
+	case cli.SelfTestRetries > 0 && cli.SelfTestBackoff <= 0:
+		return fmt.Errorf("--self-test-backoff must be positive when --self-test-retries is set")
+
+	case cli.MetadataMaxAge < 0:
+		return fmt.Errorf("--metadata-max-age may not be negative")
+
+	case cli.VerifyConcurrency < 0:
+		return fmt.Errorf("--verify-concurrency may not be negative")
+
+	case cli.VerifyQueueTimeout < 0:
+		return fmt.Errorf("--verify-queue-timeout may not be negative")
+
+	case cli.MaxTokenBytes < 0:
+		return fmt.Errorf("--max-token-bytes may not be negative")
+
+	case cli.MaxCustomClaims < 0:
+		return fmt.Errorf("--max-custom-claims may not be negative")
+
+	case cli.MaxClaimValueBytes < 0:
+		return fmt.Errorf("--max-claim-value-bytes may not be negative")
+
+	case cli.HealthAttestation < 0:
+		return fmt.Errorf("--health-attestation may not be negative")
+
+	case cli.JTICollisionWindow < 0:
+		return fmt.Errorf("--jti-collision-window may not be negative")
+
+	case slices.ContainsFunc(cli.InheritClaim, func(name string) bool { return registeredClaims[name] }):
+		return fmt.Errorf("--inherit-claim may not name a registered claim")
+
+	case len(cli.CosignerURL) > 0 && cli.CosignerTimeout <= 0:
+		return fmt.Errorf("--cosigner-timeout must be positive")
+
+	case cli.KeyStoreType == "file" && len(cli.KeyStoreDir) == 0:
+		return fmt.Errorf("--keystore=file requires --keystore-dir")
+
+	case cli.RotationLock && cli.KeyStoreType != "file":
+		return fmt.Errorf("--rotation-lock requires --keystore=file")
+
+	case cli.ReuseCurrentKey && cli.KeyStoreType != "file":
+		return fmt.Errorf("--reuse-current-key requires --keystore=file")
+
+	case cli.StrictStartup && (cli.KeyType == "oct" || slices.Contains(cli.KeyFallback, "oct")):
+		return fmt.Errorf("--strict-startup cannot be used with oct keys, since their tokens can't be verified from /keys")
+
+	case cli.KeyStoreType == "file" && (cli.KeyType == "oct" || slices.Contains(cli.KeyFallback, "oct")):
+		return fmt.Errorf("--keystore=file cannot persist oct keys, since they have no public material")
+
+	case cli.KeyRotate <= 0 || cli.KeyRotate > MaxKeyRotate:
+		return fmt.Errorf("--key-rotate must be positive and at most %s", MaxKeyRotate)
+
+	case cli.SigningPoolSize < 1:
+		return fmt.Errorf("--signing-pool-size must be at least 1")
+
+	case cli.KeyGenerationWorkers < 1:
+		return fmt.Errorf("--key-generation-workers must be at least 1")
+
+	case cli.KeyActivationDelay < 0 || cli.KeyActivationDelay >= cli.KeyRotate:
+		return fmt.Errorf("--key-activation-delay may not be negative, and must be less than --key-rotate")
+
+	case cli.KeyActivationDelay > 0 && cli.RekeyKeepKID:
+		return fmt.Errorf("--key-activation-delay cannot be used with --rekey-keep-kid")
+
+	default:
+		for route, timeout := range cli.RouteTimeout {
+			if !slices.Contains(routePaths, route) {
+				return fmt.Errorf("--route-timeout for %s names no route", route)
+			}
+
+			if timeout <= 0 {
+				return fmt.Errorf("--route-timeout for %s must be positive", route)
+			}
+		}
+
+		for scope, expires := range cli.ScopeExpires {
+			if expires <= 0 || expires > MaxExpires {
+				return fmt.Errorf("--scope-expires for %s must be positive and at most %s", scope, MaxExpires)
+			}
+		}
+
+		return nil
+	}
 }
 
 func NewCLI(args []string, options ...kong.Option) (cli CLI, kctx *kong.Context, err error) {
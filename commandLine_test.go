@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCLIValidate(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expectErr   bool
+	}{
+		{
+			description: "defaults",
+		},
+		{
+			description: "longest expires",
+			args:        []string{"--expires=8784h", "--max-expires=8784h"},
+		},
+		{
+			description: "zero expires",
+			args:        []string{"--expires=0s"},
+			expectErr:   true,
+		},
+		{
+			description: "negative expires",
+			args:        []string{"--expires=-1m"},
+			expectErr:   true,
+		},
+		{
+			description: "expires past the maximum",
+			args:        []string{"--expires=8785h"},
+			expectErr:   true,
+		},
+		{
+			description: "longest key rotation",
+			args:        []string{"--key-rotate=8784h"},
+		},
+		{
+			description: "zero key rotation",
+			args:        []string{"--key-rotate=0s"},
+			expectErr:   true,
+		},
+		{
+			description: "key rotation past the maximum",
+			args:        []string{"--key-rotate=8785h"},
+			expectErr:   true,
+		},
+		{
+			description: "URL issuer not required",
+			args:        []string{"--issuer=utu"},
+		},
+		{
+			description: "https issuer",
+			args:        []string{"--require-url-issuer", "--issuer=https://utu.example.com"},
+		},
+		{
+			description: "http issuer",
+			args:        []string{"--require-url-issuer", "--issuer=http://localhost:8080"},
+		},
+		{
+			description: "bare issuer",
+			args:        []string{"--require-url-issuer", "--issuer=utu"},
+			expectErr:   true,
+		},
+		{
+			description: "issuer without a host",
+			args:        []string{"--require-url-issuer", "--issuer=https://"},
+			expectErr:   true,
+		},
+		{
+			description: "issuer with another scheme",
+			args:        []string{"--require-url-issuer", "--issuer=ftp://utu.example.com"},
+			expectErr:   true,
+		},
+		{
+			description: "zero max audiences",
+			args:        []string{"--max-audiences=0"},
+			expectErr:   true,
+		},
+		{
+			description: "negative max audiences",
+			args:        []string{"--max-audiences=-1"},
+			expectErr:   true,
+		},
+		{
+			description: "unknown audience override mode",
+			args:        []string{"--aud-override-mode=append"},
+			expectErr:   true,
+		},
+		{
+			description: "client CA without TLS",
+			args:        []string{"--tls-client-ca-file=commandLine.go"},
+			expectErr:   true,
+		},
+		{
+			description: "minimal token requiring a URL issuer",
+			args:        []string{"--minimal-token", "--require-url-issuer", "--issuer=https://utu.example.com"},
+			expectErr:   true,
+		},
+		{
+			description: "negative max custom claims",
+			args:        []string{"--max-custom-claims=-1"},
+			expectErr:   true,
+		},
+		{
+			description: "zero cosigner timeout",
+			args:        []string{"--cosigner-url=http://cosigner.example.com", "--cosigner-timeout=0s"},
+			expectErr:   true,
+		},
+		{
+			description: "unknown issue key type",
+			args:        []string{"--issue-key-types=DSA"},
+			expectErr:   true,
+		},
+		{
+			description: "non-positive route timeout",
+			args:        []string{"--route-timeout=/sign=0s"},
+			expectErr:   true,
+		},
+		{
+			description: "negative iat skew",
+			args:        []string{"--iat-skew=-1s"},
+			expectErr:   true,
+		},
+		{
+			description: "iat skew not less than expires",
+			args:        []string{"--iat-skew=15m"},
+			expectErr:   true,
+		},
+		{
+			description: "no key generation workers",
+			args:        []string{"--key-generation-workers=0"},
+			expectErr:   true,
+		},
+		{
+			description: "negative metadata max age",
+			args:        []string{"--metadata-max-age=-1s"},
+			expectErr:   true,
+		},
+		{
+			description: "negative self test retries",
+			args:        []string{"--self-test-retries=-1"},
+			expectErr:   true,
+		},
+		{
+			description: "self test retries without a backoff",
+			args:        []string{"--self-test-retries=1", "--self-test-backoff=0s"},
+			expectErr:   true,
+		},
+		{
+			description: "unknown missing current key mode",
+			args:        []string{"--missing-current-key=panic"},
+			expectErr:   true,
+		},
+		{
+			description: "minimal token requiring an audience",
+			args:        []string{"--minimal-token", "--require-audience"},
+			expectErr:   true,
+		},
+		{
+			description: "negative max token bytes",
+			args:        []string{"--max-token-bytes=-1"},
+			expectErr:   true,
+		},
+		{
+			description: "negative verify concurrency",
+			args:        []string{"--verify-concurrency=-1"},
+			expectErr:   true,
+		},
+		{
+			description: "negative verify queue timeout",
+			args:        []string{"--verify-queue-timeout=-1s"},
+			expectErr:   true,
+		},
+		{
+			description: "negative max claim value bytes",
+			args:        []string{"--max-claim-value-bytes=-1"},
+			expectErr:   true,
+		},
+		{
+			description: "negative key activation delay",
+			args:        []string{"--key-activation-delay=-1s"},
+			expectErr:   true,
+		},
+		{
+			description: "key activation delay not less than key rotate",
+			args:        []string{"--key-activation-delay=1h", "--key-rotate=1h"},
+			expectErr:   true,
+		},
+		{
+			description: "key activation delay with rekey keep kid",
+			args:        []string{"--key-activation-delay=1h", "--rekey-keep-kid"},
+			expectErr:   true,
+		},
+		{
+			description: "negative jti collision window",
+			args:        []string{"--jti-collision-window=-1"},
+			expectErr:   true,
+		},
+		{
+			description: "inherit a registered claim",
+			args:        []string{"--inherit-claim=tenant,sub"},
+			expectErr:   true,
+		},
+		{
+			description: "negative health attestation",
+			args:        []string{"--health-attestation=-1s"},
+			expectErr:   true,
+		},
+		{
+			description: "file key store without a directory",
+			args:        []string{"--keystore=file"},
+			expectErr:   true,
+		},
+		{
+			description: "file key store with oct keys",
+			args:        []string{"--keystore=file", "--keystore-dir=.", "--key-type=oct"},
+			expectErr:   true,
+		},
+		{
+			description: "file key store with an oct fallback",
+			args:        []string{"--keystore=file", "--keystore-dir=.", "--key-fallback=oct"},
+			expectErr:   true,
+		},
+		{
+			description: "strict startup with oct keys",
+			args:        []string{"--strict-startup", "--key-type=oct"},
+			expectErr:   true,
+		},
+		{
+			description: "strict startup with an oct fallback",
+			args:        []string{"--strict-startup", "--key-fallback=oct"},
+			expectErr:   true,
+		},
+		{
+			description: "zero signing pool size",
+			args:        []string{"--signing-pool-size=0"},
+			expectErr:   true,
+		},
+		{
+			description: "negative signing pool size",
+			args:        []string{"--signing-pool-size=-1"},
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			_, _, err := NewCLI(tc.args)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrCosignerFailed is wrapped by all errors that result from the external
+	// co-signer being unreachable, timing out, or returning an unusable signature.
+	ErrCosignerFailed = errors.New("the co-signer failed to sign")
+)
+
+const (
+	// CosignerFormatRaw indicates that the co-signer returns JOSE signatures as-is.
+	CosignerFormatRaw = "raw"
+
+	// CosignerFormatDER indicates that the co-signer returns ECDSA signatures in ASN.1
+	// DER form, which must be converted to the JOSE R||S form.
+	CosignerFormatDER = "der"
+)
+
+// CosignRequest is the body POSTed to the co-signer. The co-signer signs the payload
+// under a protected header of its own choosing.
+type CosignRequest struct {
+	// Payload is the base64url encoded payload of the JWS.
+	Payload string `json:"payload"`
+}
+
+// CosignSignature is the co-signer's response, which is a signature object of a
+// RFC 7515 JWS JSON serialization.
+type CosignSignature struct {
+	Protected string         `json:"protected"`
+	Header    map[string]any `json:"header,omitempty"`
+	Signature string         `json:"signature"`
+}
+
+// Cosigner obtains a second signature over JWS payloads from an external service,
+// for deployments that require dual control of signing.
+type Cosigner struct {
+	url    string
+	client *http.Client
+
+	// der indicates whether the co-signer's ECDSA signatures must be converted from DER.
+	der bool
+}
+
+// NewCosigner creates the Cosigner for the configured co-signer URL. If no co-signer
+// URL is configured, this function returns a nil Cosigner.
+func NewCosigner(l *zap.Logger, cli CLI) (c *Cosigner) {
+	if len(cli.CosignerURL) == 0 {
+		return
+	}
+
+	c = &Cosigner{
+		url: cli.CosignerURL,
+		client: &http.Client{
+			Timeout: cli.CosignerTimeout,
+		},
+		der: cli.CosignerSignatureFormat == CosignerFormatDER,
+	}
+
+	l.Info("cosigner",
+		zap.String("url", c.url),
+		zap.Duration("timeout", cli.CosignerTimeout),
+		zap.Bool("der", c.der),
+	)
+
+	return
+}
+
+// Cosign asks the co-signer to sign the given payload. Any error returned by this
+// method wraps ErrCosignerFailed.
+func (c *Cosigner) Cosign(p []byte) (sig CosignSignature, err error) {
+	var (
+		body     []byte
+		response *http.Response
+	)
+
+	body, err = json.Marshal(CosignRequest{
+		Payload: base64.RawURLEncoding.EncodeToString(p),
+	})
+
+	if err == nil {
+		response, err = c.client.Post(c.url, "application/json", bytes.NewReader(body))
+	}
+
+	if err == nil {
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			io.Copy(io.Discard, response.Body)
+			err = fmt.Errorf("unexpected status %d", response.StatusCode)
+		}
+	}
+
+	if err == nil {
+		err = json.NewDecoder(response.Body).Decode(&sig)
+	}
+
+	if err == nil && (len(sig.Protected) == 0 || len(sig.Signature) == 0) {
+		err = errors.New("the co-signer returned an incomplete signature")
+	}
+
+	if err == nil && c.der {
+		sig.Signature, err = convertDERSignature(sig)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%w: %s: %w", ErrCosignerFailed, c.url, err)
+	}
+
+	return
+}
+
+// convertDERSignature converts the signature of a co-signer signature object from
+// DER to the JOSE form, as dictated by the alg of its protected header. The result
+// is base64url encoded.
+func convertDERSignature(sig CosignSignature) (signature string, err error) {
+	var (
+		protected []byte
+		header    struct {
+			Alg string `json:"alg"`
+		}
+
+		der, raw []byte
+	)
+
+	protected, err = base64.RawURLEncoding.DecodeString(sig.Protected)
+	if err == nil {
+		err = json.Unmarshal(protected, &header)
+	}
+
+	if err == nil {
+		der, err = base64.RawURLEncoding.DecodeString(sig.Signature)
+	}
+
+	if err == nil {
+		raw, err = ecdsaDERToRaw(header.Alg, der)
+	}
+
+	if err == nil {
+		signature = base64.RawURLEncoding.EncodeToString(raw)
+	}
+
+	return
+}
+
+// jwsJSON is a RFC 7515 JWS JSON serialization, in either the general or the
+// flattened syntax.
+type jwsJSON struct {
+	Payload    string            `json:"payload"`
+	Signatures []json.RawMessage `json:"signatures,omitempty"`
+
+	// the flattened syntax holds a single signature inline
+	Protected string         `json:"protected,omitempty"`
+	Header    map[string]any `json:"header,omitempty"`
+	Signature string         `json:"signature,omitempty"`
+}
+
+// appendSignature adds a signature to a JWS JSON serialization. The result always
+// uses the general syntax.
+func appendSignature(signed []byte, sig CosignSignature) (result []byte, err error) {
+	var j jwsJSON
+	err = json.Unmarshal(signed, &j)
+
+	var raw json.RawMessage
+	if err == nil && len(j.Signatures) == 0 {
+		// convert the flattened syntax into the general syntax
+		raw, err = json.Marshal(CosignSignature{
+			Protected: j.Protected,
+			Header:    j.Header,
+			Signature: j.Signature,
+		})
+
+		j.Signatures = append(j.Signatures, raw)
+		j.Protected, j.Header, j.Signature = "", nil, ""
+	}
+
+	if err == nil {
+		raw, err = json.Marshal(sig)
+	}
+
+	if err == nil {
+		j.Signatures = append(j.Signatures, raw)
+		result, err = json.Marshal(j)
+	}
+
+	return
+}
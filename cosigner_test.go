@@ -0,0 +1,189 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCosigner is a co-signer service that signs payloads with its own EC key.
+type testCosigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func newTestCosigner(t *testing.T) testCosigner {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return testCosigner{key: key}
+}
+
+// sign produces the ES256 signature object for the given base64url payload.
+func (tc testCosigner) sign(t *testing.T, payload string) CosignSignature {
+	protected, digest := tc.signingInput(payload)
+	r, s, err := ecdsa.Sign(rand.Reader, tc.key, digest[:])
+	require.NoError(t, err)
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return CosignSignature{
+		Protected: protected,
+		Signature: base64.RawURLEncoding.EncodeToString(signature),
+	}
+}
+
+// signDER is like sign, but produces the ASN.1 DER signature that an HSM would.
+func (tc testCosigner) signDER(t *testing.T, payload string) CosignSignature {
+	protected, digest := tc.signingInput(payload)
+	der, err := ecdsa.SignASN1(rand.Reader, tc.key, digest[:])
+	require.NoError(t, err)
+
+	return CosignSignature{
+		Protected: protected,
+		Signature: base64.RawURLEncoding.EncodeToString(der),
+	}
+}
+
+// signingInput returns the protected header and the digest of the signing input
+// for the given base64url payload.
+func (testCosigner) signingInput(payload string) (string, [sha256.Size]byte) {
+	protected := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","kid":"cosigner"}`))
+	return protected, sha256.Sum256([]byte(protected + "." + payload))
+}
+
+// handler returns the co-signer service that signs with the given function.
+func (tc testCosigner) handler(t *testing.T, sign func(testCosigner, *testing.T, string) CosignSignature) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		var cr CosignRequest
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&cr))
+		json.NewEncoder(response).Encode(sign(tc, t, cr.Payload))
+	}
+}
+
+func TestSignHandlerCosigner(t *testing.T) {
+	cosigner := newTestCosigner(t)
+
+	tests := []struct {
+		description string
+
+		// handler is the co-signer service. When nil, the co-signer is unreachable.
+		handler        http.HandlerFunc
+		args           []string
+		expectedStatus int
+
+		// expectedSignatures is the number of signatures in a successful response.
+		expectedSignatures int
+	}{
+		{
+			description:        "cosigned",
+			handler:            cosigner.handler(t, testCosigner.sign),
+			expectedStatus:     http.StatusOK,
+			expectedSignatures: 2,
+		},
+		{
+			description:        "cosigned with multi-sign",
+			handler:            cosigner.handler(t, testCosigner.sign),
+			args:               []string{"--multi-sign=RSA"},
+			expectedStatus:     http.StatusOK,
+			expectedSignatures: 3,
+		},
+		{
+			description:        "DER signature",
+			handler:            cosigner.handler(t, testCosigner.signDER),
+			args:               []string{"--cosigner-signature-format=der"},
+			expectedStatus:     http.StatusOK,
+			expectedSignatures: 2,
+		},
+		{
+			description:    "raw signature in DER format",
+			handler:        cosigner.handler(t, testCosigner.sign),
+			args:           []string{"--cosigner-signature-format=der"},
+			expectedStatus: http.StatusBadGateway,
+		},
+		{
+			description: "co-signer error",
+			handler: func(response http.ResponseWriter, _ *http.Request) {
+				response.WriteHeader(http.StatusInternalServerError)
+			},
+			expectedStatus: http.StatusBadGateway,
+		},
+		{
+			description: "incomplete signature",
+			handler: func(response http.ResponseWriter, _ *http.Request) {
+				response.Write([]byte(`{"protected":"e30"}`))
+			},
+			expectedStatus: http.StatusBadGateway,
+		},
+		{
+			description: "malformed response",
+			handler: func(response http.ResponseWriter, _ *http.Request) {
+				response.Write([]byte(`not json`))
+			},
+			expectedStatus: http.StatusBadGateway,
+		},
+		{
+			description: "timeout",
+			handler: func(http.ResponseWriter, *http.Request) {
+				time.Sleep(250 * time.Millisecond)
+			},
+			args:           []string{"--cosigner-timeout=50ms"},
+			expectedStatus: http.StatusBadGateway,
+		},
+		{
+			description:    "unreachable",
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			cosignerURL := "http://127.0.0.1:1/cosign"
+			if tc.handler != nil {
+				server := httptest.NewServer(tc.handler)
+				cosignerURL = server.URL
+				defer server.Close()
+			}
+
+			h := newTestServer(t, append([]string{"--cosigner-url=" + cosignerURL}, tc.args...)...)
+			response := serve(h, http.MethodPut, "/sign", strings.NewReader("payload"), "Content-Type: text/plain")
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus != http.StatusOK {
+				assert.Contains(t, response.Body.String(), ErrCosignerFailed.Error())
+				return
+			}
+
+			message, err := jws.Parse(response.Body.Bytes())
+			require.NoError(t, err)
+			assert.Len(t, message.Signatures(), tc.expectedSignatures)
+			assert.Equal(t, "payload", string(message.Payload()))
+
+			// the co-signer's signature comes last, and verifies with its key
+			last := message.Signatures()[len(message.Signatures())-1]
+			kid, _ := last.ProtectedHeaders().KeyID()
+			assert.Equal(t, "cosigner", kid)
+
+			_, err = jws.Verify(response.Body.Bytes(), jws.WithKey(jwa.ES256(), &cosigner.key.PublicKey))
+			assert.NoError(t, err)
+
+			// tokens are never cosigned, since a JWT holds a single signature
+			assert.Len(t, strings.Split(issueToken(t, h, ""), "."), 3)
+
+		})
+	}
+}
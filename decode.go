@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrMalformedToken indicates that a token could not be decoded as a compact JWS.
+	ErrMalformedToken = errors.New("the token is not a well-formed compact JWS")
+)
+
+// DecodedToken is the body returned by DecodeHandler. Verified is always false, since
+// decoding never checks the signature, so nothing in a DecodedToken can be trusted.
+type DecodedToken struct {
+	Verified bool            `json:"verified"`
+	Header   json.RawMessage `json:"header"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// DecodeToken parses a compact token without verifying its signature. The token's
+// payload must be JSON, as it is for any JWT. Any error returned by this function
+// wraps ErrMalformedToken.
+func DecodeToken(token []byte) (dt DecodedToken, err error) {
+	var msg *jws.Message
+	msg, err = jws.Parse(bytes.TrimSpace(token), jws.WithCompact())
+	if err == nil {
+		dt.Header, err = json.Marshal(msg.Signatures()[0].ProtectedHeaders())
+	}
+
+	if err == nil {
+		dt.Payload = msg.Payload()
+		if !json.Valid(dt.Payload) {
+			err = errors.New("the payload is not JSON")
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrMalformedToken, err)
+	}
+
+	return
+}
+
+// DecodeHandler decodes tokens without verifying them, for debugging tokens that
+// fail verification.
+type DecodeHandler struct {
+	logger *zap.Logger
+}
+
+func NewDecodeHandler(l *zap.Logger) *DecodeHandler {
+	return &DecodeHandler{
+		logger: l,
+	}
+}
+
+// ServeHTTP decodes the compact token in the request body, responding with its
+// header and payload. Malformed tokens result in a 400.
+func (dh *DecodeHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	var (
+		dt   DecodedToken
+		data []byte
+	)
+
+	token, err := io.ReadAll(request.Body)
+	if err == nil {
+		dt, err = DecodeToken(token)
+		if err != nil {
+			response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(err.Error()))
+			return
+		}
+	}
+
+	if err == nil {
+		data, err = json.Marshal(dt)
+	}
+
+	if err == nil {
+		writeBody(response, "application/json", data)
+	} else {
+		dh.logger.Error("unable to decode token", zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeToken(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	header := encode(`{"alg":"ES256","kid":"test"}`)
+	tests := []struct {
+		description     string
+		token           string
+		expectedPayload map[string]any
+		expectErr       bool
+	}{
+		{
+			description:     "decoded",
+			token:           header + "." + encode(`{"sub":"test"}`) + "." + encode("not a valid signature"),
+			expectedPayload: map[string]any{"sub": "test"},
+		},
+		{
+			description:     "surrounding whitespace",
+			token:           "\n " + header + "." + encode(`{"sub":"test"}`) + "." + encode("signature") + "\n",
+			expectedPayload: map[string]any{"sub": "test"},
+		},
+		{
+			description: "empty",
+			expectErr:   true,
+		},
+		{
+			description: "not a JWS",
+			token:       "not a token",
+			expectErr:   true,
+		},
+		{
+			description: "payload not JSON",
+			token:       header + "." + encode("payload") + "." + encode("signature"),
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			dt, err := DecodeToken([]byte(tc.token))
+			if tc.expectErr {
+				assert.ErrorIs(err, ErrMalformedToken)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.False(dt.Verified)
+
+			var h map[string]any
+			require.NoError(t, json.Unmarshal(dt.Header, &h))
+			assert.Equal("ES256", h["alg"])
+			assert.Equal("test", h["kid"])
+
+			var payload map[string]any
+			require.NoError(t, json.Unmarshal(dt.Payload, &payload))
+			assert.Equal(tc.expectedPayload, payload)
+		})
+	}
+}
+
+func TestDecodeHandler(t *testing.T) {
+	h := newTestServer(t, "--admin-token="+testAdminToken)
+	token := issueToken(t, h, "claim=a=1")
+
+	tests := []struct {
+		description    string
+		body           string
+		headers        []string
+		expectedStatus int
+	}{
+		{
+			description:    "unauthenticated",
+			body:           token,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "decoded",
+			body:           token,
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "malformed",
+			body:           "not a token",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			response := serve(h, http.MethodPost, "/decode", strings.NewReader(tc.body), tc.headers...)
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal("application/json", response.Header().Get("Content-Type"))
+
+			var dt DecodedToken
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &dt))
+			assert.False(dt.Verified)
+
+			var payload map[string]any
+			require.NoError(t, json.Unmarshal(dt.Payload, &payload))
+			assert.Equal(tokenClaims(t, token), payload)
+		})
+	}
+}
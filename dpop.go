@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"go.uber.org/zap"
+)
+
+const (
+	// DPoPHeader is the RFC 9449 request header that carries a DPoP proof.
+	DPoPHeader = "DPoP"
+
+	// DPoPProofType is the typ header that every DPoP proof must have.
+	DPoPProofType = "dpop+jwt"
+
+	// DPoPTokenType is the token_type of access tokens bound to a DPoP key.
+	DPoPTokenType = "DPoP"
+
+	// JKTMember is the RFC 9449 confirmation member that carries the JWK SHA-256
+	// thumbprint of the DPoP key a token is bound to.
+	JKTMember = "jkt"
+
+	// dpopProofWindow is how far a proof's iat may be from the current time, in
+	// either direction.
+	dpopProofWindow = time.Minute
+)
+
+var (
+	// ErrInvalidDPoPProof is wrapped by all errors that result from a DPoP proof
+	// failing validation.
+	ErrInvalidDPoPProof = errors.New("invalid DPoP proof")
+)
+
+// dpopClaims are the claims of a DPoP proof that are validated.
+type dpopClaims struct {
+	JTI string `json:"jti"`
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	IAT int64  `json:"iat"`
+}
+
+// DPoPVerifier validates RFC 9449 DPoP proofs sent to the token endpoint.
+type DPoPVerifier struct {
+	logger    *zap.Logger
+	blacklist BlacklistStore
+	now       func() time.Time
+
+	// tokenURL is the externally visible token endpoint URL. When unset, the
+	// URL is derived from each request.
+	tokenURL string
+}
+
+func NewDPoPVerifier(l *zap.Logger, blacklist BlacklistStore, cli CLI) (dv *DPoPVerifier, err error) {
+	dv = &DPoPVerifier{
+		logger:    l,
+		blacklist: blacklist,
+		now:       time.Now,
+	}
+
+	if len(cli.ExternalURL) > 0 {
+		dv.tokenURL, err = url.JoinPath(cli.ExternalURL, "token")
+	}
+
+	return
+}
+
+// requestURL returns the htu that a proof for the given request must carry.
+//
+// When no external URL is configured, the htu is derived from the request's Host
+// header and whether the connection to this server used TLS. Behind a proxy that
+// terminates TLS or rewrites the Host, that isn't the URL the client used, so
+// --external-url must be set for DPoP proofs to validate. Forwarding headers are
+// never consulted, since a client could use them to choose the htu.
+func (dv *DPoPVerifier) requestURL(request *http.Request) string {
+	if len(dv.tokenURL) > 0 {
+		return dv.tokenURL
+	}
+
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + request.Host + request.URL.Path
+}
+
+// normalizeHTU strips the query and fragment from an htu, and lowercases its scheme
+// and host, as RFC 9449 requires before comparison.
+func normalizeHTU(htu string) (string, error) {
+	u, err := url.Parse(htu)
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.RawQuery, u.Fragment = "", ""
+	return u.String(), nil
+}
+
+// checkClaims validates the claims of a proof against the given request.
+func (dv *DPoPVerifier) checkClaims(request *http.Request, claims dpopClaims) error {
+	htu, err := normalizeHTU(claims.HTU)
+	expected, _ := normalizeHTU(dv.requestURL(request))
+	iat := time.Unix(claims.IAT, 0)
+	now := dv.now()
+
+	switch {
+	case len(claims.JTI) == 0:
+		return errors.New("the proof has no jti")
+
+	case claims.HTM != request.Method:
+		return fmt.Errorf("the proof's htm %q doesn't match the request method %s", claims.HTM, request.Method)
+
+	case err != nil || htu != expected:
+		return fmt.Errorf("the proof's htu %q doesn't match %s", claims.HTU, expected)
+
+	case claims.IAT == 0 || iat.Before(now.Add(-dpopProofWindow)) || iat.After(now.Add(dpopProofWindow)):
+		return fmt.Errorf("the proof's iat must be within %s of the current time", dpopProofWindow)
+
+	default:
+		return nil
+	}
+}
+
+// proofKey returns the public JWK in the protected header of a proof. The proof
+// must be an asymmetric dpop+jwt signed with that key.
+func proofKey(msg *jws.Message) (key jwk.Key, alg jwa.SignatureAlgorithm, err error) {
+	if len(msg.Signatures()) != 1 {
+		err = errors.New("the proof must have exactly one signature")
+		return
+	}
+
+	h := msg.Signatures()[0].ProtectedHeaders()
+	typ, _ := h.Type()
+	alg, _ = h.Algorithm()
+	key, hasKey := h.JWK()
+
+	var private bool
+	switch {
+	case typ != DPoPProofType:
+		err = fmt.Errorf("the proof's typ must be %s", DPoPProofType)
+
+	case !hasKey:
+		err = errors.New("the proof has no jwk header")
+
+	case alg == jwa.NoSignature() || alg.IsSymmetric():
+		err = fmt.Errorf("the proof's alg must be asymmetric: %s", alg)
+
+	default:
+		if private, err = jwk.IsPrivateKey(key); err == nil && private {
+			err = errors.New("the proof's jwk must not contain private material")
+		}
+	}
+
+	return
+}
+
+// Verify validates the DPoP proof of a token request, returning the JWK thumbprint
+// of the proof's key. Each proof may be used only once. Any error returned by this
+// method wraps ErrInvalidDPoPProof.
+func (dv *DPoPVerifier) Verify(request *http.Request, proof string) (jkt string, err error) {
+	var (
+		msg    *jws.Message
+		key    jwk.Key
+		alg    jwa.SignatureAlgorithm
+		claims dpopClaims
+	)
+
+	msg, err = jws.Parse([]byte(proof))
+	if err == nil {
+		key, alg, err = proofKey(msg)
+	}
+
+	if err == nil {
+		_, err = jws.Verify([]byte(proof), jws.WithKey(alg, key))
+	}
+
+	if err == nil {
+		err = json.Unmarshal(msg.Payload(), &claims)
+	}
+
+	if err == nil {
+		err = dv.checkClaims(request, claims)
+	}
+
+	var fresh bool
+	if err == nil {
+		// a proof is only accepted within its window, so its jti needn't be kept any longer
+		fresh, err = dv.blacklist.AddIfAbsent(revocationID("dpop", claims.JTI), dv.now().Add(2*dpopProofWindow))
+	}
+
+	if err == nil && !fresh {
+		err = errors.New("the proof's jti has already been used")
+	}
+
+	var thumbprint []byte
+	if err == nil {
+		thumbprint, err = key.Thumbprint(crypto.SHA256)
+	}
+
+	if err == nil {
+		jkt = base64.RawURLEncoding.EncodeToString(thumbprint)
+	} else {
+		err = fmt.Errorf("%w: %w", ErrInvalidDPoPProof, err)
+	}
+
+	return
+}
@@ -0,0 +1,306 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testProof describes a DPoP proof to sign for a test.
+type testProof struct {
+	typ string
+	alg jwa.SignatureAlgorithm
+
+	// signer is the key that signs the proof, and key is the jwk header. When
+	// key is nil, the proof has no jwk header.
+	signer any
+	key    jwk.Key
+
+	claims map[string]any
+}
+
+// newTestProof describes a valid ES256 proof for a POST to http://example.com/token,
+// which is the token endpoint of an httptest request.
+func newTestProof(t *testing.T) *testProof {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	key, err := jwk.Import(private.PublicKey)
+	require.NoError(t, err)
+
+	return &testProof{
+		typ:    DPoPProofType,
+		alg:    jwa.ES256(),
+		signer: private,
+		key:    key,
+		claims: map[string]any{
+			"jti": rand.Text(),
+			"htm": http.MethodPost,
+			"htu": "http://example.com/token",
+			"iat": time.Now().Unix(),
+		},
+	}
+}
+
+// sign returns the compact serialization of this proof.
+func (tp *testProof) sign(t *testing.T) string {
+	h := jws.NewHeaders()
+	require.NoError(t, h.Set(jws.TypeKey, tp.typ))
+	if tp.key != nil {
+		require.NoError(t, h.Set(jws.JWKKey, tp.key))
+	}
+
+	payload, err := json.Marshal(tp.claims)
+	require.NoError(t, err)
+
+	proof, err := jws.Sign(payload, jws.WithKey(tp.alg, tp.signer, jws.WithProtectedHeaders(h)))
+	require.NoError(t, err)
+	return string(proof)
+}
+
+// thumbprint returns the base64url JWK SHA-256 thumbprint of this proof's key.
+func (tp *testProof) thumbprint(t *testing.T) string {
+	sum, err := tp.key.Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(sum)
+}
+
+func TestDPoPVerifier(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// mutate changes a valid proof before it is signed.
+		mutate func(*testing.T, *testProof)
+		tls    bool
+		valid  bool
+	}{
+		{
+			description: "valid",
+			valid:       true,
+		},
+		{
+			description: "htu with a query and uppercase host",
+			mutate: func(_ *testing.T, tp *testProof) {
+				tp.claims["htu"] = "HTTP://EXAMPLE.COM/token?x=1#f"
+			},
+			valid: true,
+		},
+		{
+			description: "htu over TLS",
+			mutate: func(_ *testing.T, tp *testProof) {
+				tp.claims["htu"] = "https://example.com/token"
+			},
+			tls:   true,
+			valid: true,
+		},
+		{
+			description: "htu of the external URL",
+			args:        []string{"--external-url=https://utu.example.com"},
+			mutate: func(_ *testing.T, tp *testProof) {
+				tp.claims["htu"] = "https://utu.example.com/token"
+			},
+			valid: true,
+		},
+		{
+			description: "request URL when an external URL is configured",
+			args:        []string{"--external-url=https://utu.example.com"},
+		},
+		{
+			description: "wrong typ",
+			mutate: func(_ *testing.T, tp *testProof) {
+				tp.typ = "JWT"
+			},
+		},
+		{
+			description: "no jwk header",
+			mutate: func(_ *testing.T, tp *testProof) {
+				tp.key = nil
+			},
+		},
+		{
+			description: "private jwk header",
+			mutate: func(t *testing.T, tp *testProof) {
+				var err error
+				tp.key, err = jwk.Import(tp.signer)
+				require.NoError(t, err)
+			},
+		},
+		{
+			description: "symmetric alg",
+			mutate: func(_ *testing.T, tp *testProof) {
+				tp.alg = jwa.HS256()
+				tp.signer = []byte("01234567890123456789012345678901")
+			},
+		},
+		{
+			description: "signed by another key",
+			mutate: func(t *testing.T, tp *testProof) {
+				tp.signer = newTestProof(t).signer
+			},
+		},
+		{
+			description: "no jti",
+			mutate: func(_ *testing.T, tp *testProof) {
+				delete(tp.claims, "jti")
+			},
+		},
+		{
+			description: "wrong htm",
+			mutate: func(_ *testing.T, tp *testProof) {
+				tp.claims["htm"] = http.MethodGet
+			},
+		},
+		{
+			description: "wrong htu",
+			mutate: func(_ *testing.T, tp *testProof) {
+				tp.claims["htu"] = "http://example.com/issue"
+			},
+		},
+		{
+			description: "no iat",
+			mutate: func(_ *testing.T, tp *testProof) {
+				delete(tp.claims, "iat")
+			},
+		},
+		{
+			description: "stale iat",
+			mutate: func(_ *testing.T, tp *testProof) {
+				tp.claims["iat"] = time.Now().Add(-2 * dpopProofWindow).Unix()
+			},
+		},
+		{
+			description: "future iat",
+			mutate: func(_ *testing.T, tp *testProof) {
+				tp.claims["iat"] = time.Now().Add(2 * dpopProofWindow).Unix()
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			dv, err := NewDPoPVerifier(zap.NewNop(), NewInMemoryBlacklistStore(), newTestCLI(t, tc.args...))
+			require.NoError(t, err)
+
+			tp := newTestProof(t)
+			if tc.mutate != nil {
+				tc.mutate(t, tp)
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/token", nil)
+			if tc.tls {
+				request.TLS = new(tls.ConnectionState)
+			}
+
+			proof := tp.sign(t)
+			jkt, err := dv.Verify(request, proof)
+			if !tc.valid {
+				assert.ErrorIs(err, ErrInvalidDPoPProof)
+				assert.Empty(jkt)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(tp.thumbprint(t), jkt)
+
+			// each proof may be used only once
+			_, err = dv.Verify(request, proof)
+			assert.ErrorIs(err, ErrInvalidDPoPProof)
+		})
+	}
+}
+
+func TestTokenHandlerDPoP(t *testing.T) {
+	tests := []struct {
+		description       string
+		proof             func(*testing.T) (proof, jkt string)
+		expectedStatus    int
+		expectedTokenType string
+	}{
+		{
+			description:       "no proof",
+			expectedStatus:    http.StatusOK,
+			expectedTokenType: "Bearer",
+		},
+		{
+			description: "valid proof",
+			proof: func(t *testing.T) (string, string) {
+				tp := newTestProof(t)
+				return tp.sign(t), tp.thumbprint(t)
+			},
+			expectedStatus:    http.StatusOK,
+			expectedTokenType: DPoPTokenType,
+		},
+		{
+			description: "invalid proof",
+			proof: func(t *testing.T) (string, string) {
+				tp := newTestProof(t)
+				tp.claims["htm"] = http.MethodGet
+				return tp.sign(t), ""
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description: "malformed proof",
+			proof: func(*testing.T) (string, string) {
+				return "not a proof", ""
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	h := newTestServer(t, "--admin-token="+testAdminToken)
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			headers := []string{testAdminAuth}
+
+			var jkt string
+			if tc.proof != nil {
+				var proof string
+				proof, jkt = tc.proof(t)
+				headers = append(headers, DPoPHeader+": "+proof)
+			}
+
+			response := serve(h, http.MethodPost, "/token", strings.NewReader("grant_type=client_credentials"), headers...)
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus != http.StatusOK {
+				var oe OAuthError
+				require.NoError(t, json.Unmarshal(response.Body.Bytes(), &oe))
+				assert.Equal("invalid_dpop_proof", oe.Error)
+				return
+			}
+
+			var tr TokenResponse
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &tr))
+			assert.Equal(tc.expectedTokenType, tr.TokenType)
+
+			cnf, _ := tokenClaims(t, tr.AccessToken)[ConfirmationClaim].(map[string]any)
+			if len(jkt) > 0 {
+				assert.Equal(jkt, cnf[JKTMember])
+			} else {
+				assert.Nil(cnf)
+			}
+		})
+	}
+}
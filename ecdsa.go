@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// ecdsaSizes is the size, in bytes, of each of R and S in a JOSE ECDSA signature.
+var ecdsaSizes = map[string]int{
+	"ES256": 32,
+	"ES384": 48,
+	"ES512": 66,
+}
+
+// deterministicECDSA is a crypto.Signer that produces RFC 6979 deterministic
+// ECDSA signatures, regardless of the random source it is handed.
+type deterministicECDSA struct {
+	key *ecdsa.PrivateKey
+}
+
+func (d deterministicECDSA) Public() crypto.PublicKey {
+	return &d.key.PublicKey
+}
+
+// Sign produces an ASN.1 DER signature of the given digest. The standard library
+// uses RFC 6979 nonces when no random source is supplied.
+func (d deterministicECDSA) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return d.key.Sign(nil, digest, opts)
+}
+
+// lowSECDSA is a crypto.Signer that normalizes the ECDSA signatures of another signer
+// so that S is always in the lower half of the curve order, as some verifiers require.
+// Both S and N-S are valid, so normalized signatures still verify everywhere.
+type lowSECDSA struct {
+	signer crypto.Signer
+	n      *big.Int
+}
+
+func (l lowSECDSA) Public() crypto.PublicKey {
+	return l.signer.Public()
+}
+
+// Sign produces an ASN.1 DER signature of the given digest with a low S.
+func (l lowSECDSA) Sign(random io.Reader, digest []byte, opts crypto.SignerOpts) (der []byte, err error) {
+	der, err = l.signer.Sign(random, digest, opts)
+	if err == nil {
+		der, err = normalizeLowS(der, l.n)
+	}
+
+	return
+}
+
+// normalizeLowS replaces the S of an ASN.1 DER ECDSA signature with N-S if S is in the
+// upper half of the curve order N. Signatures that already have a low S are returned unchanged.
+func normalizeLowS(der []byte, n *big.Int) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("invalid DER signature: %w", err)
+	}
+
+	if sig.S.Cmp(new(big.Int).Rsh(n, 1)) <= 0 {
+		return der, nil
+	}
+
+	sig.S.Sub(n, sig.S)
+	return asn1.Marshal(sig)
+}
+
+// ecdsaDERToRaw converts an ASN.1 DER ECDSA signature, as produced by most HSMs and
+// key management services, into the fixed-length R||S form that RFC 7518 requires.
+// Signatures for algorithms other than ECDSA are returned unchanged.
+func ecdsaDERToRaw(alg string, der []byte) (raw []byte, err error) {
+	size, ok := ecdsaSizes[alg]
+	if !ok {
+		return der, nil
+	}
+
+	var (
+		sig struct {
+			R, S *big.Int
+		}
+
+		rest []byte
+	)
+
+	rest, err = asn1.Unmarshal(der, &sig)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("invalid DER %s signature: %w", alg, err)
+
+	case len(rest) > 0:
+		err = fmt.Errorf("invalid DER %s signature: %d trailing bytes", alg, len(rest))
+
+	case sig.R.Sign() <= 0 || sig.S.Sign() <= 0:
+		err = errors.New("invalid DER signature: R and S must be positive")
+
+	case len(sig.R.Bytes()) > size || len(sig.S.Bytes()) > size:
+		err = fmt.Errorf("invalid DER signature: R and S must fit in %d bytes for %s", size, alg)
+
+	default:
+		raw = make([]byte, 2*size)
+		sig.R.FillBytes(raw[:size])
+		sig.S.FillBytes(raw[size:])
+	}
+
+	return
+}
@@ -0,0 +1,328 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeterministicECDSA(t *testing.T) {
+	tests := []struct {
+		description string
+		curve       elliptic.Curve
+	}{
+		{description: "P-256", curve: elliptic.P256()},
+		{description: "P-384", curve: elliptic.P384()},
+		{description: "P-521", curve: elliptic.P521()},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			key, err := ecdsa.GenerateKey(tc.curve, rand.Reader)
+			require.NoError(t, err)
+
+			digest := sha256.Sum256([]byte("payload"))
+			signer := deterministicECDSA{key: key}
+			first, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+			require.NoError(t, err)
+
+			second, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+			require.NoError(t, err)
+
+			assert.Equal(first, second)
+			assert.True(ecdsa.VerifyASN1(&key.PublicKey, digest[:], first))
+			assert.Equal(&key.PublicKey, signer.Public())
+		})
+	}
+}
+
+func TestSignHandlerDeterministicECDSA(t *testing.T) {
+	tests := []struct {
+		description   string
+		args          []string
+		expectedEqual bool
+	}{
+		{
+			description: "randomized",
+		},
+		{
+			description:   "deterministic",
+			args:          []string{"--deterministic-ecdsa"},
+			expectedEqual: true,
+		},
+		{
+			description:   "deterministic P-384",
+			args:          []string{"--deterministic-ecdsa", "--key-curve=P-384"},
+			expectedEqual: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newTestServer(t, tc.args...)
+			sign := func() string {
+				response := serve(h, http.MethodPut, "/sign", strings.NewReader("payload"), "Content-Type: text/plain")
+				require.Equal(t, http.StatusOK, response.Code)
+				return response.Body.String()
+			}
+
+			first, second := sign(), sign()
+			if tc.expectedEqual {
+				assert.Equal(t, first, second)
+			} else {
+				assert.NotEqual(t, first, second)
+			}
+		})
+	}
+}
+
+func TestECDSADERToRaw(t *testing.T) {
+	// derSignature encodes R and S as an ASN.1 DER ECDSA signature
+	derSignature := func(r, s *big.Int) []byte {
+		der, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+		require.NoError(t, err)
+		return der
+	}
+
+	one, big66 := big.NewInt(1), new(big.Int).Lsh(big.NewInt(1), 8*66)
+	tests := []struct {
+		description string
+		alg         string
+		der         []byte
+		expected    []byte
+		expectErr   bool
+	}{
+		{
+			description: "ES256",
+			alg:         "ES256",
+			der:         derSignature(big.NewInt(0x0102), big.NewInt(0x0304)),
+			expected:    append(append(make([]byte, 30), 0x01, 0x02), append(make([]byte, 30), 0x03, 0x04)...),
+		},
+		{
+			description: "ES512",
+			alg:         "ES512",
+			der:         derSignature(one, one),
+			expected:    append(append(make([]byte, 65), 0x01), append(make([]byte, 65), 0x01)...),
+		},
+		{
+			description: "not ECDSA",
+			alg:         "RS256",
+			der:         []byte("unchanged"),
+			expected:    []byte("unchanged"),
+		},
+		{
+			description: "malformed DER",
+			alg:         "ES256",
+			der:         []byte("not DER"),
+			expectErr:   true,
+		},
+		{
+			description: "trailing bytes",
+			alg:         "ES256",
+			der:         append(derSignature(one, one), 0x00),
+			expectErr:   true,
+		},
+		{
+			description: "zero R",
+			alg:         "ES256",
+			der:         derSignature(big.NewInt(0), one),
+			expectErr:   true,
+		},
+		{
+			description: "negative S",
+			alg:         "ES256",
+			der:         derSignature(one, big.NewInt(-1)),
+			expectErr:   true,
+		},
+		{
+			description: "R too large",
+			alg:         "ES512",
+			der:         derSignature(big66, one),
+			expectErr:   true,
+		},
+		{
+			description: "S too large for ES384",
+			alg:         "ES384",
+			der:         derSignature(one, new(big.Int).Lsh(one, 8*48)),
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			raw, err := ecdsaDERToRaw(tc.alg, tc.der)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, raw)
+		})
+	}
+}
+
+func TestNormalizeLowS(t *testing.T) {
+	n := elliptic.P256().Params().N
+	half := new(big.Int).Rsh(n, 1)
+	derSignature := func(r, s *big.Int) []byte {
+		der, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+		require.NoError(t, err)
+		return der
+	}
+
+	one := big.NewInt(1)
+	tests := []struct {
+		description string
+		der         []byte
+		expected    []byte
+		expectErr   bool
+	}{
+		{
+			description: "low S",
+			der:         derSignature(one, one),
+			expected:    derSignature(one, one),
+		},
+		{
+			description: "half the order",
+			der:         derSignature(one, half),
+			expected:    derSignature(one, half),
+		},
+		{
+			description: "high S",
+			der:         derSignature(one, new(big.Int).Sub(n, one)),
+			expected:    derSignature(one, one),
+		},
+		{
+			description: "just above half the order",
+			der:         derSignature(one, new(big.Int).Add(half, one)),
+			expected:    derSignature(one, new(big.Int).Sub(n, new(big.Int).Add(half, one))),
+		},
+		{
+			description: "malformed DER",
+			der:         []byte("not DER"),
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			der, err := normalizeLowS(tc.der, n)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, der)
+		})
+	}
+}
+
+func TestLowSECDSA(t *testing.T) {
+	tests := []struct {
+		description   string
+		curve         elliptic.Curve
+		deterministic bool
+	}{
+		{description: "P-256", curve: elliptic.P256()},
+		{description: "P-384", curve: elliptic.P384()},
+		{description: "P-521", curve: elliptic.P521()},
+		{description: "deterministic", curve: elliptic.P256(), deterministic: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			key, err := ecdsa.GenerateKey(tc.curve, rand.Reader)
+			require.NoError(t, err)
+
+			var signer crypto.Signer = key
+			if tc.deterministic {
+				signer = deterministicECDSA{key: key}
+			}
+
+			n := tc.curve.Params().N
+			signer = lowSECDSA{signer: signer, n: n}
+			assert.Equal(&key.PublicKey, signer.Public())
+
+			// random nonces give a high S about half the time, so enough
+			// signatures make it all but certain that one was normalized
+			for i := range 32 {
+				digest := sha256.Sum256([]byte{byte(i)})
+				der, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+				require.NoError(t, err)
+
+				var sig struct{ R, S *big.Int }
+				_, err = asn1.Unmarshal(der, &sig)
+				require.NoError(t, err)
+				assert.LessOrEqual(sig.S.Cmp(new(big.Int).Rsh(n, 1)), 0)
+				assert.True(ecdsa.VerifyASN1(&key.PublicKey, digest[:], der))
+			}
+		})
+	}
+}
+
+func TestSignHandlerLowSECDSA(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		size        int
+		n           *big.Int
+	}{
+		{
+			description: "P-256",
+			args:        []string{"--low-s-ecdsa"},
+			size:        32,
+			n:           elliptic.P256().Params().N,
+		},
+		{
+			description: "P-384",
+			args:        []string{"--low-s-ecdsa", "--key-curve=P-384"},
+			size:        48,
+			n:           elliptic.P384().Params().N,
+		},
+		{
+			description: "deterministic",
+			args:        []string{"--low-s-ecdsa", "--deterministic-ecdsa"},
+			size:        32,
+			n:           elliptic.P256().Params().N,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newTestServer(t, tc.args...)
+			for i := range 16 {
+				response := serve(h, http.MethodPut, "/sign", strings.NewReader("payload"+strconv.Itoa(i)), "Content-Type: text/plain")
+				require.Equal(t, http.StatusOK, response.Code)
+
+				parts := strings.Split(strings.TrimSpace(response.Body.String()), ".")
+				require.Len(t, parts, 3)
+
+				raw, err := base64.RawURLEncoding.DecodeString(parts[2])
+				require.NoError(t, err)
+				require.Len(t, raw, 2*tc.size)
+
+				s := new(big.Int).SetBytes(raw[tc.size:])
+				assert.LessOrEqual(t, s.Cmp(new(big.Int).Rsh(tc.n, 1)), 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwe"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// recipient is a single JWE recipient.
+type recipient struct {
+	kid string
+	alg jwa.KeyEncryptionAlgorithm
+	key jwk.Key
+}
+
+// Encrypter wraps signed tokens in a JWE addressed to multiple recipients, using the
+// general JSON serialization. Each recipient's copy of the content encryption key is
+// wrapped with that recipient's public key.
+type Encrypter struct {
+	logger     *zap.Logger
+	recipients []recipient
+}
+
+// keyEncryptionAlgOf determines the key encryption algorithm for a recipient key. A key
+// that declares its own alg uses that alg. Otherwise, the alg is inferred from the key type.
+func keyEncryptionAlgOf(k jwk.Key) (alg jwa.KeyEncryptionAlgorithm, err error) {
+	if declared, ok := k.Algorithm(); ok {
+		if alg, ok = declared.(jwa.KeyEncryptionAlgorithm); !ok {
+			err = fmt.Errorf("recipient alg %s is not a key encryption algorithm", declared)
+		}
+
+		return
+	}
+
+	switch k.KeyType() {
+	case jwa.RSA():
+		alg = jwa.RSA_OAEP_256()
+
+	case jwa.EC(), jwa.OKP():
+		alg = jwa.ECDH_ES_A256KW()
+
+	default:
+		err = fmt.Errorf("unsupported recipient key type: %s", k.KeyType())
+	}
+
+	return
+}
+
+func NewEncrypter(l *zap.Logger, cli CLI) (e *Encrypter, err error) {
+	e = &Encrypter{
+		logger: l,
+	}
+
+	if len(cli.Recipients) == 0 {
+		return
+	}
+
+	var set jwk.Set
+	set, err = jwk.ReadFile(cli.Recipients)
+	for i := 0; err == nil && i < set.Len(); i++ {
+		k, _ := set.Key(i)
+		r := recipient{key: k}
+		r.kid, _ = k.KeyID()
+		r.alg, err = keyEncryptionAlgOf(k)
+		e.recipients = append(e.recipients, r)
+	}
+
+	if err == nil {
+		e.logger.Info("encrypter",
+			zap.String("recipients", cli.Recipients),
+			zap.Int("count", len(e.recipients)),
+		)
+	}
+
+	return
+}
+
+// Enabled tests if this Encrypter has any recipients.
+func (e *Encrypter) Enabled() bool {
+	return len(e.recipients) > 0
+}
+
+// Encrypt produces a JWE general JSON serialization of the given signed token,
+// with one entry in recipients for each configured recipient.
+func (e *Encrypter) Encrypt(signed []byte) ([]byte, error) {
+	protected := jwe.NewHeaders()
+	protected.Set(jwe.ContentTypeKey, "JWT")
+
+	options := make([]jwe.EncryptOption, 0, len(e.recipients)+3)
+	options = append(options,
+		jwe.WithJSON(),
+		jwe.WithContentEncryption(jwa.A256GCM()),
+		jwe.WithProtectedHeaders(protected),
+	)
+
+	for _, r := range e.recipients {
+		h := jwe.NewHeaders()
+		if len(r.kid) > 0 {
+			h.Set(jwe.KeyIDKey, r.kid)
+		}
+
+		options = append(options, jwe.WithKey(r.alg, r.key, jwe.WithPerRecipientHeaders(h)))
+	}
+
+	return jwe.Encrypt(signed, options...)
+}
+
+func ProvideEncrypter() fx.Option {
+	return fx.Provide(
+		NewEncrypter,
+	)
+}
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v3/jwe"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newRecipientKey generates a private recipient key of the given kind.
+func newRecipientKey(t *testing.T, kind string) jwk.Key {
+	var (
+		raw any
+		err error
+	)
+
+	switch kind {
+	case "RSA":
+		raw, err = rsa.GenerateKey(rand.Reader, 2048)
+
+	case "EC":
+		raw, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	case "X25519":
+		raw, err = ecdh.X25519().GenerateKey(rand.Reader)
+
+	case "oct":
+		raw = []byte("01234567890123456789012345678901")
+	}
+
+	require.NoError(t, err)
+	k, err := jwk.Import(raw)
+	require.NoError(t, err)
+	require.NoError(t, k.Set(jwk.KeyIDKey, kind))
+	return k
+}
+
+// writeRecipients writes the public recipient keys to a JWK set file.
+func writeRecipients(t *testing.T, keys ...jwk.Key) string {
+	set := jwk.NewSet()
+	for _, k := range keys {
+		pk, err := k.PublicKey()
+		if err != nil {
+			// symmetric keys have no public key, so they are written as is
+			pk = k
+		}
+
+		require.NoError(t, set.AddKey(pk))
+	}
+
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "recipients.json")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func TestIssueHandlerRecipients(t *testing.T) {
+	tests := []struct {
+		description string
+		recipients  []string
+	}{
+		{description: "RSA", recipients: []string{"RSA"}},
+		{description: "EC", recipients: []string{"EC"}},
+		{description: "X25519", recipients: []string{"X25519"}},
+		{description: "every key type", recipients: []string{"RSA", "EC", "X25519"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			keys := make([]jwk.Key, 0, len(tc.recipients))
+			for _, kind := range tc.recipients {
+				keys = append(keys, newRecipientKey(t, kind))
+			}
+
+			h := newTestServer(t, "--recipients="+writeRecipients(t, keys...))
+			response := serve(h, http.MethodPost, "/issue", nil)
+			require.Equal(t, http.StatusOK, response.Code)
+			assert.Equal("application/jose+json", response.Header().Get("Content-Type"))
+
+			msg, err := jwe.Parse(response.Body.Bytes())
+			require.NoError(t, err)
+			assert.Len(msg.Recipients(), len(keys))
+
+			set := publishedKeys(t, h)
+			for _, k := range keys {
+				alg, err := keyEncryptionAlgOf(k)
+				require.NoError(t, err)
+
+				signed, err := jwe.Decrypt(response.Body.Bytes(), jwe.WithKey(alg, k))
+				require.NoError(t, err)
+
+				_, err = jws.Verify(signed, jws.WithKeySet(set, jws.WithInferAlgorithmFromKey(true)))
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestNewEncrypterInvalidRecipient(t *testing.T) {
+	path := writeRecipients(t, newRecipientKey(t, "oct"))
+	_, err := NewEncrypter(zap.NewNop(), newTestCLI(t, "--recipients="+path))
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// ExportHandlerIn defines the dependencies necessary to create an ExportHandler.
+type ExportHandlerIn struct {
+	fx.In
+
+	Logger        *zap.Logger
+	KeyAccessor   *KeyAccessor
+	KeyStore      KeyStore
+	MultiSignKeys MultiSignKeys `optional:"true"`
+	SignKey       *SignKey      `optional:"true"`
+	IssueKeys     IssueKeys     `optional:"true"`
+}
+
+// ExportHandler renders every key in the KeyStore as a JWK set for backup and migration.
+//
+// The KeyStore only ever holds public material. Private material is included for the
+// keys that this process holds privately, i.e. the pool of current signing keys and
+// any additional current keys.
+type ExportHandler struct {
+	logger       *zap.Logger
+	keyStore     KeyStore
+	keyAccessors []*KeyAccessor
+}
+
+func NewExportHandler(in ExportHandlerIn) *ExportHandler {
+	eh := &ExportHandler{
+		logger:       in.Logger,
+		keyStore:     in.KeyStore,
+		keyAccessors: []*KeyAccessor{in.KeyAccessor},
+	}
+
+	for _, ak := range in.MultiSignKeys {
+		eh.keyAccessors = append(eh.keyAccessors, ak.KeyAccessor)
+	}
+
+	if in.SignKey != nil {
+		eh.keyAccessors = append(eh.keyAccessors, in.SignKey.KeyAccessor)
+	}
+
+	for _, ak := range in.IssueKeys {
+		eh.keyAccessors = append(eh.keyAccessors, ak.KeyAccessor)
+	}
+
+	return eh
+}
+
+// privateKeys returns the private keys held by this process, keyed by kid.
+func (eh *ExportHandler) privateKeys() map[string]Key {
+	private := make(map[string]Key, len(eh.keyAccessors))
+	for _, ka := range eh.keyAccessors {
+		pool, _ := ka.LoadAll()
+		for _, k := range pool {
+			private[k.KID] = k
+		}
+	}
+
+	return private
+}
+
+func (eh *ExportHandler) exportSet() (set jwk.Set, err error) {
+	var keys []Key
+	keys, err = eh.keyStore.LoadAll()
+
+	private := eh.privateKeys()
+	set = jwk.NewSet()
+	for i := 0; err == nil && i < len(keys); i++ {
+		k := keys[i]
+		if pk, ok := private[k.KID]; ok {
+			k = pk
+		}
+
+		var exported jwk.Key
+		if exported, err = k.ExportJWK(); err == nil {
+			err = set.AddKey(exported)
+		}
+	}
+
+	return
+}
+
+// ServeHTTP renders the exported key set. This handler refuses to serve anything
+// over plaintext HTTP, since the response carries private keys.
+func (eh *ExportHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.TLS == nil {
+		response.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var data []byte
+	set, err := eh.exportSet()
+	if err == nil {
+		data, err = json.Marshal(set)
+	}
+
+	if err == nil {
+		eh.logger.Warn("exported keys", zap.Int("count", set.Len()), zap.String("remoteAddr", request.RemoteAddr))
+		response.Header().Set("Cache-Control", "no-store")
+		writeBody(response, "application/jwk-set+json", data)
+	} else {
+		eh.logger.Error("unable to export keys", zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func ProvideExport() fx.Option {
+	return fx.Provide(
+		NewExportHandler,
+	)
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportHandler(t *testing.T) {
+	var (
+		s  *http.Server
+		ka *KeyAccessor
+		ks KeyStore
+	)
+
+	startTestApp(t, []string{"--admin-token=" + testAdminToken}, &s, &ka, &ks)
+	current, err := ka.Load()
+	require.NoError(t, err)
+
+	// a previous key exists only as public material in the store
+	previous, err := newTestKey(t).PublicKey()
+	require.NoError(t, err)
+	require.NoError(t, ks.Store(previous))
+
+	tests := []struct {
+		description    string
+		tls            bool
+		authorization  string
+		expectedStatus int
+	}{
+		{
+			description:    "unauthenticated",
+			tls:            true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "plaintext",
+			authorization:  "Bearer " + testAdminToken,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description:    "authenticated over TLS",
+			tls:            true,
+			authorization:  "Bearer " + testAdminToken,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			request := httptest.NewRequest(http.MethodGet, "/admin/export", nil)
+			if tc.tls {
+				request.TLS = new(tls.ConnectionState)
+			}
+
+			if len(tc.authorization) > 0 {
+				request.Header.Set("Authorization", tc.authorization)
+			}
+
+			response := httptest.NewRecorder()
+			s.Handler.ServeHTTP(response, request)
+			require.Equal(t, tc.expectedStatus, response.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal("no-store", response.Header().Get("Cache-Control"))
+			set, err := jwk.Parse(response.Body.Bytes())
+			require.NoError(t, err)
+			assert.Equal(2, set.Len())
+
+			exported, ok := set.LookupKeyID(current.KID)
+			require.True(t, ok)
+			private, err := jwk.IsPrivateKey(exported)
+			assert.NoError(err)
+			assert.True(private)
+			assert.True(exported.Has(CreatedParameter))
+
+			exported, ok = set.LookupKeyID(previous.KID)
+			require.True(t, ok)
+			private, err = jwk.IsPrivateKey(exported)
+			assert.NoError(err)
+			assert.False(private)
+		})
+	}
+}
@@ -0,0 +1,294 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"go.uber.org/zap"
+)
+
+const (
+	// fileKeyStoreExt is the extension of each key file in a FileKeyStore.
+	fileKeyStoreExt = ".jwk"
+
+	// fileKeyStoreCurrent is the file, within a FileKeyStore's directory, that holds
+	// the current signing key. Its extension keeps it out of LoadAll.
+	fileKeyStoreCurrent = "current.key"
+
+	// fileKeyStoreRotationLock is the file, within a FileKeyStore's directory, that holds
+	// the expiry of the rotation lock as epoch nanoseconds.
+	fileKeyStoreRotationLock = "rotation.lock"
+)
+
+var (
+	// ErrNotPublicKey indicates that a key with private or symmetric material was
+	// passed to a KeyStore that only persists public keys.
+	ErrNotPublicKey = errors.New("only public keys may be persisted")
+)
+
+// FileKeyStore is a KeyStore that persists each key as a JWK file, named by its kid,
+// in a directory. Keys survive restarts, so tokens signed before a deploy still
+// verify after it. Only public keys are persisted, since the files may be read by
+// anything with access to the directory.
+//
+// A FileKeyStore is also a CurrentKeyStore. The current key, with its private
+// material, is kept in a separate file that only the owner may read.
+//
+// A FileKeyStore is also a RotationLocker, so replicas that share the directory,
+// e.g. over a shared volume, rotate at most once per lock.
+type FileKeyStore struct {
+	logger *zap.Logger
+	dir    string
+	codec  JSONKeyCodec
+	lock   sync.RWMutex
+	now    func() time.Time
+}
+
+func NewFileKeyStore(l *zap.Logger, dir string) *FileKeyStore {
+	return &FileKeyStore{
+		logger: l,
+		dir:    dir,
+		now:    time.Now,
+	}
+}
+
+// path returns the file that holds the key with the given kid. A kid that can't
+// be used as a file name, e.g. because it contains a path separator, yields false.
+func (s *FileKeyStore) path(kid string) (string, bool) {
+	if len(kid) == 0 || kid == "." || kid == ".." || strings.ContainsAny(kid, `/\`) {
+		return "", false
+	}
+
+	return filepath.Join(s.dir, kid+fileKeyStoreExt), true
+}
+
+// Store writes the key's file, replacing any existing file for the same kid.
+func (s *FileKeyStore) Store(k Key) error {
+	path, ok := s.path(k.KID)
+	if !ok {
+		return fmt.Errorf("invalid kid for a file key store: %q", k.KID)
+	}
+
+	private, privateErr := jwk.IsPrivateKey(k.Key)
+	if privateErr != nil || private {
+		return fmt.Errorf("%w: %s", ErrNotPublicKey, k.KID)
+	}
+
+	return s.write(k, path, 0o644)
+}
+
+// StoreCurrent writes the current key, including its private material, to a
+// file readable only by its owner.
+func (s *FileKeyStore) StoreCurrent(k Key) error {
+	return s.write(k, filepath.Join(s.dir, fileKeyStoreCurrent), 0o600)
+}
+
+// LoadCurrent reads the key last passed to StoreCurrent. If there is no such key,
+// this method returns ErrNoCurrentKey.
+func (s *FileKeyStore) LoadCurrent() (k Key, err error) {
+	s.lock.RLock()
+	k, err = s.load(filepath.Join(s.dir, fileKeyStoreCurrent))
+	s.lock.RUnlock()
+
+	if errors.Is(err, fs.ErrNotExist) {
+		err = ErrNoCurrentKey
+	}
+
+	return
+}
+
+// write encodes a key to the given path with the given permissions. The key is
+// written to a temporary file first, so that a reader never sees a partial key.
+func (s *FileKeyStore) write(k Key, path string, perm os.FileMode) (err error) {
+	var data []byte
+	data, err = s.codec.Encode(k)
+
+	var f *os.File
+	if err == nil {
+		// CreateTemp uses 0600, so private material is never readable by others
+		f, err = os.CreateTemp(s.dir, "."+filepath.Base(path)+"-*")
+	}
+
+	if err == nil {
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err == nil {
+			err = os.Chmod(f.Name(), perm)
+		}
+
+		s.lock.Lock()
+		if err == nil {
+			err = os.Rename(f.Name(), path)
+		}
+
+		s.lock.Unlock()
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}
+
+	return
+}
+
+// load reads and decodes a single key file.
+func (s *FileKeyStore) load(path string) (k Key, err error) {
+	var data []byte
+	data, err = os.ReadFile(path)
+	if err == nil {
+		k, err = s.codec.Decode(data)
+	}
+
+	return
+}
+
+func (s *FileKeyStore) Load(kid string) (k Key, err error) {
+	path, ok := s.path(kid)
+	if !ok {
+		err = ErrNoSuchKey
+		return
+	}
+
+	s.lock.RLock()
+	k, err = s.load(path)
+	s.lock.RUnlock()
+
+	if errors.Is(err, fs.ErrNotExist) {
+		err = ErrNoSuchKey
+	}
+
+	return
+}
+
+// LoadAll loads every key file in the directory. A file that can't be read or
+// decoded, e.g. one left corrupt by a full disk, is logged and skipped, so that
+// one bad file can't take down the whole key set.
+func (s *FileKeyStore) LoadAll() (ks []Key, err error) {
+	defer s.lock.RUnlock()
+	s.lock.RLock()
+
+	var paths []string
+	paths, err = filepath.Glob(filepath.Join(s.dir, "*"+fileKeyStoreExt))
+	ks = make([]Key, 0, len(paths))
+	for _, path := range paths {
+		if k, loadErr := s.load(path); loadErr == nil {
+			ks = append(ks, k)
+		} else {
+			s.logger.Error("skipping unloadable key file", zap.String("path", path), zap.Error(loadErr))
+		}
+	}
+
+	return
+}
+
+func (s *FileKeyStore) Delete(kid string) (err error) {
+	path, ok := s.path(kid)
+	if !ok {
+		return ErrNoSuchKey
+	}
+
+	s.lock.Lock()
+	err = os.Remove(path)
+	s.lock.Unlock()
+
+	if errors.Is(err, fs.ErrNotExist) {
+		err = ErrNoSuchKey
+	}
+
+	return
+}
+
+// TryLockRotation implements RotationLocker with a lock file. The lock is created
+// with a hard link, which fails if the lock already exists, so only one replica can
+// create it. An expired lock is first moved aside, so that only one replica can take
+// it over.
+func (s *FileKeyStore) TryLockRotation(ttl time.Duration) (acquired bool, err error) {
+	path := filepath.Join(s.dir, fileKeyStoreRotationLock)
+	now := s.now()
+	data := strconv.AppendInt(nil, now.Add(ttl).UnixNano(), 10)
+	acquired, err = s.createLock(path, data)
+	if err != nil || acquired {
+		return
+	}
+
+	var f *os.File
+	if f, err = os.CreateTemp(s.dir, "."+fileKeyStoreRotationLock+"-stale-*"); err != nil {
+		return
+	}
+
+	f.Close()
+	stale := f.Name()
+	defer os.Remove(stale)
+
+	if os.Rename(path, stale) != nil {
+		// another replica moved the lock first, and is taking it over
+		return
+	}
+
+	expires, parseErr := readLockExpiry(stale)
+	if parseErr == nil && now.Before(expires) {
+		// the lock was taken over between the link and the rename, so put it back
+		os.Link(stale, path)
+		return
+	}
+
+	return s.createLock(path, data)
+}
+
+// createLock atomically creates the lock file with the given contents. If the lock
+// file already exists, this method returns false.
+func (s *FileKeyStore) createLock(path string, data []byte) (acquired bool, err error) {
+	var f *os.File
+	f, err = os.CreateTemp(s.dir, "."+fileKeyStoreRotationLock+"-*")
+	if err != nil {
+		return
+	}
+
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Link(f.Name(), path)
+		acquired = err == nil
+		if errors.Is(err, fs.ErrExist) {
+			err = nil
+		}
+	}
+
+	return
+}
+
+// readLockExpiry reads the expiry from a rotation lock file.
+func readLockExpiry(path string) (expires time.Time, err error) {
+	var (
+		data  []byte
+		nanos int64
+	)
+
+	data, err = os.ReadFile(path)
+	if err == nil {
+		nanos, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+
+	if err == nil {
+		expires = time.Unix(0, nanos)
+	}
+
+	return
+}
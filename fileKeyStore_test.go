@@ -0,0 +1,277 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFileKeyStore(t *testing.T) {
+	tests := []struct {
+		description string
+		kid         string
+		private     bool
+		expectedErr error
+	}{
+		{
+			description: "public key",
+		},
+		{
+			description: "private key",
+			private:     true,
+			expectedErr: ErrNotPublicKey,
+		},
+		{
+			description: "path separator",
+			kid:         "../escape",
+		},
+		{
+			description: "backslash",
+			kid:         `a\b`,
+		},
+		{
+			description: "dot dot",
+			kid:         "..",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			dir := t.TempDir()
+			s := NewFileKeyStore(zap.NewNop(), dir)
+
+			k := newTestKey(t)
+			if !tc.private {
+				var err error
+				k, err = k.PublicKey()
+				require.NoError(t, err)
+			}
+
+			if len(tc.kid) > 0 {
+				k.KID = tc.kid
+			}
+
+			err := s.Store(k)
+			switch {
+			case tc.expectedErr != nil:
+				assert.ErrorIs(err, tc.expectedErr)
+
+			case len(tc.kid) > 0:
+				// an invalid kid is neither stored nor found
+				assert.Error(err)
+				_, err = s.Load(tc.kid)
+				assert.ErrorIs(err, ErrNoSuchKey)
+				assert.ErrorIs(s.Delete(tc.kid), ErrNoSuchKey)
+
+			default:
+				require.NoError(t, err)
+				loaded, err := s.Load(k.KID)
+				require.NoError(t, err)
+				assert.Equal(k.KID, loaded.KID)
+				assert.True(k.Expires.Truncate(time.Second).Equal(loaded.Expires), "expires is kept to the second")
+
+				info, err := os.Stat(filepath.Join(dir, k.KID+fileKeyStoreExt))
+				require.NoError(t, err)
+				assert.Equal(os.FileMode(0o644), info.Mode().Perm())
+
+				require.NoError(t, s.Delete(k.KID))
+				_, err = s.Load(k.KID)
+				assert.ErrorIs(err, ErrNoSuchKey)
+				assert.ErrorIs(s.Delete(k.KID), ErrNoSuchKey)
+			}
+
+			keys, err := s.LoadAll()
+			require.NoError(t, err)
+			assert.Empty(keys)
+		})
+	}
+}
+
+func TestFileKeyStoreLoadAll(t *testing.T) {
+	tests := []struct {
+		description string
+		keys        int
+
+		// files are extra files written to the directory, by name.
+		files          map[string]string
+		current        bool
+		expectedKeys   int
+		expectedErrors int
+	}{
+		{
+			description: "empty",
+		},
+		{
+			description:  "keys",
+			keys:         2,
+			expectedKeys: 2,
+		},
+		{
+			description:  "current key excluded",
+			keys:         1,
+			current:      true,
+			expectedKeys: 1,
+		},
+		{
+			description:  "other files ignored",
+			keys:         1,
+			files:        map[string]string{"notes.txt": "notes", ".key.jwk-123": "partial"},
+			expectedKeys: 1,
+		},
+		{
+			description:    "corrupt files skipped",
+			keys:           2,
+			files:          map[string]string{"corrupt.jwk": "{", "empty.jwk": ""},
+			expectedKeys:   2,
+			expectedErrors: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			dir := t.TempDir()
+			core, logs := observer.New(zap.ErrorLevel)
+			s := NewFileKeyStore(zap.New(core), dir)
+
+			for range tc.keys {
+				k, err := newTestKey(t).PublicKey()
+				require.NoError(t, err)
+				require.NoError(t, s.Store(k))
+			}
+
+			if tc.current {
+				require.NoError(t, s.StoreCurrent(newTestKey(t)))
+			}
+
+			for name, contents := range tc.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644))
+			}
+
+			keys, err := s.LoadAll()
+			require.NoError(t, err)
+			assert.Len(keys, tc.expectedKeys)
+			assert.Equal(tc.expectedErrors, logs.FilterMessage("skipping unloadable key file").Len())
+		})
+	}
+}
+
+func TestFileKeyStoreCurrent(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	s := NewFileKeyStore(zap.NewNop(), dir)
+
+	_, err := s.LoadCurrent()
+	assert.ErrorIs(err, ErrNoCurrentKey)
+
+	for range 2 {
+		current := newTestKey(t)
+		require.NoError(t, s.StoreCurrent(current))
+
+		loaded, err := s.LoadCurrent()
+		require.NoError(t, err)
+		assert.Equal(current.KID, loaded.KID)
+
+		private, err := jwk.IsPrivateKey(loaded.Key)
+		require.NoError(t, err)
+		assert.True(private)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, fileKeyStoreCurrent))
+	require.NoError(t, err)
+	assert.Equal(os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestFileKeyStoreTryLockRotation(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		description string
+
+		// lock, when set, is the contents of an existing lock file.
+		lock             string
+		expectedAcquired bool
+	}{
+		{
+			description:      "no lock",
+			expectedAcquired: true,
+		},
+		{
+			description: "held",
+			lock:        strconv.FormatInt(now.Add(time.Minute).UnixNano(), 10),
+		},
+		{
+			description:      "expired",
+			lock:             strconv.FormatInt(now.Add(-time.Minute).UnixNano(), 10),
+			expectedAcquired: true,
+		},
+		{
+			description:      "corrupt",
+			lock:             "not a time",
+			expectedAcquired: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			dir := t.TempDir()
+			path := filepath.Join(dir, fileKeyStoreRotationLock)
+			if len(tc.lock) > 0 {
+				require.NoError(t, os.WriteFile(path, []byte(tc.lock), 0o644))
+			}
+
+			s := NewFileKeyStore(zap.NewNop(), dir)
+			s.now = func() time.Time { return now }
+
+			acquired, err := s.TryLockRotation(time.Hour)
+			require.NoError(t, err)
+			assert.Equal(tc.expectedAcquired, acquired)
+
+			expires, err := readLockExpiry(path)
+			require.NoError(t, err)
+			if tc.expectedAcquired {
+				assert.True(now.Add(time.Hour).Equal(expires))
+			} else {
+				assert.Equal(tc.lock, strconv.FormatInt(expires.UnixNano(), 10))
+			}
+
+			// the lock is now held, so it can't be acquired again
+			acquired, err = s.TryLockRotation(time.Hour)
+			require.NoError(t, err)
+			assert.False(acquired)
+
+			// only the lock file is left behind
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Len(entries, 1)
+		})
+	}
+}
+
+func TestServerFileKeyStoreRestart(t *testing.T) {
+	args := []string{"--keystore=file", "--keystore-dir=" + t.TempDir()}
+
+	var s *http.Server
+	app := startTestApp(t, args, &s)
+	token := issueToken(t, s.Handler, "")
+	app.RequireStop()
+
+	h := newTestServer(t, args...)
+	response := serve(h, http.MethodPost, "/verify", strings.NewReader(token))
+	assert.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, 2, publishedKeys(t, h).Len())
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// HealthAttestationHeader is the /readyz response header that carries a signed
+	// token attesting the reported status, which gateways verify against /keys.
+	HealthAttestationHeader = "X-Health-Attestation"
+
+	// HealthAttestationType is the typ header for health attestation tokens.
+	HealthAttestationType = "health+jwt"
+
+	// HealthAttestationAudience is the aud of every health attestation token, so that
+	// relying parties that check the audience never accept one as an access token.
+	HealthAttestationAudience = "utu-health"
+
+	// HealthStatusClaim is the claim of a health attestation token that holds the
+	// attested status, either HealthStatusReady or HealthStatusUnavailable.
+	HealthStatusClaim = "status"
+
+	HealthStatusReady       = "ready"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// attestation is a signed health attestation token, cached until its refresh time.
+type attestation struct {
+	signed  []byte
+	refresh time.Time
+}
+
+// ReadyHandler reports whether this server is ready to issue tokens. A server
+// whose key rotation is overdue is not ready, since its rotator may be wedged.
+type ReadyHandler struct {
+	logger  *zap.Logger
+	rotator *Rotator
+	issuer  *Issuer
+	signer  *Signer
+	now     func() time.Time
+
+	// attestation, when positive, is the lifetime of the signed token that attests
+	// each response's status.
+	attestation time.Duration
+
+	// attestations caches the attestation for each status. /readyz is unauthenticated,
+	// so caching bounds how often it signs, no matter how often it is polled.
+	lock         sync.Mutex
+	attestations map[string]attestation
+}
+
+func NewReadyHandler(l *zap.Logger, rotator *Rotator, issuer *Issuer, signer *Signer, cli CLI) *ReadyHandler {
+	return &ReadyHandler{
+		logger:      l,
+		rotator:     rotator,
+		issuer:      issuer,
+		signer:      signer,
+		now:         time.Now,
+		attestation: cli.HealthAttestation,
+
+		attestations: make(map[string]attestation, 2),
+	}
+}
+
+// attest produces a signed token attesting the given status. The token expires
+// quickly, so that a captured attestation can't be replayed for long. The token is
+// cached for half its lifetime, so that the attestations a gateway receives are never
+// close to expiring.
+//
+// Claims follow the Issuer's claim map, and the iss is omitted for minimal tokens.
+// The aud is always HealthAttestationAudience, since it is what keeps an attestation
+// from being mistaken for an access token.
+func (rh *ReadyHandler) attest(status string) (signed []byte, err error) {
+	now := rh.now().UTC()
+	defer rh.lock.Unlock()
+	rh.lock.Lock()
+	if cached, ok := rh.attestations[status]; ok && now.Before(cached.refresh) {
+		return cached.signed, nil
+	}
+
+	var (
+		jti string
+		t   jwt.Token
+	)
+
+	jti, err = rh.issuer.generateID()
+	if err == nil {
+		i, b := rh.issuer, jwt.NewBuilder()
+		i.claim(b, jwt.JwtIDKey, jti)
+		if !i.minimal {
+			i.claim(b, jwt.IssuerKey, i.iss)
+		}
+
+		i.claim(b, jwt.AudienceKey, []string{HealthAttestationAudience})
+		i.claim(b, jwt.IssuedAtKey, i.timeClaim(jwt.IssuedAtKey, now))
+		i.claim(b, jwt.ExpirationKey, i.timeClaim(jwt.ExpirationKey, now.Add(rh.attestation)))
+		i.claim(b, HealthStatusClaim, status)
+		t, err = b.Build()
+	}
+
+	if err == nil {
+		signed, err = rh.signer.SignTokenWithType(t, HealthAttestationType)
+	}
+
+	if err == nil {
+		rh.attestations[status] = attestation{
+			signed:  signed,
+			refresh: now.Add(rh.attestation / 2),
+		}
+	}
+
+	return
+}
+
+// writeStatus writes a readiness response, along with its attestation when configured.
+// If the status can't be attested, the response is a 503, since a gateway that requires
+// attestations can't trust it anyway.
+func (rh *ReadyHandler) writeStatus(response http.ResponseWriter, statusCode int, status, body string) {
+	if rh.attestation > 0 {
+		signed, err := rh.attest(status)
+		if err == nil {
+			response.Header().Set(HealthAttestationHeader, string(signed))
+		} else {
+			rh.logger.Error("unable to attest health", zap.Error(err))
+			statusCode, body = http.StatusServiceUnavailable, "unable to attest health"
+		}
+	}
+
+	response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+	response.WriteHeader(statusCode)
+	response.Write([]byte(body))
+}
+
+func (rh *ReadyHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if rh.rotator.Overdue() {
+		rh.logger.Warn("key rotation is overdue", zap.Time("lastRotation", rh.rotator.LastRotation()))
+		rh.writeStatus(response, http.StatusServiceUnavailable, HealthStatusUnavailable, "key rotation overdue")
+		return
+	}
+
+	rh.writeStatus(response, http.StatusOK, HealthStatusReady, "ok")
+}
+
+func ProvideHealth() fx.Option {
+	return fx.Provide(
+		NewReadyHandler,
+	)
+}
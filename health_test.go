@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadyHandler(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// elapsed is how long after the last rotation the readiness check runs.
+		elapsed         time.Duration
+		expectedStatus  int
+		expectedOverdue float64
+	}{
+		{
+			description:    "fresh rotation",
+			args:           []string{"--key-rotate=1h"},
+			elapsed:        time.Hour,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "at the threshold",
+			args:           []string{"--key-rotate=1h"},
+			elapsed:        2 * time.Hour,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:     "overdue rotation",
+			args:            []string{"--key-rotate=1h"},
+			elapsed:         2*time.Hour + time.Second,
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedOverdue: 1,
+		},
+		{
+			description:     "custom overdue factor",
+			args:            []string{"--key-rotate=1h", "--rotation-overdue-factor=1.5"},
+			elapsed:         90*time.Minute + time.Second,
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedOverdue: 1,
+		},
+		{
+			description:    "overdue detection disabled",
+			args:           []string{"--key-rotate=1h", "--rotation-overdue-factor=0"},
+			elapsed:        100 * time.Hour,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				s *http.Server
+				r *Rotator
+				g prometheus.Gatherer
+			)
+
+			startTestApp(t, tc.args, &s, &r, &g)
+			last := r.LastRotation()
+			require.False(t, last.IsZero())
+			r.now = func() time.Time { return last.Add(tc.elapsed) }
+
+			response := serve(s.Handler, http.MethodGet, "/readyz", nil)
+			assert.Equal(tc.expectedStatus, response.Code)
+
+			overdue := gatherMetric(t, g, "key_rotation_overdue").GetMetric()[0].GetGauge().GetValue()
+			assert.Equal(tc.expectedOverdue, overdue)
+
+			timestamp := gatherMetric(t, g, "key_last_rotation_timestamp_seconds").GetMetric()[0].GetGauge().GetValue()
+			assert.InDelta(float64(last.UnixNano())/float64(time.Second), timestamp, 0.001)
+		})
+	}
+}
+
+func TestReadyHandlerAttestation(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		overdue     bool
+
+		// expectedClaims are the attested claims, and expectedAbsent are the claims
+		// that must not be attested. No claims means no attestation.
+		expectedClaims map[string]any
+		expectedAbsent []string
+
+		// jtiClaim is the claim expected to hold the jti, when it isn't jti.
+		jtiClaim string
+	}{
+		{
+			description: "disabled",
+		},
+		{
+			description: "ready",
+			args:        []string{"--health-attestation=1m", "--issuer=the-issuer"},
+			expectedClaims: map[string]any{
+				"iss":             "the-issuer",
+				"aud":             []any{HealthAttestationAudience},
+				HealthStatusClaim: HealthStatusReady,
+			},
+		},
+		{
+			description: "unavailable",
+			args:        []string{"--health-attestation=1m", "--key-rotate=1h"},
+			overdue:     true,
+			expectedClaims: map[string]any{
+				HealthStatusClaim: HealthStatusUnavailable,
+			},
+		},
+		{
+			description: "claim map",
+			args:        []string{"--health-attestation=1m", "--claim-map=jti=token_id"},
+			expectedClaims: map[string]any{
+				HealthStatusClaim: HealthStatusReady,
+			},
+			expectedAbsent: []string{"jti"},
+			jtiClaim:       "token_id",
+		},
+		{
+			description: "minimal token",
+			args:        []string{"--health-attestation=1m", "--minimal-token", "--audience=a"},
+			expectedClaims: map[string]any{
+				"aud":             []any{HealthAttestationAudience},
+				HealthStatusClaim: HealthStatusReady,
+			},
+			expectedAbsent: []string{"iss"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				s *http.Server
+				r *Rotator
+			)
+
+			startTestApp(t, tc.args, &s, &r)
+			if tc.overdue {
+				last := r.LastRotation()
+				r.now = func() time.Time { return last.Add(100 * time.Hour) }
+			}
+
+			response := serve(s.Handler, http.MethodGet, "/readyz", nil)
+			attestation := response.Header().Get(HealthAttestationHeader)
+			if len(tc.expectedClaims) == 0 {
+				assert.Empty(attestation)
+				return
+			}
+
+			require.NotEmpty(t, attestation)
+			assert.Equal(HealthAttestationType, tokenHeader(t, attestation)["typ"])
+
+			_, err := jws.Verify([]byte(attestation), jws.WithKeySet(publishedKeys(t, s.Handler), jws.WithInferAlgorithmFromKey(true)))
+			require.NoError(t, err)
+
+			claims := tokenClaims(t, attestation)
+			for name, value := range tc.expectedClaims {
+				assert.Equal(value, claims[name], name)
+			}
+
+			for _, name := range tc.expectedAbsent {
+				assert.NotContains(claims, name)
+			}
+
+			jtiClaim := "jti"
+			if len(tc.jtiClaim) > 0 {
+				jtiClaim = tc.jtiClaim
+			}
+
+			assert.NotEmpty(claims[jtiClaim])
+
+			assert.Equal(float64(60), claims["exp"].(float64)-claims["iat"].(float64))
+		})
+	}
+}
+
+func TestReadyHandlerAttestationCache(t *testing.T) {
+	tests := []struct {
+		description string
+
+		// elapsed is how long after the first attestation the second is requested.
+		elapsed       time.Duration
+		status        string
+		expectedReuse bool
+	}{
+		{
+			description:   "reused",
+			elapsed:       29 * time.Second,
+			status:        HealthStatusReady,
+			expectedReuse: true,
+		},
+		{
+			description: "refreshed after half its lifetime",
+			elapsed:     30 * time.Second,
+			status:      HealthStatusReady,
+		},
+		{
+			description: "cached per status",
+			status:      HealthStatusUnavailable,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var rh *ReadyHandler
+			startTestApp(t, []string{"--health-attestation=1m"}, &rh)
+
+			now := time.Now()
+			rh.now = func() time.Time { return now }
+			first, err := rh.attest(HealthStatusReady)
+			require.NoError(t, err)
+
+			now = now.Add(tc.elapsed)
+			second, err := rh.attest(tc.status)
+			require.NoError(t, err)
+
+			if tc.expectedReuse {
+				assert.Equal(t, first, second)
+			} else {
+				assert.NotEqual(t, first, second)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+	"go.uber.org/zap"
+)
+
+// newTestCLI parses and validates the given command line.
+func newTestCLI(t *testing.T, args ...string) CLI {
+	cli, _, err := NewCLI(args)
+	require.NoError(t, err)
+	return cli
+}
+
+// startTestApp starts the server on a loopback port using the given command line
+// and populates each target. The app stops when the test ends, unless the test
+// has already stopped it.
+func startTestApp(t *testing.T, args []string, targets ...any) *fxtest.App {
+	return startTestAppWith(t, args, nil, targets...)
+}
+
+// startTestAppWith is like startTestApp, but adds the given options to the app,
+// e.g. to decorate or replace a component.
+func startTestAppWith(t *testing.T, args []string, options []fx.Option, targets ...any) *fxtest.App {
+	cli, kctx, err := NewCLI(append([]string{"--address=127.0.0.1:0"}, args...))
+	require.NoError(t, err)
+
+	app := fxtest.New(
+		t,
+		fx.NopLogger,
+		fx.Supply(cli, kctx, zap.NewNop()),
+		ProvideMetrics(),
+		ProvideKeyAccessor(),
+		ProvideKeyStore(),
+		ProvideBlacklistStore(),
+		ProvideRandomSource(),
+		ProvideIDGenerator(),
+		ProvideKeyGenerator(),
+		ProvideSigner(),
+		ProvideEncrypter(),
+		ProvidePostIssue(),
+		ProvideIssuer(),
+		ProvidePublisher(),
+		ProvideRotator(),
+		ProvideSelfTest(),
+		ProvideSwagger(),
+		ProvideExport(),
+		ProvideVerifier(),
+		ProvideLogout(),
+		ProvideInfo(),
+		ProvideServerMetadata(),
+		ProvideHealth(),
+		ProvideAlgorithms(),
+		ProvideAdminAuth(),
+		ProvideServer(),
+		fx.Options(options...),
+		fx.Populate(targets...),
+	)
+
+	app.RequireStart()
+	t.Cleanup(app.RequireStop)
+	return app
+}
+
+// newTestServer starts the server using the given command line and returns its handler.
+func newTestServer(t *testing.T, args ...string) http.Handler {
+	var s *http.Server
+	startTestApp(t, args, &s)
+	return s.Handler
+}
+
+// newTestIssuer creates an Issuer, without a Verifier, from the given command line.
+func newTestIssuer(t *testing.T, args ...string) (*Issuer, error) {
+	return NewIssuer(zap.NewNop(), NewIDGenerator(NewRandomSource()), NewRandomSource(), nil, newTestCLI(t, args...))
+}
+
+// newTestIssueRequest creates the IssueRequest for a POST to /issue with the given
+// form-encoded body.
+func newTestIssueRequest(i *Issuer, form string) (IssueRequest, error) {
+	request := httptest.NewRequest(http.MethodPost, "/issue", strings.NewReader(form))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return i.NewIssueRequest(request)
+}
+
+// tokenMap returns the claims of an unsigned token as a map.
+func tokenMap(t *testing.T, token jwt.Token) (claims map[string]any) {
+	data, err := json.Marshal(token)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &claims))
+	return
+}
+
+// gatherMetric returns the metric family with the given name, without the metrics
+// namespace, from the given Gatherer.
+func gatherMetric(t *testing.T, g prometheus.Gatherer, name string) *dto.MetricFamily {
+	families, err := g.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == MetricsNamespace+"_"+name {
+			return family
+		}
+	}
+
+	require.Failf(t, "no such metric", "metric %s was not gathered", name)
+	return nil
+}
+
+// newTestKey generates a key with the key generator configured by the given command line.
+func newTestKey(t *testing.T, args ...string) Key {
+	kg, err := NewKeyGenerator(zap.NewNop(), NewIDGenerator(NewRandomSource()), NewRandomSource(), newTestCLI(t, args...))
+	require.NoError(t, err)
+
+	k, err := kg.Generate()
+	require.NoError(t, err)
+	return k
+}
+
+// testAdminToken is the admin token configured by tests that use admin routes, and
+// testAdminAuth is the Authorization header that presents it.
+const (
+	testAdminToken = "s3cret"
+	testAdminAuth  = "Authorization: Bearer " + testAdminToken
+)
+
+// serve sends a request to the given handler and returns the recorded response.
+// Each header is a "Name: value" pair.
+func serve(h http.Handler, method, target string, body io.Reader, headers ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, body)
+	for _, header := range headers {
+		name, value, _ := strings.Cut(header, ":")
+		request.Header.Add(name, strings.TrimSpace(value))
+	}
+
+	if body != nil && len(request.Header.Get("Content-Type")) == 0 {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	response := httptest.NewRecorder()
+	h.ServeHTTP(response, request)
+	return response
+}
+
+// issueToken issues a token from the given server handler with the given
+// form-encoded parameters.
+func issueToken(t *testing.T, h http.Handler, form string, headers ...string) string {
+	response := serve(h, http.MethodPost, "/issue", strings.NewReader(form), headers...)
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	return response.Body.String()
+}
+
+// publishedKeys fetches the key set published by the given server handler.
+func publishedKeys(t *testing.T, h http.Handler) jwk.Set {
+	response := serve(h, http.MethodGet, "/keys", nil)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	set, err := jwk.Parse(response.Body.Bytes())
+	require.NoError(t, err)
+	return set
+}
+
+// tokenClaims decodes the payload of a compact JWS without verifying it.
+func tokenClaims(t *testing.T, token string) (claims map[string]any) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	require.Len(t, parts, 3)
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	return
+}
+
+// tokenHeader decodes the protected header of a compact JWS.
+func tokenHeader(t *testing.T, token string) (header map[string]any) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	require.Len(t, parts, 3)
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &header))
+	return
+}
+
+// newTestCertificate creates a self-signed CA certificate and returns it along with
+// its PEM encoding.
+func newTestCertificate(t *testing.T) (*x509.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	c, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return c, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -4,7 +4,6 @@
 package main
 
 import (
-	"crypto/rand"
 	"encoding/base64"
 	"io"
 
@@ -24,9 +23,9 @@ func (idg *IDGenerator) Generate(size int) string {
 	return base64.RawURLEncoding.EncodeToString(raw)
 }
 
-func NewIDGenerator() *IDGenerator {
+func NewIDGenerator(random *RandomSource) *IDGenerator {
 	return &IDGenerator{
-		random: rand.Reader,
+		random: random,
 	}
 }
 
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var (
+	// version is the release version of this build, set via -ldflags.
+	version = "development"
+
+	// commit is the source revision of this build, set via -ldflags. When unset,
+	// the VCS revision embedded by the go toolchain is used, if any.
+	commit = ""
+)
+
+// buildCommit returns the source revision of this build.
+func buildCommit() string {
+	if len(commit) > 0 {
+		return commit
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+
+	return "unknown"
+}
+
+// Info describes the running server.
+type Info struct {
+	Version    string  `json:"version"`
+	Commit     string  `json:"commit"`
+	GoVersion  string  `json:"go_version"`
+	Started    string  `json:"started"`
+	Uptime     float64 `json:"uptime"`
+	CurrentKID string  `json:"current_kid,omitempty"`
+}
+
+// InfoHandler serves build and runtime information about this server.
+type InfoHandler struct {
+	logger      *zap.Logger
+	keyAccessor *KeyAccessor
+	commit      string
+	started     time.Time
+	now         func() time.Time
+}
+
+func NewInfoHandler(l *zap.Logger, keyAccessor *KeyAccessor) *InfoHandler {
+	return &InfoHandler{
+		logger:      l,
+		keyAccessor: keyAccessor,
+		commit:      buildCommit(),
+		started:     time.Now(),
+		now:         time.Now,
+	}
+}
+
+// ServeHTTP renders the Info for this server as JSON. The uptime is expressed in seconds.
+func (ih *InfoHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	info := Info{
+		Version:   version,
+		Commit:    ih.commit,
+		GoVersion: runtime.Version(),
+		Started:   ih.started.UTC().Format(time.RFC3339),
+		Uptime:    ih.now().Sub(ih.started).Seconds(),
+	}
+
+	if currentKey, err := ih.keyAccessor.Load(); err == nil {
+		info.CurrentKID = currentKey.KID
+	}
+
+	data, err := json.Marshal(info)
+	if err == nil {
+		writeBody(response, "application/json", data)
+	} else {
+		ih.logger.Error("unable to render info", zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func ProvideInfo() fx.Option {
+	return fx.Provide(
+		NewInfoHandler,
+	)
+}
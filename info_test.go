@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestInfoHandler(t *testing.T) {
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name       string
+		current    *Key
+		elapsed    time.Duration
+		commit     string
+		wantKID    string
+		wantUptime float64
+	}{
+		{
+			name:       "no current key",
+			elapsed:    90 * time.Second,
+			commit:     "abc123",
+			wantUptime: 90,
+		},
+		{
+			name:       "current key",
+			current:    &Key{KID: "test-kid"},
+			elapsed:    1500 * time.Millisecond,
+			commit:     "def456",
+			wantKID:    "test-kid",
+			wantUptime: 1.5,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ka := new(KeyAccessor)
+			if tc.current != nil {
+				ka.Store(*tc.current)
+			}
+
+			ih := NewInfoHandler(zap.NewNop(), ka)
+			ih.commit = tc.commit
+			ih.started = started
+			ih.now = func() time.Time { return started.Add(tc.elapsed) }
+
+			response := httptest.NewRecorder()
+			ih.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/info", nil))
+			require.Equal(t, http.StatusOK, response.Code)
+			assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+			var info Info
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &info))
+			assert.Equal(t, Info{
+				Version:    version,
+				Commit:     tc.commit,
+				GoVersion:  runtime.Version(),
+				Started:    "2025-01-02T03:04:05Z",
+				Uptime:     tc.wantUptime,
+				CurrentKID: tc.wantKID,
+			}, info)
+		})
+	}
+}
+
+func TestInfoRoute(t *testing.T) {
+	h := newTestServer(t)
+	response := serve(h, http.MethodGet, "/info", nil)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var info Info
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &info))
+	assert.NotEmpty(t, info.CurrentKID)
+	assert.Equal(t, version, info.Version)
+}
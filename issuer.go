@@ -4,20 +4,123 @@
 package main
 
 import (
-	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/lestrrat-go/jwx/v3/jws"
 	"github.com/lestrrat-go/jwx/v3/jwt"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+const (
+	// AuthTimeClaim is the OIDC claim that records when the end user authenticated.
+	AuthTimeClaim = "auth_time"
+
+	// SessionIDClaim is the claim that binds a token to a session, e.g. for back-channel logout.
+	SessionIDClaim = "sid"
+
+	// NonceClaim is the OIDC claim, and request parameter, that binds an id_token to the
+	// client's authentication request. The nonce of a request is echoed as is.
+	NonceClaim = "nonce"
+
+	// AudOverrideReplace is the audience override mode in which requested audiences
+	// replace the configured audience.
+	AudOverrideReplace = "replace"
+
+	// AudOverrideMerge is the audience override mode in which requested audiences
+	// are merged with the configured audience.
+	AudOverrideMerge = "merge"
+
+	// DeprecatedClaimWarn is the deprecated claim mode in which tokens with deprecated
+	// claims are still issued, with a Warning header describing each deprecated claim.
+	DeprecatedClaimWarn = "warn"
+
+	// DeprecatedClaimReject is the deprecated claim mode in which requests for
+	// deprecated claims are rejected.
+	DeprecatedClaimReject = "reject"
+
+	// TokenSizeWarn is the token size mode in which tokens over the size budget are
+	// still issued, with a Warning header.
+	TokenSizeWarn = "warn"
+
+	// TokenSizeReject is the token size mode in which requests for tokens over the
+	// size budget are rejected.
+	TokenSizeReject = "reject"
+
+	// ConfirmationClaim is the RFC 7800 claim that binds a token to a proof-of-possession key.
+	ConfirmationClaim = "cnf"
+
+	// X5TS256Member is the RFC 8705 confirmation member that carries the SHA-256
+	// thumbprint of the certificate a token is bound to.
+	X5TS256Member = "x5t#S256"
+
+	// ResourceParameter is the RFC 8707 request parameter that indicates the
+	// resource a token is intended for.
+	ResourceParameter = "resource"
+
+	// DebugParameter is the request parameter that asks /issue for the debug view of a token.
+	DebugParameter = "debug"
+
+	// ActorClaim is the RFC 8693 claim that identifies the party acting on behalf of the subject.
+	ActorClaim = "act"
+
+	// ActorSubjectParameter is the request parameter that supplies the subject of an actor.
+	ActorSubjectParameter = "act_sub"
+
+	// MaxActors is the longest actor chain that a single request may supply.
+	MaxActors = 8
+
+	// KeyTypeParameter is the request parameter that selects the key type, e.g. RSA,
+	// that signs the token.
+	KeyTypeParameter = "key_type"
+
+	// TokenExpiresHeader is the /issue response header that carries the token's exp as
+	// an RFC 3339 time, so that clients caching tokens needn't decode them.
+	TokenExpiresHeader = "X-Token-Expires"
+
+	// ClaimParameter is the request parameter that supplies a custom claim for
+	// a single token, as name=value.
+	ClaimParameter = "claim"
+
+	// SubjectTokenParameter is the RFC 8693 request parameter that supplies a token
+	// whose allow-listed claims are inherited by the issued token.
+	SubjectTokenParameter = "subject_token"
+
+	// ExpiresInParameter is the request parameter that asks for a specific token
+	// lifetime, in seconds.
+	ExpiresInParameter = "expires_in"
+
+	// ScopeClaim is the RFC 8693 claim that holds the space-delimited scopes of a token.
+	ScopeClaim = "scope"
+)
+
+var (
+	// ErrInvalidIssueRequest is wrapped by all errors that result from bad client input
+	// when issuing tokens. Handlers translate this error into a 400.
+	ErrInvalidIssueRequest = errors.New("invalid issue request")
+
+	// ErrInvalidResource indicates that a request asked for an RFC 8707 resource
+	// that has no configured audience. Errors that wrap this also wrap ErrInvalidIssueRequest.
+	ErrInvalidResource = errors.New("invalid resource")
+
+	// ErrNoAudience indicates that a token would have been issued without an audience
+	// while an audience is required. Handlers translate this error into a 422.
+	ErrNoAudience = errors.New("the token has no audience, and an audience is required")
+)
+
 type claim struct {
 	name  string
 	value any
@@ -37,112 +140,1083 @@ func (cs claims) MarshalLogArray(ae zapcore.ArrayEncoder) error {
 	return nil
 }
 
+// registeredClaims are the registered claim names that the Issuer always emits.
+// A claim map may rename these, but may not map another claim onto one of
+// these names unless that registered claim is itself mapped away.
+var registeredClaims = map[string]bool{
+	jwt.IssuerKey:     true,
+	jwt.SubjectKey:    true,
+	jwt.AudienceKey:   true,
+	jwt.IssuedAtKey:   true,
+	jwt.ExpirationKey: true,
+	jwt.JwtIDKey:      true,
+}
+
+// issuerClaims are the claims, beyond the registered claims, that an Issuer derives
+// from a request's parameters and binding rather than from its custom claims.
+var issuerClaims = []string{
+	jwt.NotBeforeKey,
+	ScopeClaim,
+	ConfirmationClaim,
+	ActorClaim,
+	AuthTimeClaim,
+	SessionIDClaim,
+	NonceClaim,
+}
+
+// newReservedClaims returns the claims that requests may not supply as custom claims:
+// the registered and issuer claims, the claims that the configuration fills in, such as
+// the client IP and header claims, and every name that the claim map emits.
+func newReservedClaims(cli CLI) map[string]bool {
+	reserved := maps.Clone(registeredClaims)
+	for _, name := range issuerClaims {
+		reserved[name] = true
+	}
+
+	if len(cli.ClientIPClaim) > 0 {
+		reserved[cli.ClientIPClaim] = true
+	}
+
+	for _, name := range cli.HeaderClaim {
+		reserved[name] = true
+	}
+
+	for _, name := range cli.ClaimMap {
+		reserved[name] = true
+	}
+
+	return reserved
+}
+
+// IssueRequest holds the per-request inputs for issuing a single token.
+type IssueRequest struct {
+	// Claims are additional claims for this token only. These are applied
+	// after the configured claims and before the registered claims.
+	Claims map[string]any
+
+	// AuthTime is the optional time at which the end user authenticated.
+	AuthTime time.Time
+
+	// SessionID is the optional session that the token is bound to.
+	SessionID string
+
+	// Nonce is the optional OIDC nonce that is echoed in the token.
+	Nonce string
+
+	// Audience is the optional audience requested for the token. When set, this
+	// either replaces or is merged with the configured audience, depending on the
+	// Issuer's audience override mode.
+	Audience []string
+
+	// Scope is the optional, space-delimited set of scopes requested for the token.
+	Scope string
+
+	// ExpiresIn is the optional lifetime requested for the token. When set, this
+	// overrides the configured lifetime, up to the Issuer's maximum.
+	ExpiresIn time.Duration
+
+	// ClientIP is the address of the client that requested the token.
+	ClientIP string
+
+	// Actors is the optional delegation chain for the token, current actor first.
+	// Each subsequent actor is the prior actor of the one before it.
+	Actors []string
+
+	// PriorActor is the act claim of a verified subject_token. The delegation chain of
+	// that token continues beneath the last of the Actors.
+	PriorActor map[string]any
+
+	// CertificateThumbprint is the base64url SHA-256 thumbprint of the client certificate
+	// that the token is bound to. When unset, the token is not certificate-bound.
+	CertificateThumbprint string
+
+	// DPoPThumbprint is the base64url JWK SHA-256 thumbprint of the DPoP key that the
+	// token is bound to. When unset, the token is not DPoP-bound.
+	DPoPThumbprint string
+
+	// KeyType is the optional key type that signs the token. When unset, the token is
+	// signed with the primary signing keys.
+	KeyType string
+
+	// Debug requests the debug view of the token, which only administrators may see.
+	Debug bool
+
+	// Warnings describe non-fatal issues with the request, such as deprecated claims.
+	// These don't prevent issuance.
+	Warnings []string
+}
+
+// parseAuthTime parses an auth_time parameter of epoch seconds. The auth_time
+// may not be after the given iat of the token.
+func parseAuthTime(v string, iat time.Time) (authTime time.Time, err error) {
+	var seconds int64
+	seconds, err = strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		err = fmt.Errorf("%w: auth_time must be epoch seconds: %w", ErrInvalidIssueRequest, err)
+		return
+	}
+
+	authTime = time.Unix(seconds, 0).UTC()
+	if authTime.After(iat) {
+		err = fmt.Errorf("%w: auth_time may not be after the token's iat", ErrInvalidIssueRequest)
+	}
+
+	return
+}
+
+// parseExpiresIn parses an expires_in parameter of positive seconds.
+func parseExpiresIn(v string) (expiresIn time.Duration, err error) {
+	var seconds int64
+	seconds, err = strconv.ParseInt(v, 10, 64)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("%w: invalid expires_in: %s", ErrInvalidIssueRequest, err)
+
+	case seconds <= 0:
+		err = fmt.Errorf("%w: expires_in must be positive", ErrInvalidIssueRequest)
+
+	case seconds > int64(MaxExpires/time.Second):
+		expiresIn = MaxExpires
+
+	default:
+		expiresIn = time.Duration(seconds) * time.Second
+	}
+
+	return
+}
+
 type Issuer struct {
-	logger *zap.Logger
-	random io.Reader
-	now    func() time.Time
+	logger      *zap.Logger
+	random      io.Reader
+	now         func() time.Time
+	idGenerator *IDGenerator
+
+	iss      string
+	sub      string
+	aud      []string
+	claims   claims
+	claimMap map[string]string
+	expires  time.Duration
+
+	// headerClaims maps canonical request header names onto claim names.
+	// Only these headers are ever copied into claims.
+	headerClaims map[string]string
+
+	// generateSID indicates whether a sid is generated when a request doesn't supply one.
+	generateSID bool
+
+	// clientIPClaim is the claim that records the client's address. When unset,
+	// no such claim is issued.
+	clientIPClaim string
+
+	// trustedProxies are the peers whose forwarding headers are honored.
+	trustedProxies TrustedProxies
+
+	// claimsSchema, when set, validates the claims of every issued token.
+	claimsSchema *ClaimsSchema
+
+	// minimal indicates whether the iss and aud claims are omitted from tokens.
+	// Such tokens are not OIDC compliant, and are meant for internal consumers only.
+	minimal bool
+
+	// requireNonce indicates whether /issue requests must supply a nonce, as OIDC
+	// requires of id_tokens.
+	requireNonce bool
+
+	// requireAudience indicates whether tokens without an aud are rejected rather than issued.
+	requireAudience bool
+
+	// bindClientCert indicates whether tokens are bound to verified client certificates.
+	bindClientCert bool
+
+	// postOnly indicates whether parameters are read only from form-encoded bodies,
+	// so that parameters in a POST's query are ignored.
+	postOnly bool
+
+	// maxAudiences is the maximum number of audiences a single request may supply.
+	maxAudiences int
+
+	// maxCustomClaims is the maximum number of custom claims a single request may supply.
+	maxCustomClaims int
+
+	// verifier verifies subject tokens, and inheritClaims are the claims that an
+	// issued token inherits from a verified subject token.
+	verifier      *Verifier
+	inheritClaims []string
+
+	// recentJTIs, when set, holds recently issued jtis so that a colliding jti is
+	// regenerated rather than issued.
+	recentJTIs *RecentJTIs
+
+	// maxClaimValueBytes is the maximum size of a single request-supplied claim value.
+	// Zero means there is no limit.
+	maxClaimValueBytes int
+
+	// claimsAllowlist, when strictClaims is set, holds the only custom claims that a
+	// request may supply.
+	claimsAllowlist []string
+	strictClaims    bool
+
+	// reservedClaims are the claims that this Issuer sets itself, which requests may not
+	// supply as custom claims.
+	reservedClaims map[string]bool
 
-	iss     string
-	sub     string
-	aud     []string
-	claims  claims
-	expires time.Duration
+	// resourceAudiences maps RFC 8707 resources onto audiences. Requests for any
+	// other resource are rejected.
+	resourceAudiences map[string]string
+
+	// mergeAudience indicates whether requested audiences are merged with the configured
+	// audience rather than replacing it.
+	mergeAudience bool
+
+	// scopeExpires maps scopes onto token lifetimes.
+	scopeExpires map[string]time.Duration
+
+	// maxExpires is the longest lifetime a request may ask for with expires_in.
+	maxExpires time.Duration
+
+	// iatSkew is how far the iat claim is backdated, so that clients whose clocks
+	// run behind don't reject freshly issued tokens.
+	iatSkew time.Duration
+
+	// keyTypes are the key types that a request may select to sign its token.
+	keyTypes []string
+
+	// deprecatedClaims maps deprecated claim names onto their replacements, which may be empty.
+	deprecatedClaims map[string]string
+
+	// rejectDeprecated indicates whether requests for deprecated claims are rejected
+	// rather than issued with warnings.
+	rejectDeprecated bool
+
+	// maxTokenBytes, when positive, is the size budget of a final token in bytes.
+	maxTokenBytes int
+
+	// rejectLargeTokens indicates whether tokens over the size budget are rejected
+	// rather than issued with warnings.
+	rejectLargeTokens bool
 }
 
-func NewIssuer(l *zap.Logger, cli CLI) (i *Issuer, err error) {
+// validateClaimMap ensures that the given claim map won't drop any registered claim.
+func validateClaimMap(claimMap map[string]string) error {
+	// sorting makes the reported conflict deterministic
+	targets := make(map[string]string, len(claimMap))
+	for _, from := range slices.Sorted(maps.Keys(claimMap)) {
+		to := claimMap[from]
+		if len(to) == 0 {
+			return fmt.Errorf("claim map for [%s] has an empty name", from)
+		}
+
+		if _, remapped := claimMap[to]; registeredClaims[to] && !remapped {
+			return fmt.Errorf("claim map [%s=%s] would drop the registered claim [%s]", from, to, to)
+		}
+
+		if other, exists := targets[to]; exists {
+			return fmt.Errorf("claim map [%s=%s] and [%s=%s] map onto the same name", other, to, from, to)
+		}
+
+		targets[to] = from
+	}
+
+	return nil
+}
+
+func NewIssuer(l *zap.Logger, idGenerator *IDGenerator, random *RandomSource, verifier *Verifier, cli CLI) (i *Issuer, err error) {
 	i = &Issuer{
-		logger:  l,
-		random:  rand.Reader,
-		now:     time.Now,
-		iss:     cli.Issuer,
-		sub:     cli.Subject,
-		aud:     cli.Audience,
-		expires: cli.Expires,
+		logger:      l,
+		random:      random,
+		now:         time.Now,
+		idGenerator: idGenerator,
+		verifier:    verifier,
+		iss:         cli.Issuer,
+		sub:         cli.Subject,
+		aud:         cli.Audience,
+		claimMap:    cli.ClaimMap,
+		expires:     cli.Expires,
+
+		headerClaims:       make(map[string]string, len(cli.HeaderClaim)),
+		generateSID:        cli.GenerateSID,
+		maxAudiences:       cli.MaxAudiences,
+		maxCustomClaims:    cli.MaxCustomClaims,
+		maxClaimValueBytes: cli.MaxClaimValueBytes,
+		inheritClaims:      cli.InheritClaim,
+		claimsAllowlist:    cli.ClaimsAllowlist,
+		strictClaims:       cli.StrictClaims,
+		mergeAudience:      cli.AudOverrideMode == AudOverrideMerge,
+		resourceAudiences:  cli.ResourceAudience,
+		scopeExpires:       cli.ScopeExpires,
+		maxExpires:         cli.MaxRequestExpires,
+		clientIPClaim:      cli.ClientIPClaim,
+		bindClientCert:     cli.BindClientCert,
+		postOnly:           cli.IssuePostOnly,
+		minimal:            cli.MinimalToken,
+		requireAudience:    cli.RequireAudience,
+		requireNonce:       cli.OIDC,
+		deprecatedClaims:   cli.DeprecatedClaim,
+		iatSkew:            cli.IATSkew,
+		keyTypes:           append([]string{cli.KeyType}, cli.IssueKeyTypes...),
+		rejectDeprecated:   cli.DeprecatedClaimMode == DeprecatedClaimReject,
+		maxTokenBytes:      cli.MaxTokenBytes,
+		rejectLargeTokens:  cli.MaxTokenBytesMode == TokenSizeReject,
+	}
+
+	if cli.JTICollisionWindow > 0 {
+		i.recentJTIs = NewRecentJTIs(cli.JTICollisionWindow)
+	}
+
+	for header, name := range cli.HeaderClaim {
+		i.headerClaims[http.CanonicalHeaderKey(header)] = name
 	}
 
+	i.reservedClaims = newReservedClaims(cli)
+
 	i.claims = make(claims, 0, len(cli.Claims))
 	for k, v := range cli.Claims {
 		i.claims = append(i.claims, claim{name: k, value: v})
 	}
 
-	i.logger.Info("issuer",
-		zap.String("iss", i.iss),
-		zap.String("sub", i.sub),
-		zap.Strings("aud", i.aud),
-		zap.Duration("expires", i.expires),
-		zap.Any("claims", i.claims),
-	)
+	err = validateClaimMap(i.claimMap)
+	if err == nil {
+		i.trustedProxies, err = NewTrustedProxies(cli.TrustedProxies)
+	}
+
+	if err == nil && len(cli.ClaimsSchema) > 0 {
+		i.claimsSchema, err = NewClaimsSchema(cli.ClaimsSchema)
+	}
+
+	if err == nil {
+		i.logger.Info("issuer",
+			zap.String("iss", i.iss),
+			zap.String("sub", i.sub),
+			zap.Strings("aud", i.aud),
+			zap.Duration("expires", i.expires),
+			zap.Any("claims", i.claims),
+			zap.Any("claimMap", i.claimMap),
+			zap.Any("headerClaims", i.headerClaims),
+			zap.Bool("generateSID", i.generateSID),
+			zap.String("clientIPClaim", i.clientIPClaim),
+			zap.Strings("trustedProxies", cli.TrustedProxies),
+			zap.Int("maxAudiences", i.maxAudiences),
+			zap.Int("maxCustomClaims", i.maxCustomClaims),
+			zap.Int("maxClaimValueBytes", i.maxClaimValueBytes),
+			zap.Int("jtiCollisionWindow", cli.JTICollisionWindow),
+			zap.Strings("inheritClaims", i.inheritClaims),
+			zap.Strings("claimsAllowlist", i.claimsAllowlist),
+			zap.Bool("strictClaims", i.strictClaims),
+			zap.Bool("mergeAudience", i.mergeAudience),
+			zap.Any("resourceAudiences", i.resourceAudiences),
+			zap.Bool("bindClientCert", i.bindClientCert),
+			zap.Bool("minimal", i.minimal),
+			zap.Bool("requireNonce", i.requireNonce),
+			zap.Any("scopeExpires", i.scopeExpires),
+			zap.Duration("maxExpires", i.maxExpires),
+			zap.Any("deprecatedClaims", i.deprecatedClaims),
+			zap.Strings("keyTypes", i.keyTypes),
+			zap.Duration("iatSkew", i.iatSkew),
+			zap.Bool("rejectDeprecated", i.rejectDeprecated),
+			zap.Int("maxTokenBytes", i.maxTokenBytes),
+			zap.Bool("rejectLargeTokens", i.rejectLargeTokens),
+		)
+	}
 
 	return
 }
 
-func (i *Issuer) generateID() (jti string, err error) {
-	var buf [32]byte
-	_, err = io.ReadFull(i.random, buf[:])
+// NewIssueRequest creates the IssueRequest for an HTTP request. Parameters are read
+// from both the query and any form-encoded body, or only from the body when issuance
+// is restricted to POST. Only allow-listed headers are copied into claims, and
+// forwarding headers are only copied from trusted proxies. Any error returned by
+// this method wraps ErrInvalidIssueRequest.
+func (i *Issuer) NewIssueRequest(request *http.Request) (ir IssueRequest, err error) {
+	if parseErr := request.ParseForm(); parseErr != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidIssueRequest, parseErr)
+		return
+	}
+
+	form := request.Form
+	if i.postOnly {
+		form = request.PostForm
+	}
+
+	ir.Debug = form.Get(DebugParameter) == "true"
+	if v := form.Get(AuthTimeClaim); len(v) > 0 {
+		// the iat is backdated by iatSkew, and the user can't authenticate after it
+		ir.AuthTime, err = parseAuthTime(v, i.now().Add(-i.iatSkew))
+	}
+
+	ir.Audience = form[jwt.AudienceKey]
+	if err == nil && len(ir.Audience) > i.maxAudiences {
+		err = fmt.Errorf("%w: at most %d audiences may be requested", ErrInvalidIssueRequest, i.maxAudiences)
+	}
+
+	if resources := form[ResourceParameter]; err == nil && len(resources) > 0 {
+		ir.Audience, err = i.resourceAudience(ir.Audience, resources)
+	}
+
+	if v := form.Get(ExpiresInParameter); err == nil && len(v) > 0 {
+		ir.ExpiresIn, err = parseExpiresIn(v)
+	}
+
+	ir.Actors = form[ActorSubjectParameter]
+	if err == nil && len(ir.Actors) > MaxActors {
+		err = fmt.Errorf("%w: at most %d actors may be requested", ErrInvalidIssueRequest, MaxActors)
+	}
+
+	if values := form[ClaimParameter]; err == nil && len(values) > 0 {
+		ir.Claims, err = i.customClaims(values)
+	}
+
+	ir.KeyType = form.Get(KeyTypeParameter)
+	if err == nil && len(ir.KeyType) > 0 && !slices.Contains(i.keyTypes, ir.KeyType) {
+		err = fmt.Errorf("%w: unsupported key_type: %s", ErrInvalidIssueRequest, ir.KeyType)
+	}
+
+	ir.Scope = form.Get(ScopeClaim)
+	ir.Nonce = form.Get(NonceClaim)
+	ir.SessionID = form.Get(SessionIDClaim)
+	if len(ir.SessionID) == 0 && i.generateSID {
+		ir.SessionID = i.idGenerator.Generate(16)
+	}
+
+	if len(i.clientIPClaim) > 0 {
+		ir.ClientIP = i.trustedProxies.ClientIP(request)
+	}
+
+	if i.bindClientCert {
+		ir.CertificateThumbprint = clientCertificateThumbprint(request)
+	}
+
+	trustedPeer := i.trustedProxies.TrustsPeer(request)
+	for header, name := range i.headerClaims {
+		if isForwardingHeader(header) && !trustedPeer {
+			continue
+		}
+
+		value := request.Header.Get(header)
+		if err == nil && i.claimValueTooLarge(value) {
+			err = fmt.Errorf("%w: the %s header may be at most %d bytes", ErrInvalidIssueRequest, header, i.maxClaimValueBytes)
+		}
+
+		if len(value) > 0 {
+			if ir.Claims == nil {
+				ir.Claims = make(map[string]any, len(i.headerClaims))
+			}
+
+			ir.Claims[name] = value
+		}
+	}
+
+	if v := form.Get(SubjectTokenParameter); err == nil && len(v) > 0 {
+		err = i.inherit(&ir, v)
+	}
+
 	if err == nil {
+		ir.Warnings, err = i.claimWarnings(ir.Claims)
+	}
+
+	return
+}
+
+// inherit verifies a subject token and copies its allow-listed claims into the request's
+// claims. Inherited claims replace any that the request supplied, since they come from
+// a verified token.
+func (i *Issuer) inherit(ir *IssueRequest, subjectToken string) error {
+	if len(i.inheritClaims) == 0 {
+		return fmt.Errorf("%w: %s is not supported", ErrInvalidIssueRequest, SubjectTokenParameter)
+	}
+
+	t, err := i.verifier.Verify([]byte(subjectToken))
+	if err != nil {
+		return fmt.Errorf("%w: invalid %s: %w", ErrInvalidIssueRequest, SubjectTokenParameter, err)
+	}
+
+	for _, name := range i.inheritClaims {
+		var value any
+		if t.Get(name, &value) != nil {
+			continue
+		}
+
+		if ir.Claims == nil {
+			ir.Claims = make(map[string]any, len(i.inheritClaims))
+		}
+
+		ir.Claims[name] = value
+	}
+
+	// the subject token's delegation chain continues under the requested actors
+	if t.Get(i.ClaimName(ActorClaim), &ir.PriorActor) == nil && actorDepth(ir.PriorActor)+len(ir.Actors) > MaxActors {
+		return fmt.Errorf("%w: the delegation chain may have at most %d actors", ErrInvalidIssueRequest, MaxActors)
+	}
+
+	return nil
+}
+
+// CheckNonce returns an error wrapping ErrInvalidIssueRequest if a nonce is required,
+// but the request has none. Only id_tokens require a nonce, so the token endpoint
+// doesn't use this method.
+func (i *Issuer) CheckNonce(ir IssueRequest) error {
+	if i.requireNonce && len(ir.Nonce) == 0 {
+		return fmt.Errorf("%w: a nonce is required", ErrInvalidIssueRequest)
+	}
+
+	return nil
+}
+
+// claimWarnings describes each deprecated claim requested for a token. When deprecated
+// claims are rejected, this method instead returns an error for the first one by name.
+func (i *Issuer) claimWarnings(requested map[string]any) (warnings []string, err error) {
+	for _, name := range slices.Sorted(maps.Keys(requested)) {
+		replacement, deprecated := i.deprecatedClaims[name]
+		if !deprecated {
+			continue
+		}
+
+		warning := fmt.Sprintf("the %s claim is deprecated", name)
+		if len(replacement) > 0 {
+			warning += fmt.Sprintf(", use %s instead", replacement)
+		}
+
+		if i.rejectDeprecated {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidIssueRequest, warning)
+		}
+
+		warnings = append(warnings, warning)
+	}
+
+	return
+}
+
+// CheckTokenSize compares the size of a final token, as it will be sent to the client,
+// against the size budget. A token over the budget either adds a warning to the request
+// or, when large tokens are rejected, results in an error wrapping ErrInvalidIssueRequest.
+func (i *Issuer) CheckTokenSize(ir *IssueRequest, token []byte) error {
+	if i.maxTokenBytes <= 0 || len(token) <= i.maxTokenBytes {
+		return nil
+	}
+
+	warning := fmt.Sprintf("the token is %d bytes, which exceeds the budget of %d bytes", len(token), i.maxTokenBytes)
+	if i.rejectLargeTokens {
+		return fmt.Errorf("%w: %s", ErrInvalidIssueRequest, warning)
+	}
+
+	ir.Warnings = append(ir.Warnings, warning)
+	return nil
+}
+
+// writeWarnings adds a Warning header for each of the given warnings, using the
+// miscellaneous persistent warning code.
+func writeWarnings(h http.Header, warnings []string) {
+	for _, w := range warnings {
+		h.Add("Warning", "299 - "+strconv.Quote(w))
+	}
+}
+
+// customClaims parses the name=value claim parameters of a request. Reserved claims,
+// such as the registered claims, are set by the Issuer, so they are ignored here and
+// don't count toward the Issuer's limit on custom claims. When claims are strict, any claim missing
+// from the allowlist is rejected.
+func (i *Issuer) customClaims(values []string) (custom map[string]any, err error) {
+	custom = make(map[string]any, len(values))
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, "=")
+		switch {
+		case !ok || len(name) == 0:
+			return nil, fmt.Errorf("%w: claims must be name=value: %s", ErrInvalidIssueRequest, v)
+
+		case i.reservedClaims[name]:
+			continue
+
+		case i.strictClaims && !slices.Contains(i.claimsAllowlist, name):
+			return nil, fmt.Errorf("%w: the %s claim is not allowed", ErrInvalidIssueRequest, name)
+
+		case i.claimValueTooLarge(value):
+			return nil, fmt.Errorf("%w: the %s claim value may be at most %d bytes", ErrInvalidIssueRequest, name, i.maxClaimValueBytes)
+		}
+
+		custom[name] = value
+	}
+
+	if len(custom) > i.maxCustomClaims {
+		return nil, fmt.Errorf("%w: at most %d custom claims may be requested", ErrInvalidIssueRequest, i.maxCustomClaims)
+	}
+
+	return
+}
+
+// claimValueTooLarge tests if a request-supplied claim value exceeds the configured limit.
+func (i *Issuer) claimValueTooLarge(value string) bool {
+	return i.maxClaimValueBytes > 0 && len(value) > i.maxClaimValueBytes
+}
+
+// ClaimName returns the name under which the given claim is emitted, taking
+// the configured claim map into account.
+func (i *Issuer) ClaimName(name string) string {
+	return mappedClaimName(i.claimMap, name)
+}
+
+// mappedClaimName returns the name under which a claim is emitted with the given claim map.
+func mappedClaimName(claimMap map[string]string, name string) string {
+	if mapped, ok := claimMap[name]; ok {
+		return mapped
+	}
+
+	return name
+}
+
+// mappedTimeClaim returns a time-based claim of a token issued with the given claim map.
+// Registered time claims are read by jwx, while remapped time claims are NumericDate values.
+func mappedTimeClaim(t jwt.Token, claimMap map[string]string, name string) (value time.Time, ok bool) {
+	mapped := mappedClaimName(claimMap, name)
+	switch {
+	case mapped != name:
+		// the remapped claim is a private claim
+
+	case name == jwt.ExpirationKey:
+		return t.Expiration()
+
+	case name == jwt.NotBeforeKey:
+		return t.NotBefore()
+
+	case name == jwt.IssuedAtKey:
+		return t.IssuedAt()
+	}
+
+	// a built token holds an int64, while a parsed token holds a float64
+	var unix int64
+	if ok = t.Get(mapped, &unix) == nil; !ok {
+		var seconds float64
+		if ok = t.Get(mapped, &seconds) == nil; ok {
+			unix = int64(seconds)
+		}
+	}
+
+	if ok {
+		value = time.Unix(unix, 0)
+	}
+
+	return
+}
+
+// timeClaim returns the value for a time-based claim. Registered time claims
+// are left to jwx, while remapped time claims are emitted as NumericDate values.
+func (i *Issuer) timeClaim(name string, t time.Time) any {
+	if i.ClaimName(name) == name {
+		return t
+	}
+
+	return t.Unix()
+}
+
+// ExpirationOf returns the exp of a token issued by this Issuer, honoring the claim map.
+func (i *Issuer) ExpirationOf(t jwt.Token) (time.Time, bool) {
+	return mappedTimeClaim(t, i.claimMap, jwt.ExpirationKey)
+}
+
+// generateID produces a random jti. When recent jtis are tracked, a jti that collides
+// with a recently issued one is regenerated, up to maxJTIAttempts times in all.
+func (i *Issuer) generateID() (jti string, err error) {
+	for attempt := 0; attempt < maxJTIAttempts; attempt++ {
+		var buf [32]byte
+		if _, err = io.ReadFull(i.random, buf[:]); err != nil {
+			return
+		}
+
 		jti = base64.RawURLEncoding.EncodeToString(buf[:])
+		if i.recentJTIs == nil || i.recentJTIs.Add(jti) {
+			return
+		}
+
+		i.logger.Warn("regenerating a jti that collided with a recently issued jti", zap.Int("attempt", attempt+1))
+	}
+
+	return "", ErrJTICollision
+}
+
+// claim sets a single claim on the builder, honoring the claim map.
+func (i *Issuer) claim(b *jwt.Builder, name string, value any) {
+	b.Claim(i.ClaimName(name), value)
+}
+
+// audienceOf returns the audience for a token issued for the given request.
+// In merge mode, the result is the configured audience followed by any requested
+// audiences not already present. The result never contains duplicates.
+func (i *Issuer) audienceOf(ir IssueRequest) []string {
+	switch {
+	case len(ir.Audience) == 0:
+		return uniqueAudience(i.aud)
+
+	case !i.mergeAudience:
+		return uniqueAudience(ir.Audience)
+
+	default:
+		return uniqueAudience(i.aud, ir.Audience)
+	}
+}
+
+// uniqueAudience concatenates the given audiences, dropping duplicates while
+// preserving the order in which each audience first appears.
+func uniqueAudience(auds ...[]string) (unique []string) {
+	for _, aud := range auds {
+		for _, a := range aud {
+			if !slices.Contains(unique, a) {
+				unique = append(unique, a)
+			}
+		}
 	}
 
 	return
 }
 
-func (i *Issuer) buildToken(b *jwt.Builder) {
-	now := i.now().UTC()
+// resourceAudience appends the audience of each requested resource to the given
+// requested audience. Any resource without a configured audience is rejected.
+func (i *Issuer) resourceAudience(aud, resources []string) ([]string, error) {
+	for _, resource := range resources {
+		mapped, ok := i.resourceAudiences[resource]
+		if !ok {
+			return nil, fmt.Errorf("%w: %w: %s", ErrInvalidIssueRequest, ErrInvalidResource, resource)
+		}
 
-	for _, c := range i.claims {
-		b.Claim(c.name, c.value)
+		if !slices.Contains(aud, mapped) {
+			aud = append(aud, mapped)
+		}
 	}
 
-	b.Issuer(i.iss).
-		Audience(i.aud).
-		Subject(i.sub).
-		IssuedAt(now).
-		Expiration(now.Add(i.expires))
+	if len(aud) > i.maxAudiences {
+		return nil, fmt.Errorf("%w: at most %d audiences may be requested", ErrInvalidIssueRequest, i.maxAudiences)
+	}
+
+	return aud, nil
+}
+
+// MaxLifetime returns the longest lifetime of any token this Issuer produces.
+func (i *Issuer) MaxLifetime() time.Duration {
+	return maxLifetime(i.expires, i.maxExpires, i.scopeExpires)
 }
 
-func (i *Issuer) Issue() (t jwt.Token, err error) {
+// maxLifetime returns the longest lifetime of a token issued with the given default,
+// expires_in maximum, and scope lifetimes.
+func maxLifetime(expires, maxExpires time.Duration, scopeExpires map[string]time.Duration) time.Duration {
+	longest := max(expires, maxExpires)
+	for _, expires := range scopeExpires {
+		longest = max(longest, expires)
+	}
+
+	return min(longest, MaxExpires)
+}
+
+// ExpiresIn returns the lifetime of a token issued for the given request.
+// When any requested scopes have a configured lifetime, the shortest of those
+// lifetimes is used. A lifetime requested with expires_in overrides the configured
+// lifetime, but is clamped to both the maximum and any scope lifetime. The returned
+// lifetime never exceeds MaxExpires.
+func (i *Issuer) ExpiresIn(ir IssueRequest) time.Duration {
 	var (
-		jti string
-		b   *jwt.Builder
+		expires = i.expires
+		scoped  = false
 	)
 
+	for _, scope := range strings.Fields(ir.Scope) {
+		if scopeExpires, ok := i.scopeExpires[scope]; ok {
+			if !scoped || scopeExpires < expires {
+				expires = scopeExpires
+			}
+
+			scoped = true
+		}
+	}
+
+	if ir.ExpiresIn > 0 {
+		requested := min(ir.ExpiresIn, i.maxExpires)
+		if scoped {
+			requested = min(requested, expires)
+		}
+
+		expires = requested
+	}
+
+	return min(expires, MaxExpires)
+}
+
+// clientCertificateThumbprint returns the base64url SHA-256 thumbprint of the verified
+// client certificate of the given request. If the client presented no verified
+// certificate, this function returns the empty string.
+func clientCertificateThumbprint(request *http.Request) string {
+	if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 || len(request.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256(request.TLS.VerifiedChains[0][0].Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// confirmationClaim builds the cnf claim for a token bound to a client certificate,
+// a DPoP key, or both. If the token is not bound, the result is empty.
+func confirmationClaim(ir IssueRequest) map[string]any {
+	cnf := make(map[string]any, 2)
+	if len(ir.CertificateThumbprint) > 0 {
+		cnf[X5TS256Member] = ir.CertificateThumbprint
+	}
+
+	if len(ir.DPoPThumbprint) > 0 {
+		cnf[JKTMember] = ir.DPoPThumbprint
+	}
+
+	return cnf
+}
+
+// actorClaim builds the nested act claim for a delegation chain, current actor first.
+// The prior act claim, if any, is nested beneath the last actor. If there are neither
+// actors nor a prior act claim, the result is nil.
+func actorClaim(actors []string, prior map[string]any) (act map[string]any) {
+	act = prior
+	for i := len(actors) - 1; i >= 0; i-- {
+		next := map[string]any{jwt.SubjectKey: actors[i]}
+		if act != nil {
+			next[ActorClaim] = act
+		}
+
+		act = next
+	}
+
+	return
+}
+
+// actorDepth returns the number of actors in a nested act claim.
+func actorDepth(act map[string]any) (depth int) {
+	for act != nil {
+		depth++
+		act, _ = act[ActorClaim].(map[string]any)
+	}
+
+	return
+}
+
+func (i *Issuer) buildToken(b *jwt.Builder, ir IssueRequest, jti string) {
+	now := i.now().UTC()
+
+	for _, c := range i.claims {
+		i.claim(b, c.name, c.value)
+	}
+
+	for name, value := range ir.Claims {
+		i.claim(b, name, value)
+	}
+
+	i.claim(b, jwt.JwtIDKey, jti)
+	if !i.minimal {
+		i.claim(b, jwt.IssuerKey, i.iss)
+		if aud := i.audienceOf(ir); len(aud) > 0 {
+			i.claim(b, jwt.AudienceKey, aud)
+		}
+	}
+
+	i.claim(b, jwt.SubjectKey, i.sub)
+	if !ir.AuthTime.IsZero() {
+		i.claim(b, AuthTimeClaim, ir.AuthTime.Unix())
+	}
+
+	if len(ir.SessionID) > 0 {
+		i.claim(b, SessionIDClaim, ir.SessionID)
+	}
+
+	if len(ir.Nonce) > 0 {
+		i.claim(b, NonceClaim, ir.Nonce)
+	}
+
+	if len(ir.Scope) > 0 {
+		i.claim(b, ScopeClaim, ir.Scope)
+	}
+
+	if act := actorClaim(ir.Actors, ir.PriorActor); act != nil {
+		i.claim(b, ActorClaim, act)
+	}
+
+	if cnf := confirmationClaim(ir); len(cnf) > 0 {
+		i.claim(b, ConfirmationClaim, cnf)
+	}
+
+	if len(i.clientIPClaim) > 0 && len(ir.ClientIP) > 0 {
+		i.claim(b, i.clientIPClaim, ir.ClientIP)
+	}
+
+	// iat is backdated for clients with lagging clocks, but exp is still relative to now
+	i.claim(b, jwt.IssuedAtKey, i.timeClaim(jwt.IssuedAtKey, now.Add(-i.iatSkew)))
+	i.claim(b, jwt.ExpirationKey, i.timeClaim(jwt.ExpirationKey, now.Add(i.ExpiresIn(ir))))
+}
+
+// Issue creates a new, unsigned token for the given request. If a claims schema
+// is configured, the token's claims must validate against it.
+//
+// If an audience is required, and neither the request nor the configuration supply
+// one, this method returns ErrNoAudience.
+func (i *Issuer) Issue(ir IssueRequest) (t jwt.Token, err error) {
+	if i.requireAudience && len(i.audienceOf(ir)) == 0 {
+		err = ErrNoAudience
+		return
+	}
+
+	var jti string
 	jti, err = i.generateID()
 	if err == nil {
-		b = jwt.NewBuilder().JwtID(jti)
-		i.buildToken(b)
+		b := jwt.NewBuilder()
+		i.buildToken(b, ir, jti)
 		t, err = b.Build()
 	}
 
+	if err == nil && i.claimsSchema != nil {
+		err = i.claimsSchema.Validate(t)
+	}
+
 	return
 }
 
+// NewExpiresInHistogram creates the histogram that tracks the lifetimes, in seconds,
+// of issued tokens.
+func NewExpiresInHistogram() prometheus.Histogram {
+	return prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: MetricsNamespace,
+			Name:      "issued_token_expires_in_seconds",
+			Help:      "the lifetimes of tokens issued via /issue",
+			Buckets:   []float64{60, 300, 900, 1800, 3600, 4 * 3600, 24 * 3600, 7 * 24 * 3600},
+		},
+	)
+}
+
 type IssueHandler struct {
 	logger      *zap.Logger
 	issuer      *Issuer
 	signer      *Signer
+	encrypter   *Encrypter
+	expiresIn   prometheus.Histogram
+	notifier    *PostIssueNotifier
+	adminAuth   *AdminAuth
 	contentType string
 }
 
-func NewIssueHandler(l *zap.Logger, issuer *Issuer, signer *Signer, cli CLI) *IssueHandler {
-	return &IssueHandler{
+func NewIssueHandler(l *zap.Logger, issuer *Issuer, signer *Signer, encrypter *Encrypter, notifier *PostIssueNotifier, adminAuth *AdminAuth, r prometheus.Registerer, cli CLI) (ih *IssueHandler, err error) {
+	ih = &IssueHandler{
 		logger:      l,
 		issuer:      issuer,
 		signer:      signer,
+		encrypter:   encrypter,
+		notifier:    notifier,
+		adminAuth:   adminAuth,
+		expiresIn:   NewExpiresInHistogram(),
 		contentType: fmt.Sprintf("application/%s", strings.ToLower(cli.Type)),
 	}
+
+	if encrypter.Enabled() {
+		ih.contentType = "application/jose+json"
+	}
+
+	err = r.Register(ih.expiresIn)
+	return
+}
+
+// IssueDebugResponse is the debug view of an issued token, with its decoded
+// header and claims alongside the compact form.
+type IssueDebugResponse struct {
+	Token   string          `json:"token"`
+	Header  json.RawMessage `json:"header"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// newIssueDebugResponse decodes the given compact JWS into its debug view.
+func newIssueDebugResponse(signed []byte) (body []byte, err error) {
+	var (
+		msg    *jws.Message
+		header []byte
+	)
+
+	msg, err = jws.Parse(signed)
+	if err == nil {
+		header, err = json.Marshal(msg.Signatures()[0].ProtectedHeaders())
+	}
+
+	if err == nil {
+		body, err = json.Marshal(IssueDebugResponse{
+			Token:   string(signed),
+			Header:  header,
+			Payload: msg.Payload(),
+		})
+	}
+
+	return
 }
 
 func (ih *IssueHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
-	var signed []byte
-	t, err := ih.issuer.Issue()
+	var (
+		t      jwt.Token
+		signed []byte
+	)
+
+	ir, err := ih.issuer.NewIssueRequest(request)
+
+	// the debug view discloses the token's claims, so it is only for administrators
+	if err == nil && ir.Debug && !ih.adminAuth.Authenticate(request) {
+		response.Header().Set("WWW-Authenticate", AdminAuthChallenge)
+		response.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if err == nil {
+		err = ih.issuer.CheckNonce(ir)
+	}
+
 	if err == nil {
-		signed, err = ih.signer.SignToken(t)
+		t, err = ih.issuer.Issue(ir)
 	}
 
 	if err == nil {
-		response.Header().Set("Content-Type", ih.contentType)
+		signed, err = ih.signer.SignTokenWithKeyType(t, ir.KeyType)
+	}
+
+	contentType := ih.contentType
+	switch {
+	case err != nil:
+		// handled below
+
+	case ir.Debug:
+		// the debug view always shows the signed token, even when tokens are encrypted,
+		// so the size budget applies to that token rather than to the debug JSON
+		contentType = "application/json"
+		err = ih.issuer.CheckTokenSize(&ir, signed)
+		if err == nil {
+			signed, err = newIssueDebugResponse(signed)
+		}
+
+	case ih.encrypter.Enabled():
+		signed, err = ih.encrypter.Encrypt(signed)
+	}
+
+	if err == nil && !ir.Debug {
+		err = ih.issuer.CheckTokenSize(&ir, signed)
+	}
+
+	switch {
+	case err == nil:
+		ih.expiresIn.Observe(ih.issuer.ExpiresIn(ir).Seconds())
+		ih.notifier.Notify(t)
+		writeWarnings(response.Header(), ir.Warnings)
+		if exp, ok := ih.issuer.ExpirationOf(t); ok {
+			response.Header().Set(TokenExpiresHeader, exp.UTC().Format(time.RFC3339))
+		}
+
+		response.Header().Set("Content-Type", contentType)
 		response.Write(signed)
-	} else {
+
+	case errors.Is(err, ErrInvalidIssueRequest):
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(err.Error()))
+
+	case errors.Is(err, ErrInvalidClaims), errors.Is(err, ErrNoAudience):
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusUnprocessableEntity)
+		response.Write([]byte(err.Error()))
+
+	default:
 		ih.logger.Error("unable to issue token", zap.Error(err))
 		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
 		response.WriteHeader(http.StatusInternalServerError)
@@ -154,5 +1228,7 @@ func ProvideIssuer() fx.Option {
 	return fx.Provide(
 		NewIssuer,
 		NewIssueHandler,
+		NewTokenHandler,
+		NewDPoPVerifier,
 	)
 }
@@ -0,0 +1,2092 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuerClaimMap(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expectErr   bool
+
+		// expectedPresent and expectedAbsent are claim names in issued tokens.
+		expectedPresent []string
+		expectedAbsent  []string
+		expectedValues  map[string]any
+	}{
+		{
+			description:     "no claim map",
+			expectedPresent: []string{"iss", "sub", "exp", "iat", "jti"},
+		},
+		{
+			description:     "rename registered claims",
+			args:            []string{"--claim-map=sub=principal", "--claim-map=jti=token_id"},
+			expectedPresent: []string{"iss", "principal", "token_id", "exp"},
+			expectedAbsent:  []string{"sub", "jti"},
+		},
+		{
+			description:    "swap registered claims",
+			args:           []string{"--issuer=the-issuer", "--subject=the-subject", "--claim-map=sub=iss", "--claim-map=iss=sub"},
+			expectedValues: map[string]any{"iss": "the-subject", "sub": "the-issuer"},
+		},
+		{
+			description: "empty name",
+			args:        []string{"--claim-map=sub="},
+			expectErr:   true,
+		},
+		{
+			description: "drops a registered claim",
+			args:        []string{"--claim-map=sub=iss"},
+			expectErr:   true,
+		},
+		{
+			description: "two claims onto one name",
+			args:        []string{"--claim-map=sub=who", "--claim-map=iss=who"},
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+
+			require.NoError(t, err)
+			token, err := i.Issue(IssueRequest{})
+			require.NoError(t, err)
+
+			claims := tokenMap(t, token)
+			for _, name := range tc.expectedPresent {
+				assert.Contains(claims, name)
+			}
+
+			for _, name := range tc.expectedAbsent {
+				assert.NotContains(claims, name)
+			}
+
+			for name, value := range tc.expectedValues {
+				assert.Equal(value, claims[name])
+			}
+		})
+	}
+}
+
+func TestIssuerHeaderClaims(t *testing.T) {
+	tests := []struct {
+		description    string
+		args           []string
+		headers        map[string]string
+		expectedClaims map[string]any
+		expectedAbsent []string
+	}{
+		{
+			description:    "no header claims",
+			headers:        map[string]string{"X-Tenant-ID": "acme"},
+			expectedAbsent: []string{"tenant", "X-Tenant-ID"},
+		},
+		{
+			description:    "allow-listed header",
+			args:           []string{"--header-claim=X-Tenant-ID=tenant"},
+			headers:        map[string]string{"X-Tenant-ID": "acme", "X-Other": "other"},
+			expectedClaims: map[string]any{"tenant": "acme"},
+			expectedAbsent: []string{"X-Other"},
+		},
+		{
+			description:    "header names are case-insensitive",
+			args:           []string{"--header-claim=x-tenant-id=tenant"},
+			headers:        map[string]string{"X-TENANT-ID": "acme"},
+			expectedClaims: map[string]any{"tenant": "acme"},
+		},
+		{
+			description:    "missing header",
+			args:           []string{"--header-claim=X-Tenant-ID=tenant"},
+			expectedAbsent: []string{"tenant"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/issue", nil)
+			for name, value := range tc.headers {
+				request.Header.Set(name, value)
+			}
+
+			ir, err := i.NewIssueRequest(request)
+			require.NoError(t, err)
+
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			claims := tokenMap(t, token)
+			for name, value := range tc.expectedClaims {
+				assert.Equal(value, claims[name])
+			}
+
+			for _, name := range tc.expectedAbsent {
+				assert.NotContains(claims, name)
+			}
+		})
+	}
+}
+
+func TestIssuerAuthTime(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		description string
+		args        []string
+		authTime    string
+		expected    any
+		expectedErr error
+	}{
+		{
+			description: "no auth_time",
+		},
+		{
+			description: "before iat",
+			authTime:    "1699999000",
+			expected:    float64(1699999000),
+		},
+		{
+			description: "at iat",
+			authTime:    "1700000000",
+			expected:    float64(1700000000),
+		},
+		{
+			description: "after iat",
+			authTime:    "1700000001",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+		{
+			description: "after the backdated iat",
+			args:        []string{"--iat-skew=1m"},
+			authTime:    "1699999970",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+		{
+			description: "not epoch seconds",
+			authTime:    "yesterday",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+			i.now = func() time.Time { return now }
+
+			form := url.Values{}
+			if len(tc.authTime) > 0 {
+				form.Set(AuthTimeClaim, tc.authTime)
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/issue?"+form.Encode(), nil)
+			ir, err := i.NewIssueRequest(request)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			claims := tokenMap(t, token)
+			if tc.expected != nil {
+				assert.Equal(tc.expected, claims[AuthTimeClaim])
+			} else {
+				assert.NotContains(claims, AuthTimeClaim)
+			}
+		})
+	}
+}
+
+func TestIssuerSessionID(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		sid         string
+		expected    string
+		expectSID   bool
+	}{
+		{
+			description: "no sid",
+		},
+		{
+			description: "requested sid",
+			sid:         "session-1",
+			expected:    "session-1",
+			expectSID:   true,
+		},
+		{
+			description: "generated sid",
+			args:        []string{"--generate-sid"},
+			expectSID:   true,
+		},
+		{
+			description: "requested sid overrides generation",
+			args:        []string{"--generate-sid"},
+			sid:         "session-1",
+			expected:    "session-1",
+			expectSID:   true,
+		},
+		{
+			description: "remapped sid",
+			args:        []string{"--claim-map=sid=session"},
+			sid:         "session-1",
+			expected:    "session-1",
+			expectSID:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			form := url.Values{}
+			if len(tc.sid) > 0 {
+				form.Set(SessionIDClaim, tc.sid)
+			}
+
+			issue := func() jwt.Token {
+				ir, err := i.NewIssueRequest(httptest.NewRequest(http.MethodPost, "/issue?"+form.Encode(), nil))
+				require.NoError(t, err)
+
+				token, err := i.Issue(ir)
+				require.NoError(t, err)
+				return token
+			}
+
+			first, second := issue(), issue()
+
+			name := i.ClaimName(SessionIDClaim)
+			claims := tokenMap(t, first)
+			if !tc.expectSID {
+				assert.NotContains(claims, name)
+				return
+			}
+
+			sid, _ := claims[name].(string)
+			assert.NotEmpty(sid)
+			if len(tc.expected) > 0 {
+				assert.Equal(tc.expected, sid)
+				assert.Equal(sid, tokenMap(t, second)[name])
+			} else {
+				// each generated sid is unique
+				assert.NotEqual(sid, tokenMap(t, second)[name])
+			}
+		})
+	}
+}
+
+func TestIssuerMaxAudiences(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		form        string
+		expectedAud []string
+		expectedErr error
+	}{
+		{
+			description: "configured audience",
+			args:        []string{"--max-audiences=2", "--audience=a", "--audience=b", "--audience=c"},
+			expectedAud: []string{"a", "b", "c"},
+		},
+		{
+			description: "at the limit",
+			args:        []string{"--max-audiences=2"},
+			form:        "aud=a&aud=b",
+			expectedAud: []string{"a", "b"},
+		},
+		{
+			description: "over the limit",
+			args:        []string{"--max-audiences=2"},
+			form:        "aud=a&aud=b&aud=c",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+		{
+			description: "resources over the limit",
+			args:        []string{"--max-audiences=2", "--resource-audience=https://r1=r1", "--resource-audience=https://r2=r2"},
+			form:        "aud=a&resource=https://r1&resource=https://r2",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			ir, err := newTestIssueRequest(i, tc.form)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			aud, _ := token.Audience()
+			assert.Equal(tc.expectedAud, aud)
+		})
+	}
+}
+
+func TestIssuerAudOverrideMode(t *testing.T) {
+	configured := []string{"--audience=a", "--audience=b"}
+	tests := []struct {
+		description string
+		args        []string
+		form        string
+		expectedAud []string
+	}{
+		{
+			description: "replace without aud",
+			args:        configured,
+			expectedAud: []string{"a", "b"},
+		},
+		{
+			description: "replace",
+			args:        configured,
+			form:        "aud=c",
+			expectedAud: []string{"c"},
+		},
+		{
+			description: "replace duplicates",
+			args:        configured,
+			form:        "aud=d&aud=c&aud=d",
+			expectedAud: []string{"d", "c"},
+		},
+		{
+			description: "configured duplicates",
+			args:        []string{"--audience=a", "--audience=b", "--audience=a"},
+			expectedAud: []string{"a", "b"},
+		},
+		{
+			description: "merge without aud",
+			args:        append([]string{"--aud-override-mode=merge"}, configured...),
+			expectedAud: []string{"a", "b"},
+		},
+		{
+			description: "merge",
+			args:        append([]string{"--aud-override-mode=merge"}, configured...),
+			form:        "aud=c&aud=d",
+			expectedAud: []string{"a", "b", "c", "d"},
+		},
+		{
+			description: "merge duplicates",
+			args:        append([]string{"--aud-override-mode=merge"}, configured...),
+			form:        "aud=b&aud=c&aud=c",
+			expectedAud: []string{"a", "b", "c"},
+		},
+		{
+			description: "merge without configured audience",
+			args:        []string{"--aud-override-mode=merge"},
+			form:        "aud=c",
+			expectedAud: []string{"c"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			ir, err := newTestIssueRequest(i, tc.form)
+			require.NoError(t, err)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			aud, _ := token.Audience()
+			assert.Equal(t, tc.expectedAud, aud)
+		})
+	}
+}
+
+func TestUniqueAudience(t *testing.T) {
+	tests := []struct {
+		description string
+		auds        [][]string
+		expected    []string
+	}{
+		{
+			description: "nothing",
+		},
+		{
+			description: "empty",
+			auds:        [][]string{{}, {}},
+		},
+		{
+			description: "unique",
+			auds:        [][]string{{"a", "b"}},
+			expected:    []string{"a", "b"},
+		},
+		{
+			description: "duplicates within a list",
+			auds:        [][]string{{"b", "a", "b", "a"}},
+			expected:    []string{"b", "a"},
+		},
+		{
+			description: "duplicates across lists",
+			auds:        [][]string{{"a", "b"}, {"c", "a", "d", "b"}},
+			expected:    []string{"a", "b", "c", "d"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expected, uniqueAudience(tc.auds...))
+		})
+	}
+}
+
+func TestIssuerResourceAudience(t *testing.T) {
+	resources := []string{"--resource-audience=https://api.example.com=api", "--resource-audience=https://other.example.com=other"}
+	tests := []struct {
+		description string
+		args        []string
+		form        string
+		expectedAud []string
+		expectedErr error
+	}{
+		{
+			description: "single resource",
+			args:        resources,
+			form:        "resource=https://api.example.com",
+			expectedAud: []string{"api"},
+		},
+		{
+			description: "multiple resources",
+			args:        resources,
+			form:        "resource=https://api.example.com&resource=https://other.example.com",
+			expectedAud: []string{"api", "other"},
+		},
+		{
+			description: "resource and aud",
+			args:        resources,
+			form:        "aud=direct&resource=https://api.example.com",
+			expectedAud: []string{"direct", "api"},
+		},
+		{
+			description: "resource duplicates aud",
+			args:        resources,
+			form:        "aud=api&resource=https://api.example.com",
+			expectedAud: []string{"api"},
+		},
+		{
+			description: "unknown resource",
+			args:        resources,
+			form:        "resource=https://unknown.example.com",
+			expectedErr: ErrInvalidResource,
+		},
+		{
+			description: "no resources configured",
+			form:        "resource=https://api.example.com",
+			expectedErr: ErrInvalidResource,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			ir, err := newTestIssueRequest(i, tc.form)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				assert.ErrorIs(err, ErrInvalidIssueRequest)
+				return
+			}
+
+			require.NoError(t, err)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			aud, _ := token.Audience()
+			assert.Equal(tc.expectedAud, aud)
+		})
+	}
+}
+
+func TestTokenHandlerResource(t *testing.T) {
+	tests := []struct {
+		description    string
+		body           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			description:    "known resource",
+			body:           "grant_type=client_credentials&resource=https://api.example.com",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "unknown resource",
+			body:           "grant_type=client_credentials&resource=https://unknown.example.com",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_target",
+		},
+		{
+			description:    "other invalid request",
+			body:           "grant_type=client_credentials&expires_in=bogus",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+	}
+
+	h := newTestServer(t, "--admin-token="+testAdminToken, "--resource-audience=https://api.example.com=api")
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			response := serve(h, http.MethodPost, "/token", strings.NewReader(tc.body), testAdminAuth)
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+
+			if tc.expectedStatus != http.StatusOK {
+				var oe OAuthError
+				require.NoError(t, json.Unmarshal(response.Body.Bytes(), &oe))
+				assert.Equal(tc.expectedError, oe.Error)
+				return
+			}
+
+			var tr TokenResponse
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &tr))
+			assert.Equal([]any{"api"}, tokenClaims(t, tr.AccessToken)["aud"])
+		})
+	}
+}
+
+func TestIssuerMinimalToken(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		form        string
+		expectedIss any
+		expectedAud any
+	}{
+		{
+			description: "full token",
+			args:        []string{"--audience=a"},
+			expectedIss: "utu",
+			expectedAud: []any{"a"},
+		},
+		{
+			description: "minimal token",
+			args:        []string{"--minimal-token", "--audience=a"},
+		},
+		{
+			description: "minimal token ignores requested audience",
+			args:        []string{"--minimal-token"},
+			form:        "aud=b",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			ir, err := newTestIssueRequest(i, tc.form)
+			require.NoError(t, err)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			claims := tokenMap(t, token)
+			assert.Equal(tc.expectedIss, claims["iss"])
+			assert.Equal(tc.expectedAud, claims["aud"])
+			assert.NotEmpty(claims["jti"])
+			assert.NotEmpty(claims["exp"])
+		})
+	}
+}
+
+func TestIssuerMaxCustomClaims(t *testing.T) {
+	tests := []struct {
+		description    string
+		args           []string
+		form           string
+		expectedClaims map[string]any
+		expectedErr    error
+	}{
+		{
+			description: "no custom claims",
+		},
+		{
+			description:    "at the limit",
+			args:           []string{"--max-custom-claims=2"},
+			form:           "claim=a=1&claim=b=2",
+			expectedClaims: map[string]any{"a": "1", "b": "2"},
+		},
+		{
+			description: "over the limit",
+			args:        []string{"--max-custom-claims=2"},
+			form:        "claim=a=1&claim=b=2&claim=c=3",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+		{
+			description:    "registered claims don't count",
+			args:           []string{"--max-custom-claims=1"},
+			form:           "claim=a=1&claim=sub=evil&claim=iss=evil",
+			expectedClaims: map[string]any{"a": "1"},
+		},
+		{
+			description:    "repeated names count once",
+			args:           []string{"--max-custom-claims=1"},
+			form:           "claim=a=1&claim=a=2",
+			expectedClaims: map[string]any{"a": "2"},
+		},
+		{
+			description: "no custom claims allowed",
+			args:        []string{"--max-custom-claims=0"},
+			form:        "claim=a=1",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+		{
+			description: "missing value",
+			form:        "claim=a",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+		{
+			description: "missing name",
+			form:        "claim==1",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			ir, err := newTestIssueRequest(i, tc.form)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			// registered claims are never overridden by the request
+			claims := tokenMap(t, token)
+			assert.Equal("utu", claims["iss"])
+			assert.NotEqual("evil", claims["sub"])
+			for name, value := range tc.expectedClaims {
+				assert.Equal(value, claims[name], name)
+			}
+		})
+	}
+}
+
+func TestIssuerStrictClaims(t *testing.T) {
+	allowlist := []string{"--claims-allowlist=a,b"}
+	tests := []struct {
+		description    string
+		args           []string
+		form           string
+		expectedClaims map[string]any
+		expectedErr    error
+	}{
+		{
+			description:    "not strict",
+			args:           allowlist,
+			form:           "claim=a=1&claim=c=3",
+			expectedClaims: map[string]any{"a": "1", "c": "3"},
+		},
+		{
+			description:    "allowed claims",
+			args:           append([]string{"--strict-claims"}, allowlist...),
+			form:           "claim=a=1&claim=b=2",
+			expectedClaims: map[string]any{"a": "1", "b": "2"},
+		},
+		{
+			description: "claim missing from the allowlist",
+			args:        append([]string{"--strict-claims"}, allowlist...),
+			form:        "claim=a=1&claim=c=3",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+		{
+			description: "empty allowlist",
+			args:        []string{"--strict-claims"},
+			form:        "claim=a=1",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+		{
+			description:    "registered claims are ignored",
+			args:           []string{"--strict-claims"},
+			form:           "claim=sub=evil",
+			expectedClaims: map[string]any{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			ir, err := newTestIssueRequest(i, tc.form)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			claims := tokenMap(t, token)
+			assert.NotEqual("evil", claims["sub"])
+			for name, value := range tc.expectedClaims {
+				assert.Equal(value, claims[name], name)
+			}
+		})
+	}
+}
+
+func TestIssuerIATSkew(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expectedAge time.Duration
+	}{
+		{
+			description: "no skew",
+		},
+		{
+			description: "skewed",
+			args:        []string{"--iat-skew=30s"},
+			expectedAge: 30 * time.Second,
+		},
+		{
+			description: "skewed with a mapped iat",
+			args:        []string{"--iat-skew=1m", "--claim-map=iat=issued"},
+			expectedAge: time.Minute,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			ir, err := newTestIssueRequest(i, "")
+			require.NoError(t, err)
+
+			before := time.Now().Truncate(time.Second)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+			claims := tokenMap(t, token)
+
+			iatClaim := "iat"
+			if _, ok := claims["issued"]; ok {
+				iatClaim = "issued"
+			}
+
+			iat := time.Unix(int64(claims[iatClaim].(float64)), 0)
+			exp := time.Unix(int64(claims["exp"].(float64)), 0)
+
+			// exp is computed from the actual time of issue, regardless of the skew
+			assert.Equal(15*time.Minute+tc.expectedAge, exp.Sub(iat))
+			assert.WithinDuration(before.Add(-tc.expectedAge), iat, 2*time.Second)
+		})
+	}
+}
+
+func TestIssueHandlerTokenExpires(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		form        string
+		expiresIn   time.Duration
+	}{
+		{
+			description: "default lifetime",
+			expiresIn:   15 * time.Minute,
+		},
+		{
+			description: "requested lifetime",
+			form:        "expires_in=60",
+			expiresIn:   time.Minute,
+		},
+		{
+			description: "mapped exp",
+			args:        []string{"--claim-map=exp=expires_at"},
+			expiresIn:   15 * time.Minute,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			h := newTestServer(t, tc.args...)
+			before := time.Now()
+			response := serve(h, http.MethodPost, "/issue", strings.NewReader(tc.form))
+			require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+			header := response.Header().Get(TokenExpiresHeader)
+			exp, err := time.Parse(time.RFC3339, header)
+			require.NoError(t, err, header)
+			assert.True(strings.HasSuffix(header, "Z"), header)
+			assert.WithinDuration(before.Add(tc.expiresIn), exp, 2*time.Second)
+		})
+	}
+}
+
+func TestIssuerRequireAudience(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		form        string
+		expectedAud []string
+		expectedErr error
+	}{
+		{
+			description: "not required",
+		},
+		{
+			description: "required without any audience",
+			args:        []string{"--require-audience"},
+			expectedErr: ErrNoAudience,
+		},
+		{
+			description: "required with a configured audience",
+			args:        []string{"--require-audience", "--audience=a"},
+			expectedAud: []string{"a"},
+		},
+		{
+			description: "required with a requested audience",
+			args:        []string{"--require-audience"},
+			form:        "aud=b",
+			expectedAud: []string{"b"},
+		},
+		{
+			description: "required with a requested resource",
+			args:        []string{"--require-audience", "--resource-audience=https://api.example.com=api"},
+			form:        "resource=https://api.example.com",
+			expectedAud: []string{"api"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			ir, err := newTestIssueRequest(i, tc.form)
+			require.NoError(t, err)
+
+			token, err := i.Issue(ir)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			aud, _ := token.Audience()
+			assert.Equal(tc.expectedAud, aud)
+		})
+	}
+}
+
+func TestRequireAudienceHandlers(t *testing.T) {
+	tests := []struct {
+		description    string
+		target         string
+		headers        []string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			description:    "issue",
+			target:         "/issue",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			description:    "token",
+			target:         "/token",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_target",
+		},
+	}
+
+	h := newTestServer(t, "--require-audience", "--admin-token="+testAdminToken)
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			response := serve(h, http.MethodPost, tc.target, strings.NewReader("grant_type=client_credentials"), tc.headers...)
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if len(tc.expectedError) > 0 {
+				var oe OAuthError
+				require.NoError(t, json.Unmarshal(response.Body.Bytes(), &oe))
+				assert.Equal(t, tc.expectedError, oe.Error)
+			}
+		})
+	}
+}
+
+func TestIssuerCheckTokenSize(t *testing.T) {
+	tests := []struct {
+		description      string
+		args             []string
+		size             int
+		expectedWarnings int
+		expectedErr      error
+	}{
+		{
+			description: "no budget",
+			size:        100000,
+		},
+		{
+			description: "within the budget",
+			args:        []string{"--max-token-bytes=100"},
+			size:        100,
+		},
+		{
+			description:      "over the budget",
+			args:             []string{"--max-token-bytes=100"},
+			size:             101,
+			expectedWarnings: 1,
+		},
+		{
+			description: "rejected within the budget",
+			args:        []string{"--max-token-bytes=100", "--max-token-bytes-mode=reject"},
+			size:        100,
+		},
+		{
+			description: "rejected over the budget",
+			args:        []string{"--max-token-bytes=100", "--max-token-bytes-mode=reject"},
+			size:        101,
+			expectedErr: ErrInvalidIssueRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			var ir IssueRequest
+			err = i.CheckTokenSize(&ir, make([]byte, tc.size))
+			assert.ErrorIs(err, tc.expectedErr)
+			assert.Len(ir.Warnings, tc.expectedWarnings)
+		})
+	}
+}
+
+func TestMaxTokenBytesHandlers(t *testing.T) {
+	tests := []struct {
+		description     string
+		args            []string
+		target          string
+		expectedStatus  int
+		expectedWarning bool
+	}{
+		{
+			description:    "issue within the budget",
+			args:           []string{"--max-token-bytes=4096"},
+			target:         "/issue",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:     "issue over the budget",
+			args:            []string{"--max-token-bytes=10"},
+			target:          "/issue",
+			expectedStatus:  http.StatusOK,
+			expectedWarning: true,
+		},
+		{
+			description:    "issue rejected over the budget",
+			args:           []string{"--max-token-bytes=10", "--max-token-bytes-mode=reject"},
+			target:         "/issue",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:     "token over the budget",
+			args:            []string{"--max-token-bytes=10"},
+			target:          "/token",
+			expectedStatus:  http.StatusOK,
+			expectedWarning: true,
+		},
+		{
+			description:    "token rejected over the budget",
+			args:           []string{"--max-token-bytes=10", "--max-token-bytes-mode=reject"},
+			target:         "/token",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newTestServer(t, append(tc.args, "--admin-token="+testAdminToken)...)
+			response := serve(h, http.MethodPost, tc.target, strings.NewReader("grant_type=client_credentials"), testAdminAuth)
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus != http.StatusOK {
+				assert.Contains(t, response.Body.String(), "exceeds the budget of 10 bytes")
+				return
+			}
+
+			warnings := strings.Join(response.Header().Values("Warning"), "\n")
+			if tc.expectedWarning {
+				assert.Contains(t, warnings, "exceeds the budget of 10 bytes")
+			} else {
+				assert.Empty(t, warnings)
+			}
+		})
+	}
+}
+
+func TestIssueHandlerNonce(t *testing.T) {
+	tests := []struct {
+		description    string
+		args           []string
+		target         string
+		form           string
+		expectedStatus int
+
+		// expectedClaim, when set, is the name of the claim that echoes the nonce.
+		expectedClaim string
+	}{
+		{
+			description:    "no nonce",
+			target:         "/issue",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "echoed nonce",
+			target:         "/issue",
+			form:           "nonce=n-0S6_WzA2Mj",
+			expectedStatus: http.StatusOK,
+			expectedClaim:  NonceClaim,
+		},
+		{
+			description:    "mapped nonce",
+			args:           []string{"--claim-map=nonce=n"},
+			target:         "/issue",
+			form:           "nonce=abc",
+			expectedStatus: http.StatusOK,
+			expectedClaim:  "n",
+		},
+		{
+			description:    "required nonce",
+			args:           []string{"--oidc"},
+			target:         "/issue",
+			form:           "nonce=abc",
+			expectedStatus: http.StatusOK,
+			expectedClaim:  NonceClaim,
+		},
+		{
+			description:    "missing required nonce",
+			args:           []string{"--oidc"},
+			target:         "/issue",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:    "token endpoint doesn't require a nonce",
+			args:           []string{"--oidc"},
+			target:         "/token",
+			form:           "grant_type=client_credentials",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newTestServer(t, append(tc.args, "--admin-token="+testAdminToken)...)
+			response := serve(h, http.MethodPost, tc.target, strings.NewReader(tc.form), testAdminAuth)
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus != http.StatusOK || tc.target != "/issue" {
+				return
+			}
+
+			claims := tokenClaims(t, response.Body.String())
+			if len(tc.expectedClaim) == 0 {
+				assert.NotContains(t, claims, NonceClaim)
+				return
+			}
+
+			form, err := url.ParseQuery(tc.form)
+			require.NoError(t, err)
+			assert.Equal(t, form.Get(NonceClaim), claims[tc.expectedClaim])
+		})
+	}
+}
+
+func TestIssuerMaxClaimValueBytes(t *testing.T) {
+	tests := []struct {
+		description    string
+		args           []string
+		form           string
+		headers        map[string]string
+		expectedClaims map[string]any
+		expectErr      bool
+	}{
+		{
+			description:    "no limit",
+			form:           "claim=a=" + strings.Repeat("x", 1024),
+			expectedClaims: map[string]any{"a": strings.Repeat("x", 1024)},
+		},
+		{
+			description:    "claim at the limit",
+			args:           []string{"--max-claim-value-bytes=4"},
+			form:           "claim=a=1234",
+			expectedClaims: map[string]any{"a": "1234"},
+		},
+		{
+			description: "claim over the limit",
+			args:        []string{"--max-claim-value-bytes=4"},
+			form:        "claim=a=12345",
+			expectErr:   true,
+		},
+		{
+			description:    "header claim at the limit",
+			args:           []string{"--max-claim-value-bytes=4", "--header-claim=X-Tenant-ID=tenant"},
+			headers:        map[string]string{"X-Tenant-ID": "acme"},
+			expectedClaims: map[string]any{"tenant": "acme"},
+		},
+		{
+			description: "header claim over the limit",
+			args:        []string{"--max-claim-value-bytes=4", "--header-claim=X-Tenant-ID=tenant"},
+			headers:     map[string]string{"X-Tenant-ID": "acme!"},
+			expectErr:   true,
+		},
+		{
+			description:    "headers that aren't claims are ignored",
+			args:           []string{"--max-claim-value-bytes=4"},
+			headers:        map[string]string{"X-Other": "too large"},
+			expectedClaims: map[string]any{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/issue", strings.NewReader(tc.form))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for name, value := range tc.headers {
+				request.Header.Set(name, value)
+			}
+
+			ir, err := i.NewIssueRequest(request)
+			if tc.expectErr {
+				assert.ErrorIs(err, ErrInvalidIssueRequest)
+				return
+			}
+
+			require.NoError(t, err)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			claims := tokenMap(t, token)
+			for name, value := range tc.expectedClaims {
+				assert.Equal(value, claims[name], name)
+			}
+		})
+	}
+}
+
+func TestIssueHandlerMaxClaimValueBytes(t *testing.T) {
+	h := newTestServer(t, "--max-claim-value-bytes=4")
+	response := serve(h, http.MethodPost, "/issue", strings.NewReader("claim=a=12345"))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	claims := tokenClaims(t, issueToken(t, h, "claim=a=1234"))
+	assert.Equal(t, "1234", claims["a"])
+}
+
+func TestIssuerJTICollisionWindow(t *testing.T) {
+	// blocks produces the random bytes for a jti per character, so that
+	// repeated characters produce colliding jtis
+	blocks := func(s string) []byte {
+		var random []byte
+		for _, c := range []byte(s) {
+			random = append(random, bytes.Repeat([]byte{c}, 32)...)
+		}
+
+		return random
+	}
+
+	tests := []struct {
+		description string
+		args        []string
+		random      string
+
+		// expected holds the random character behind each successive jti.
+		expected    string
+		expectedErr error
+	}{
+		{
+			description: "collisions not detected",
+			random:      "aa",
+			expected:    "aa",
+		},
+		{
+			description: "collision regenerated",
+			args:        []string{"--jti-collision-window=4"},
+			random:      "aab",
+			expected:    "ab",
+		},
+		{
+			description: "every attempt collides",
+			args:        []string{"--jti-collision-window=4"},
+			random:      "aaaa",
+			expected:    "a",
+			expectedErr: ErrJTICollision,
+		},
+		{
+			description: "outside the window",
+			args:        []string{"--jti-collision-window=1"},
+			random:      "aba",
+			expected:    "aba",
+		},
+		{
+			description: "random source fails",
+			args:        []string{"--jti-collision-window=4"},
+			random:      "aa",
+			expected:    "a",
+			expectedErr: io.EOF,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+			i.random = bytes.NewReader(blocks(tc.random))
+
+			for _, c := range []byte(tc.expected) {
+				jti, err := i.generateID()
+				require.NoError(t, err)
+				assert.Equal(base64.RawURLEncoding.EncodeToString(blocks(string(c))), jti)
+			}
+
+			if tc.expectedErr != nil {
+				_, err := i.generateID()
+				assert.ErrorIs(err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestIssueHandlerInheritClaim(t *testing.T) {
+	other := newTestServer(t)
+	tests := []struct {
+		description string
+		args        []string
+
+		// subjectToken, when set, produces the subject_token from the server under test.
+		subjectToken   func(*testing.T, http.Handler) string
+		form           string
+		expectedStatus int
+		expectedClaims map[string]any
+		expectedAbsent []string
+	}{
+		{
+			description: "not supported",
+			subjectToken: func(t *testing.T, h http.Handler) string {
+				return issueToken(t, h, "claim=tenant=acme")
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description: "inherited",
+			args:        []string{"--inherit-claim=tenant,missing"},
+			subjectToken: func(t *testing.T, h http.Handler) string {
+				return issueToken(t, h, "claim=tenant=acme&claim=other=x")
+			},
+			expectedStatus: http.StatusOK,
+			expectedClaims: map[string]any{"tenant": "acme"},
+			expectedAbsent: []string{"other", "missing"},
+		},
+		{
+			description: "replaces a supplied claim",
+			args:        []string{"--inherit-claim=tenant"},
+			subjectToken: func(t *testing.T, h http.Handler) string {
+				return issueToken(t, h, "claim=tenant=acme")
+			},
+			form:           "claim=tenant=evil&claim=other=x",
+			expectedStatus: http.StatusOK,
+			expectedClaims: map[string]any{"tenant": "acme", "other": "x"},
+		},
+		{
+			description: "unverified",
+			args:        []string{"--inherit-claim=tenant"},
+			subjectToken: func(t *testing.T, _ http.Handler) string {
+				return issueToken(t, other, "claim=tenant=acme")
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description: "malformed",
+			args:        []string{"--inherit-claim=tenant"},
+			subjectToken: func(*testing.T, http.Handler) string {
+				return "not a token"
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:    "no subject token",
+			args:           []string{"--inherit-claim=tenant"},
+			form:           "claim=tenant=acme",
+			expectedStatus: http.StatusOK,
+			expectedClaims: map[string]any{"tenant": "acme"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			h := newTestServer(t, tc.args...)
+			form, err := url.ParseQuery(tc.form)
+			require.NoError(t, err)
+			if tc.subjectToken != nil {
+				form.Set(SubjectTokenParameter, tc.subjectToken(t, h))
+			}
+
+			response := serve(h, http.MethodPost, "/issue", strings.NewReader(form.Encode()))
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			claims := tokenClaims(t, response.Body.String())
+			for name, value := range tc.expectedClaims {
+				assert.Equal(value, claims[name], name)
+			}
+
+			for _, name := range tc.expectedAbsent {
+				assert.NotContains(claims, name)
+			}
+		})
+	}
+}
+
+func TestIssuerScopeExpires(t *testing.T) {
+	scopes := []string{"--scope-expires=admin=5m", "--scope-expires=read=1h"}
+	tests := []struct {
+		description string
+		args        []string
+		form        string
+		expected    time.Duration
+	}{
+		{
+			description: "no scope",
+			args:        scopes,
+			expected:    15 * time.Minute,
+		},
+		{
+			description: "unconfigured scope",
+			args:        scopes,
+			form:        "scope=write",
+			expected:    15 * time.Minute,
+		},
+		{
+			description: "configured scope",
+			args:        scopes,
+			form:        "scope=read",
+			expected:    time.Hour,
+		},
+		{
+			description: "shortest configured scope",
+			args:        scopes,
+			form:        "scope=read+admin+write",
+			expected:    5 * time.Minute,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			ir, err := newTestIssueRequest(i, tc.form)
+			require.NoError(t, err)
+			assert.Equal(tc.expected, i.ExpiresIn(ir))
+
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+			exp, _ := token.Expiration()
+			iat, _ := token.IssuedAt()
+			assert.Equal(tc.expected, exp.Sub(iat))
+			assert.Equal(max(tc.expected, time.Hour), i.MaxLifetime())
+		})
+	}
+}
+
+func TestIssueHandlerPostOnly(t *testing.T) {
+	tests := []struct {
+		description    string
+		args           []string
+		method         string
+		target         string
+		body           string
+		expectedStatus int
+		expectedAud    any
+	}{
+		{
+			description:    "GET",
+			method:         http.MethodGet,
+			target:         "/issue?aud=query",
+			expectedStatus: http.StatusOK,
+			expectedAud:    []any{"query"},
+		},
+		{
+			description:    "POST with query parameters",
+			method:         http.MethodPost,
+			target:         "/issue?aud=query",
+			expectedStatus: http.StatusOK,
+			expectedAud:    []any{"query"},
+		},
+		{
+			description:    "GET when POST only",
+			args:           []string{"--issue-post-only"},
+			method:         http.MethodGet,
+			target:         "/issue?aud=query",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			description:    "POST query parameters when POST only",
+			args:           []string{"--issue-post-only"},
+			method:         http.MethodPost,
+			target:         "/issue?aud=query",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "POST body when POST only",
+			args:           []string{"--issue-post-only"},
+			method:         http.MethodPost,
+			target:         "/issue?aud=query",
+			body:           "aud=body",
+			expectedStatus: http.StatusOK,
+			expectedAud:    []any{"body"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newTestServer(t, tc.args...)
+			response := serve(h, tc.method, tc.target, strings.NewReader(tc.body))
+			require.Equal(t, tc.expectedStatus, response.Code)
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, tc.expectedAud, tokenClaims(t, response.Body.String())["aud"])
+			}
+		})
+	}
+}
+
+func TestIssuerExpiresIn(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		form        string
+		expected    time.Duration
+		expectedErr error
+	}{
+		{
+			description: "default lifetime",
+			expected:    15 * time.Minute,
+		},
+		{
+			description: "shorter lifetime",
+			form:        "expires_in=60",
+			expected:    time.Minute,
+		},
+		{
+			description: "longer lifetime",
+			form:        "expires_in=1800",
+			expected:    30 * time.Minute,
+		},
+		{
+			description: "clamped to the maximum",
+			form:        "expires_in=86400",
+			expected:    time.Hour,
+		},
+		{
+			description: "clamped to a configured maximum",
+			args:        []string{"--max-expires=20m"},
+			form:        "expires_in=86400",
+			expected:    20 * time.Minute,
+		},
+		{
+			description: "clamped to the scope lifetime",
+			args:        []string{"--scope-expires=admin=5m"},
+			form:        "expires_in=1800&scope=admin",
+			expected:    5 * time.Minute,
+		},
+		{
+			description: "clamped to MaxExpires",
+			form:        "expires_in=999999999999",
+			expected:    time.Hour,
+		},
+		{
+			description: "zero",
+			form:        "expires_in=0",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+		{
+			description: "not seconds",
+			form:        "expires_in=1h",
+			expectedErr: ErrInvalidIssueRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			ir, err := newTestIssueRequest(i, tc.form)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(tc.expected, i.ExpiresIn(ir))
+			assert.GreaterOrEqual(i.MaxLifetime(), tc.expected)
+		})
+	}
+}
+
+func TestIssueHandlerExpiresInHistogram(t *testing.T) {
+	tests := []struct {
+		description   string
+		forms         []string
+		expectedCount uint64
+		expectedSum   float64
+	}{
+		{
+			description: "no tokens",
+		},
+		{
+			description:   "default lifetime",
+			forms:         []string{""},
+			expectedCount: 1,
+			expectedSum:   900,
+		},
+		{
+			description:   "requested lifetimes",
+			forms:         []string{"expires_in=60", "expires_in=300", "expires_in=86400"},
+			expectedCount: 3,
+			expectedSum:   60 + 300 + 3600,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var (
+				s *http.Server
+				g prometheus.Gatherer
+			)
+
+			startTestApp(t, nil, &s, &g)
+			for _, form := range tc.forms {
+				issueToken(t, s.Handler, form)
+			}
+
+			histogram := gatherMetric(t, g, "issued_token_expires_in_seconds").GetMetric()[0].GetHistogram()
+			assert.Equal(t, tc.expectedCount, histogram.GetSampleCount())
+			assert.Equal(t, tc.expectedSum, histogram.GetSampleSum())
+		})
+	}
+}
+
+func TestIssuerClientIPClaim(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		remoteAddr  string
+		headers     map[string]string
+		claim       string
+		expected    any
+	}{
+		{
+			description: "disabled",
+			remoteAddr:  "192.0.2.1:1234",
+			claim:       "client_ip",
+		},
+		{
+			description: "IPv4 peer",
+			args:        []string{"--client-ip-claim=client_ip"},
+			remoteAddr:  "192.0.2.1:1234",
+			claim:       "client_ip",
+			expected:    "192.0.2.1",
+		},
+		{
+			description: "IPv6 peer",
+			args:        []string{"--client-ip-claim=ip"},
+			remoteAddr:  "[2001:db8::1]:1234",
+			claim:       "ip",
+			expected:    "2001:db8::1",
+		},
+		{
+			description: "forwarding headers from an untrusted peer are ignored",
+			args:        []string{"--client-ip-claim=client_ip"},
+			remoteAddr:  "192.0.2.1:1234",
+			claim:       "client_ip",
+			headers:     map[string]string{"X-Forwarded-For": "198.51.100.7"},
+			expected:    "192.0.2.1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/issue", nil)
+			request.RemoteAddr = tc.remoteAddr
+			for name, value := range tc.headers {
+				request.Header.Set(name, value)
+			}
+
+			ir, err := i.NewIssueRequest(request)
+			require.NoError(t, err)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			claims := tokenMap(t, token)
+			if tc.expected == nil {
+				assert.NotContains(t, claims, tc.claim)
+			} else {
+				assert.Equal(t, tc.expected, claims[tc.claim])
+			}
+		})
+	}
+}
+
+func TestActorClaim(t *testing.T) {
+	tests := []struct {
+		description   string
+		actors        []string
+		prior         map[string]any
+		expected      map[string]any
+		expectedDepth int
+	}{
+		{
+			description: "no actors",
+		},
+		{
+			description:   "one actor",
+			actors:        []string{"a"},
+			expected:      map[string]any{"sub": "a"},
+			expectedDepth: 1,
+		},
+		{
+			description: "delegation chain",
+			actors:      []string{"a", "b"},
+			expected: map[string]any{
+				"sub": "a",
+				"act": map[string]any{"sub": "b"},
+			},
+			expectedDepth: 2,
+		},
+		{
+			description:   "prior chain only",
+			prior:         map[string]any{"sub": "p"},
+			expected:      map[string]any{"sub": "p"},
+			expectedDepth: 1,
+		},
+		{
+			description: "prior chain beneath the actors",
+			actors:      []string{"a"},
+			prior:       map[string]any{"sub": "p", "act": map[string]any{"sub": "q"}},
+			expected: map[string]any{
+				"sub": "a",
+				"act": map[string]any{
+					"sub": "p",
+					"act": map[string]any{"sub": "q"},
+				},
+			},
+			expectedDepth: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			act := actorClaim(tc.actors, tc.prior)
+			if tc.expected == nil {
+				assert.Nil(t, act)
+			} else {
+				assert.Equal(t, tc.expected, act)
+			}
+
+			assert.Equal(t, tc.expectedDepth, actorDepth(act))
+		})
+	}
+}
+
+func TestIssueHandlerActors(t *testing.T) {
+	tooMany := strings.Repeat("act_sub=a&", MaxActors) + "act_sub=a"
+	tests := []struct {
+		description string
+
+		// subjectForm, if set, issues a subject token that the request inherits from.
+		subjectForm    string
+		form           string
+		expectedStatus int
+		expectedAct    any
+	}{
+		{
+			description:    "no actors",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "delegation chain",
+			form:           "act_sub=a&act_sub=b",
+			expectedStatus: http.StatusOK,
+			expectedAct:    map[string]any{"sub": "a", "act": map[string]any{"sub": "b"}},
+		},
+		{
+			description:    "too many actors",
+			form:           tooMany,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:    "chained from a subject token",
+			subjectForm:    "act_sub=b",
+			form:           "act_sub=a",
+			expectedStatus: http.StatusOK,
+			expectedAct:    map[string]any{"sub": "a", "act": map[string]any{"sub": "b"}},
+		},
+		{
+			description:    "chain from a subject token too long",
+			subjectForm:    strings.Repeat("act_sub=b&", MaxActors-1) + "act_sub=b",
+			form:           "act_sub=a",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	h := newTestServer(t, "--inherit-claim=tenant")
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			form := tc.form
+			if len(tc.subjectForm) > 0 {
+				form += "&subject_token=" + issueToken(t, h, tc.subjectForm)
+			}
+
+			response := serve(h, http.MethodPost, "/issue", strings.NewReader(form))
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, tc.expectedAct, tokenClaims(t, response.Body.String())[ActorClaim])
+			}
+		})
+	}
+}
+
+func TestIssuerBindClientCert(t *testing.T) {
+	c, _ := newTestCertificate(t)
+	sum := sha256.Sum256(c.Raw)
+	thumbprint := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		description string
+		args        []string
+		state       *tls.ConnectionState
+		expectedCnf any
+	}{
+		{
+			description: "binding disabled",
+			state:       &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{c}}},
+		},
+		{
+			description: "no TLS",
+			args:        []string{"--bind-client-cert"},
+		},
+		{
+			description: "no client certificate",
+			args:        []string{"--bind-client-cert"},
+			state:       new(tls.ConnectionState),
+		},
+		{
+			description: "unverified client certificate",
+			args:        []string{"--bind-client-cert"},
+			state:       &tls.ConnectionState{PeerCertificates: []*x509.Certificate{c}},
+		},
+		{
+			description: "verified client certificate",
+			args:        []string{"--bind-client-cert"},
+			state:       &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{c}}},
+			expectedCnf: map[string]any{X5TS256Member: thumbprint},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			i, err := newTestIssuer(t, tc.args...)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/issue", nil)
+			request.TLS = tc.state
+			ir, err := i.NewIssueRequest(request)
+			require.NoError(t, err)
+
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedCnf, tokenMap(t, token)[ConfirmationClaim])
+		})
+	}
+}
+
+func TestIssueHandlerDebug(t *testing.T) {
+	tests := []struct {
+		description     string
+		args            []string
+		target          string
+		form            string
+		headers         []string
+		expectedStatus  int
+		expectedDebug   bool
+		expectedWarning bool
+	}{
+		{
+			description:    "no debug",
+			target:         "/issue",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "debug false",
+			target:         "/issue?debug=false",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "unauthenticated debug",
+			target:         "/issue?debug=true",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "wrong admin token",
+			target:         "/issue?debug=true",
+			headers:        []string{"Authorization: Bearer wrong"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "debug",
+			target:         "/issue?debug=true",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusOK,
+			expectedDebug:  true,
+		},
+		{
+			description:    "debug with encryption",
+			args:           []string{"--recipients=" + writeRecipients(t, newRecipientKey(t, "EC"))},
+			target:         "/issue?debug=true",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusOK,
+			expectedDebug:  true,
+		},
+		{
+			description:    "debug over the token size budget",
+			args:           []string{"--max-token-bytes=10", "--max-token-bytes-mode=reject"},
+			target:         "/issue?debug=true",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:     "debug warns over the token size budget",
+			args:            []string{"--max-token-bytes=10"},
+			target:          "/issue?debug=true",
+			headers:         []string{testAdminAuth},
+			expectedStatus:  http.StatusOK,
+			expectedDebug:   true,
+			expectedWarning: true,
+		},
+		{
+			description:    "debug in the form",
+			target:         "/issue",
+			form:           "debug=true",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusOK,
+			expectedDebug:  true,
+		},
+		{
+			description:    "post only debug in the form",
+			args:           []string{"--issue-post-only"},
+			target:         "/issue",
+			form:           "debug=true",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusOK,
+			expectedDebug:  true,
+		},
+		{
+			description:    "post only debug in the query",
+			args:           []string{"--issue-post-only"},
+			target:         "/issue?debug=true",
+			form:           "claim=tenant=acme",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			h := newTestServer(t, append([]string{"--admin-token=" + testAdminToken}, tc.args...)...)
+
+			method, body := http.MethodGet, io.Reader(nil)
+			if len(tc.form) > 0 {
+				method, body = http.MethodPost, strings.NewReader(tc.form)
+			}
+
+			response := serve(h, method, tc.target, body, tc.headers...)
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+
+			switch {
+			case tc.expectedStatus == http.StatusUnauthorized:
+				assert.Equal(AdminAuthChallenge, response.Header().Get("WWW-Authenticate"))
+
+			case tc.expectedStatus == http.StatusBadRequest:
+				assert.Contains(response.Body.String(), "exceeds the budget")
+
+			case tc.expectedDebug:
+				assert.Equal("application/json", response.Header().Get("Content-Type"))
+
+				// the size budget is checked against the signed token, never the debug JSON
+				assert.Equal(tc.expectedWarning, len(response.Header().Values("Warning")) > 0)
+
+				var idr IssueDebugResponse
+				require.NoError(t, json.Unmarshal(response.Body.Bytes(), &idr))
+
+				// the decoded parts are those of the signed, compact token
+				claims := tokenClaims(t, idr.Token)
+				var payload map[string]any
+				require.NoError(t, json.Unmarshal(idr.Payload, &payload))
+				assert.Equal(claims, payload)
+
+				var header map[string]any
+				require.NoError(t, json.Unmarshal(idr.Header, &header))
+				assert.Equal(tokenHeader(t, idr.Token), header)
+
+			default:
+				assert.Equal("application/jwt", response.Header().Get("Content-Type"))
+				assert.NotEmpty(tokenClaims(t, response.Body.String())["jti"])
+			}
+		})
+	}
+}
+
+func TestIssuerDeprecatedClaims(t *testing.T) {
+	deprecated := []string{"--deprecated-claim=user=sub2", "--deprecated-claim=grp="}
+	tests := []struct {
+		description      string
+		args             []string
+		form             string
+		expectedStatus   int
+		expectedBody     string
+		expectedWarnings []string
+	}{
+		{
+			description:    "no deprecated claims",
+			args:           deprecated,
+			form:           "claim=tenant=acme",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:      "deprecated claim with a replacement",
+			args:             deprecated,
+			form:             "claim=user=joe",
+			expectedStatus:   http.StatusOK,
+			expectedWarnings: []string{`299 - "the user claim is deprecated, use sub2 instead"`},
+		},
+		{
+			description:    "deprecated claims",
+			args:           deprecated,
+			form:           "claim=user=joe&claim=grp=admins",
+			expectedStatus: http.StatusOK,
+			expectedWarnings: []string{
+				`299 - "the grp claim is deprecated"`,
+				`299 - "the user claim is deprecated, use sub2 instead"`,
+			},
+		},
+		{
+			description:    "rejected deprecated claim",
+			args:           append([]string{"--deprecated-claim-mode=reject"}, deprecated...),
+			form:           "claim=grp=admins",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid issue request: the grp claim is deprecated",
+		},
+		{
+			description: "rejected deprecated claims",
+			args: append(
+				[]string{"--deprecated-claim-mode=reject", "--deprecated-claim=dept=", "--deprecated-claim=org=", "--deprecated-claim=role="},
+				deprecated...,
+			),
+			form:           "claim=user=joe&claim=role=admin&claim=org=acme&claim=grp=admins&claim=dept=ops",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid issue request: the dept claim is deprecated",
+		},
+		{
+			description:    "reject mode without deprecated claims",
+			args:           append([]string{"--deprecated-claim-mode=reject"}, deprecated...),
+			form:           "claim=tenant=acme",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newTestServer(t, append([]string{"--admin-token=" + testAdminToken}, tc.args...)...)
+			for _, target := range []struct {
+				path    string
+				body    string
+				headers []string
+			}{
+				{path: "/issue", body: tc.form},
+				{path: "/token", body: "grant_type=client_credentials&" + tc.form, headers: []string{testAdminAuth}},
+			} {
+				response := serve(h, http.MethodPost, target.path, strings.NewReader(target.body), target.headers...)
+				require.Equal(t, tc.expectedStatus, response.Code, target.path)
+				assert.Equal(t, tc.expectedWarnings, response.Header().Values("Warning"), target.path)
+				if len(tc.expectedBody) > 0 {
+					// the rejected claim is always the first by name
+					assert.Contains(t, response.Body.String(), tc.expectedBody, target.path)
+				}
+			}
+		})
+	}
+}
@@ -5,11 +5,31 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"time"
 
 	"github.com/lestrrat-go/jwx/v3/jwa"
 	"github.com/lestrrat-go/jwx/v3/jwk"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNoPublicKey indicates that a key has no public component, as with symmetric keys.
+	ErrNoPublicKey = errors.New("the key has no public component")
+
+	// ErrNoKeyMaterial indicates that a Key has no JWK at all.
+	ErrNoKeyMaterial = errors.New("the key has no key material")
+)
+
+const (
+	// CreatedParameter is the additional JWK member that carries a Key's
+	// Created time, expressed in seconds since the epoch.
+	CreatedParameter = "utu_created"
+
+	// ExpiresParameter is the additional JWK member that carries a Key's
+	// Expires time, expressed in seconds since the epoch.
+	ExpiresParameter = "utu_expires"
 )
 
 // Key represents a signing and/or verification key.
@@ -21,6 +41,17 @@ type Key struct {
 	Expires time.Time
 }
 
+// ForEncryption tests if this is an encryption key, i.e. one whose use is enc,
+// rather than a signing key.
+func (k Key) ForEncryption() bool {
+	if k.Key == nil {
+		return false
+	}
+
+	use, _ := k.Key.KeyUsage()
+	return use == jwk.ForEncryption.String()
+}
+
 // PublicKey produces a Key that represents only public key material.
 // All other fields, such as the KID, are copied over as is.
 //
@@ -37,6 +68,56 @@ func (k Key) PublicKey() (pk Key, err error) {
 	return
 }
 
+// PublicJWK produces the PUBLIC portion of this key as a JWK. The returned
+// JWK carries the CreatedParameter and ExpiresParameter members, which lets
+// caching clients know when this key stops being used.
+//
+// Symmetric keys have no public component, and this method returns ErrNoPublicKey
+// for them so that secrets are never published.
+func (k Key) PublicJWK() (public jwk.Key, err error) {
+	switch {
+	case k.Key == nil:
+		err = ErrNoKeyMaterial
+		return
+
+	case k.Key.KeyType() == jwa.OctetSeq():
+		err = ErrNoPublicKey
+		return
+	}
+
+	public, err = k.Key.PublicKey()
+	if err == nil {
+		err = k.setMetadata(public)
+	}
+
+	return
+}
+
+// ExportJWK produces a copy of this key's JWK, including any private material,
+// with the CreatedParameter and ExpiresParameter members. This method must
+// only be used where private keys may safely be disclosed.
+func (k Key) ExportJWK() (exported jwk.Key, err error) {
+	exported, err = k.Key.Clone()
+	if err == nil {
+		err = k.setMetadata(exported)
+	}
+
+	return
+}
+
+// setMetadata sets the utu validity members on the given JWK.
+func (k Key) setMetadata(dst jwk.Key) (err error) {
+	if !k.Created.IsZero() {
+		err = dst.Set(CreatedParameter, k.Created.Unix())
+	}
+
+	if err == nil && !k.Expires.IsZero() {
+		err = dst.Set(ExpiresParameter, k.Expires.Unix())
+	}
+
+	return
+}
+
 // WriteTo writes the PUBLIC portion of this key to the given writer
 // in JWK format.
 func (k Key) WriteTo(dst io.Writer) (n int64, err error) {
@@ -45,7 +126,7 @@ func (k Key) WriteTo(dst io.Writer) (n int64, err error) {
 		data   []byte
 	)
 
-	public, err = k.Key.PublicKey()
+	public, err = k.PublicJWK()
 	if err == nil {
 		data, err = json.Marshal(public)
 	}
@@ -60,12 +141,18 @@ func (k Key) WriteTo(dst io.Writer) (n int64, err error) {
 }
 
 // NewPublicSet creates a JWK key set using only public key material.
-func NewPublicSet(keys ...Key) (set jwk.Set, err error) {
+// Symmetric keys are omitted. Any other key that cannot produce a public
+// component is also omitted, with a warning, rather than failing the whole set.
+func NewPublicSet(l *zap.Logger, keys ...Key) (set jwk.Set, err error) {
 	set = jwk.NewSet()
 	for i := 0; err == nil && i < len(keys); i++ {
-		var pk jwk.Key
-		if pk, err = keys[i].Key.PublicKey(); err == nil {
+		pk, pkErr := keys[i].PublicJWK()
+		switch {
+		case pkErr == nil:
 			err = set.AddKey(pk)
+
+		case !errors.Is(pkErr, ErrNoPublicKey):
+			l.Warn("skipping key without a usable public component", KeyField("key", keys[i]), zap.Error(pkErr))
 		}
 	}
 
@@ -17,25 +17,68 @@ var (
 )
 
 // KeyAccessor is a simple, atomic access point for the current signing key.
+//
+// A KeyAccessor may hold a pool of current keys. The first key in the pool is
+// the current key, and Next selects keys from the pool in round-robin order.
 type KeyAccessor struct {
 	current atomic.Value
+	next    atomic.Uint64
+}
+
+// LoadAll returns the current pool of signing keys. If no signing key has been
+// set yet, this method returns ErrNoCurrentKey.
+func (ck *KeyAccessor) LoadAll() (pool []Key, err error) {
+	pool, _ = ck.current.Load().([]Key)
+	if len(pool) == 0 {
+		err = ErrNoCurrentKey
+	}
+
+	return
 }
 
 // Load returns the current signing key. If no signing key has been set yet,
 // this method returns ErrNoCurrentKey.
 func (ck *KeyAccessor) Load() (k Key, err error) {
-	var ok bool
-	k, ok = ck.current.Load().(Key)
-	if !ok {
-		err = ErrNoCurrentKey
+	var pool []Key
+	if pool, err = ck.LoadAll(); err == nil {
+		k = pool[0]
+	}
+
+	return
+}
+
+// Next returns the next signing key from the pool in round-robin order. If no
+// signing key has been set yet, this method returns ErrNoCurrentKey.
+func (ck *KeyAccessor) Next() (k Key, err error) {
+	var pool []Key
+	if pool, err = ck.LoadAll(); err == nil {
+		k = pool[(ck.next.Add(1)-1)%uint64(len(pool))]
+	}
+
+	return
+}
+
+// Lookup returns the key in the current pool with the given kid.
+func (ck *KeyAccessor) Lookup(kid string) (k Key, ok bool) {
+	pool, _ := ck.LoadAll()
+	for i := 0; !ok && i < len(pool); i++ {
+		k, ok = pool[i], pool[i].KID == kid
 	}
 
 	return
 }
 
-// Store updates the current key.
+// Store updates the current key. This replaces the pool with just the given key.
 func (ck *KeyAccessor) Store(k Key) {
-	ck.current.Store(k)
+	ck.StoreAll(k)
+}
+
+// StoreAll replaces the pool of current keys. The first key is the current key.
+// This method does nothing if no keys are supplied.
+func (ck *KeyAccessor) StoreAll(pool ...Key) {
+	if len(pool) > 0 {
+		ck.current.Store(append([]Key(nil), pool...))
+	}
 }
 
 func ProvideKeyAccessor() fx.Option {
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyAccessorNext(t *testing.T) {
+	tests := []struct {
+		description string
+		pool        []string
+		lookups     int
+		expected    []string
+	}{
+		{
+			description: "single key",
+			pool:        []string{"a"},
+			lookups:     3,
+			expected:    []string{"a", "a", "a"},
+		},
+		{
+			description: "round robin",
+			pool:        []string{"a", "b", "c"},
+			lookups:     7,
+			expected:    []string{"a", "b", "c", "a", "b", "c", "a"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var ka KeyAccessor
+			_, err := ka.Next()
+			assert.ErrorIs(err, ErrNoCurrentKey)
+
+			pool := make([]Key, 0, len(tc.pool))
+			for _, kid := range tc.pool {
+				pool = append(pool, Key{KID: kid})
+			}
+
+			ka.StoreAll(pool...)
+			current, err := ka.Load()
+			require.NoError(t, err)
+			assert.Equal(tc.pool[0], current.KID)
+
+			actual := make([]string, 0, tc.lookups)
+			for range tc.lookups {
+				k, err := ka.Next()
+				require.NoError(t, err)
+				actual = append(actual, k.KID)
+			}
+
+			assert.Equal(tc.expected, actual)
+			for _, kid := range tc.pool {
+				_, ok := ka.Lookup(kid)
+				assert.True(ok)
+			}
+
+			_, ok := ka.Lookup("missing")
+			assert.False(ok)
+		})
+	}
+}
+
+func TestSigningPool(t *testing.T) {
+	tests := []struct {
+		description string
+		size        string
+		expected    int
+	}{
+		{description: "one key", size: "1", expected: 1},
+		{description: "three keys", size: "3", expected: 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			h := newTestServer(t, "--signing-pool-size="+tc.size)
+			assert.Equal(tc.expected, publishedKeys(t, h).Len())
+
+			kids := make(map[any]int)
+			for range 2 * tc.expected {
+				kids[tokenHeader(t, issueToken(t, h, ""))["kid"]]++
+			}
+
+			assert.Len(kids, tc.expected)
+			for kid, n := range kids {
+				assert.Equal(2, n, "kid %v", kid)
+			}
+		})
+	}
+}
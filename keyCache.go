@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// KeyLookupHit is the result label value for key lookups served from the cache.
+	KeyLookupHit = "hit"
+
+	// KeyLookupMiss is the result label value for key lookups that went to the underlying store.
+	KeyLookupMiss = "miss"
+)
+
+// NewKeyLookupCounter creates the counter that tracks key lookups by cache result.
+func NewKeyLookupCounter() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "key_lookups_total",
+			Help:      "the number of key lookups by kid, labeled by whether the key cache was hit",
+		},
+		[]string{"result"},
+	)
+}
+
+// cachedKey is a Key along with the time it leaves the cache.
+type cachedKey struct {
+	key     Key
+	expires time.Time
+}
+
+// CachingKeyStore is a KeyStore decorator that serves Load from an in-memory cache,
+// falling through to the decorated KeyStore on a miss. This is useful for external
+// stores where each lookup is expensive.
+type CachingKeyStore struct {
+	KeyStore
+
+	lookups *prometheus.CounterVec
+	ttl     time.Duration
+	now     func() time.Time
+
+	lock  sync.RWMutex
+	cache map[string]cachedKey
+}
+
+func NewCachingKeyStore(next KeyStore, ttl time.Duration, lookups *prometheus.CounterVec) *CachingKeyStore {
+	return &CachingKeyStore{
+		KeyStore: next,
+		lookups:  lookups,
+		ttl:      ttl,
+		now:      time.Now,
+		cache:    make(map[string]cachedKey),
+	}
+}
+
+// Unwrap returns the decorated KeyStore.
+func (s *CachingKeyStore) Unwrap() KeyStore {
+	return s.KeyStore
+}
+
+func (s *CachingKeyStore) Store(k Key) (err error) {
+	err = s.KeyStore.Store(k)
+	s.lock.Lock()
+	delete(s.cache, k.KID)
+	s.lock.Unlock()
+	return
+}
+
+func (s *CachingKeyStore) Load(kid string) (k Key, err error) {
+	now := s.now()
+	s.lock.RLock()
+	ck, exists := s.cache[kid]
+	s.lock.RUnlock()
+
+	if exists && now.Before(ck.expires) {
+		s.lookups.WithLabelValues(KeyLookupHit).Inc()
+		k = ck.key
+		return
+	}
+
+	s.lookups.WithLabelValues(KeyLookupMiss).Inc()
+	k, err = s.KeyStore.Load(kid)
+	if err == nil {
+		s.lock.Lock()
+		s.cache[kid] = cachedKey{key: k, expires: now.Add(s.ttl)}
+		s.lock.Unlock()
+	}
+
+	return
+}
+
+func (s *CachingKeyStore) Delete(kid string) (err error) {
+	err = s.KeyStore.Delete(kid)
+	s.lock.Lock()
+	delete(s.cache, kid)
+	s.lock.Unlock()
+	return
+}
+
+// MissCachingKeyStore is a KeyStore decorator that remembers kids which the decorated
+// KeyStore reported as missing. Repeated lookups of a missing kid, such as from clients
+// probing with random kids, are then answered without going to the underlying store.
+//
+// The number of remembered kids is bounded, so random kids cannot exhaust memory.
+type MissCachingKeyStore struct {
+	KeyStore
+
+	ttl     time.Duration
+	maxSize int
+	now     func() time.Time
+
+	lock   sync.Mutex
+	misses map[string]time.Time
+}
+
+func NewMissCachingKeyStore(next KeyStore, ttl time.Duration, maxSize int) *MissCachingKeyStore {
+	return &MissCachingKeyStore{
+		KeyStore: next,
+		ttl:      ttl,
+		maxSize:  maxSize,
+		now:      time.Now,
+		misses:   make(map[string]time.Time),
+	}
+}
+
+// Unwrap returns the decorated KeyStore.
+func (s *MissCachingKeyStore) Unwrap() KeyStore {
+	return s.KeyStore
+}
+
+func (s *MissCachingKeyStore) Store(k Key) (err error) {
+	err = s.KeyStore.Store(k)
+	s.lock.Lock()
+	delete(s.misses, k.KID)
+	s.lock.Unlock()
+	return
+}
+
+func (s *MissCachingKeyStore) Load(kid string) (k Key, err error) {
+	now := s.now()
+	s.lock.Lock()
+	expires, missing := s.misses[kid]
+	s.lock.Unlock()
+
+	if missing && now.Before(expires) {
+		err = ErrNoSuchKey
+		return
+	}
+
+	k, err = s.KeyStore.Load(kid)
+	if errors.Is(err, ErrNoSuchKey) {
+		s.lock.Lock()
+		s.unsafeAddMiss(kid, now)
+		s.lock.Unlock()
+	}
+
+	return
+}
+
+// unsafeAddMiss remembers a missing kid, making room if the cache is full.
+// This method must be executed under the lock.
+func (s *MissCachingKeyStore) unsafeAddMiss(kid string, now time.Time) {
+	if len(s.misses) >= s.maxSize {
+		for k, expires := range s.misses {
+			if !now.Before(expires) {
+				delete(s.misses, k)
+			}
+		}
+	}
+
+	// if nothing has expired, evict an arbitrary entry
+	for k := range s.misses {
+		if len(s.misses) < s.maxSize {
+			break
+		}
+
+		delete(s.misses, k)
+	}
+
+	if s.maxSize > 0 {
+		s.misses[kid] = now.Add(s.ttl)
+	}
+}
@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingKeyStore is a KeyStore that counts the lookups that reach it.
+type countingKeyStore struct {
+	KeyStore
+	loads int
+}
+
+func (s *countingKeyStore) Load(kid string) (Key, error) {
+	s.loads++
+	return s.KeyStore.Load(kid)
+}
+
+func TestCachingKeyStore(t *testing.T) {
+	tests := []struct {
+		description string
+
+		// elapsed is the time since the first lookup at which each lookup happens.
+		elapsed []time.Duration
+
+		// deleteBefore, if positive, is the lookup before which the key is deleted.
+		deleteBefore int
+
+		expectedHits   float64
+		expectedMisses float64
+		expectedLoads  int
+	}{
+		{
+			description:    "single lookup",
+			elapsed:        []time.Duration{0},
+			expectedMisses: 1,
+			expectedLoads:  1,
+		},
+		{
+			description:    "repeated lookups within the ttl",
+			elapsed:        []time.Duration{0, time.Second, 59 * time.Second},
+			expectedHits:   2,
+			expectedMisses: 1,
+			expectedLoads:  1,
+		},
+		{
+			description:    "lookup after the ttl",
+			elapsed:        []time.Duration{0, time.Minute, time.Minute + time.Second},
+			expectedHits:   1,
+			expectedMisses: 2,
+			expectedLoads:  2,
+		},
+		{
+			description:    "deleting evicts the key",
+			elapsed:        []time.Duration{0, time.Second},
+			deleteBefore:   1,
+			expectedMisses: 2,
+			expectedLoads:  2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			next := &countingKeyStore{KeyStore: NewInMemoryKeyStore()}
+			lookups := NewKeyLookupCounter()
+			s := NewCachingKeyStore(next, time.Minute, lookups)
+
+			start := time.Now()
+			k := newTestKey(t)
+			require.NoError(t, s.Store(k))
+
+			for i, elapsed := range tc.elapsed {
+				s.now = func() time.Time { return start.Add(elapsed) }
+				if tc.deleteBefore > 0 && i == tc.deleteBefore {
+					require.NoError(t, s.Delete(k.KID))
+				}
+
+				loaded, err := s.Load(k.KID)
+				if tc.deleteBefore > 0 && i >= tc.deleteBefore {
+					assert.ErrorIs(err, ErrNoSuchKey)
+				} else {
+					assert.NoError(err)
+					assert.Equal(k.KID, loaded.KID)
+				}
+			}
+
+			assert.Equal(tc.expectedHits, testutil.ToFloat64(lookups.WithLabelValues(KeyLookupHit)))
+			assert.Equal(tc.expectedMisses, testutil.ToFloat64(lookups.WithLabelValues(KeyLookupMiss)))
+			assert.Equal(tc.expectedLoads, next.loads)
+		})
+	}
+}
+
+func TestMissCachingKeyStore(t *testing.T) {
+	tests := []struct {
+		description string
+		maxSize     int
+
+		// lookups are the kids looked up in order, each at the given elapsed time.
+		lookups []string
+		elapsed []time.Duration
+
+		// storeBefore, if positive, is the lookup before which the "missing" kid is stored.
+		storeBefore   int
+		expectedLoads int
+	}{
+		{
+			description:   "repeated misses within the ttl",
+			maxSize:       10,
+			lookups:       []string{"missing", "missing", "missing"},
+			elapsed:       []time.Duration{0, time.Second, 59 * time.Second},
+			expectedLoads: 1,
+		},
+		{
+			description:   "miss after the ttl",
+			maxSize:       10,
+			lookups:       []string{"missing", "missing"},
+			elapsed:       []time.Duration{0, time.Minute},
+			expectedLoads: 2,
+		},
+		{
+			description:   "storing the kid forgets the miss",
+			maxSize:       10,
+			lookups:       []string{"missing", "missing"},
+			elapsed:       []time.Duration{0, time.Second},
+			storeBefore:   1,
+			expectedLoads: 2,
+		},
+		{
+			description:   "bounded size",
+			maxSize:       1,
+			lookups:       []string{"a", "b", "a"},
+			elapsed:       []time.Duration{0, 0, 0},
+			expectedLoads: 3,
+		},
+		{
+			description:   "disabled by a zero size",
+			lookups:       []string{"a", "a"},
+			elapsed:       []time.Duration{0, 0},
+			expectedLoads: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			next := &countingKeyStore{KeyStore: NewInMemoryKeyStore()}
+			s := NewMissCachingKeyStore(next, time.Minute, tc.maxSize)
+			start := time.Now()
+
+			stored := false
+			for i, kid := range tc.lookups {
+				s.now = func() time.Time { return start.Add(tc.elapsed[i]) }
+				if tc.storeBefore > 0 && i == tc.storeBefore {
+					k, err := newTestKey(t).PublicKey()
+					require.NoError(t, err)
+					k.KID = kid
+					require.NoError(t, s.Store(k))
+					stored = true
+				}
+
+				_, err := s.Load(kid)
+				if stored {
+					assert.NoError(err)
+				} else {
+					assert.ErrorIs(err, ErrNoSuchKey)
+				}
+			}
+
+			assert.Equal(tc.expectedLoads, next.loads)
+			assert.LessOrEqual(len(s.misses), tc.maxSize)
+		})
+	}
+}
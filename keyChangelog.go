@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// SinceParameter is the request parameter that holds the RFC 3339 time after
+	// which key changes are reported.
+	SinceParameter = "since"
+
+	// MaxKeyChanges is the number of key changes retained by a ChangelogKeyStore.
+	MaxKeyChanges = 1024
+)
+
+// keyChange is a single addition or removal of a kid.
+type keyChange struct {
+	kid     string
+	added   bool
+	changed time.Time
+}
+
+// ChangelogKeyStore is a KeyStore decorator that records when kids are added and
+// removed, so that caching clients can ask what changed since they last polled.
+// Only the most recent MaxKeyChanges changes are retained.
+type ChangelogKeyStore struct {
+	KeyStore
+
+	now func() time.Time
+
+	lock sync.Mutex
+
+	// changes is in the order the changes happened.
+	changes []keyChange
+
+	// truncated is the time of the newest change discarded from changes.
+	truncated time.Time
+}
+
+func NewChangelogKeyStore(next KeyStore) *ChangelogKeyStore {
+	return &ChangelogKeyStore{
+		KeyStore: next,
+		now:      time.Now,
+	}
+}
+
+// Unwrap returns the decorated KeyStore.
+func (s *ChangelogKeyStore) Unwrap() KeyStore {
+	return s.KeyStore
+}
+
+// record appends a change, discarding the oldest change if the log is full.
+func (s *ChangelogKeyStore) record(kid string, added bool) {
+	s.lock.Lock()
+	if len(s.changes) >= MaxKeyChanges {
+		s.truncated = s.changes[0].changed
+		s.changes = slices.Delete(s.changes, 0, 1)
+	}
+
+	s.changes = append(s.changes, keyChange{kid: kid, added: added, changed: s.now()})
+	s.lock.Unlock()
+}
+
+// Store records an addition if the kid is new to the decorated KeyStore.
+func (s *ChangelogKeyStore) Store(k Key) (err error) {
+	_, loadErr := s.KeyStore.Load(k.KID)
+	err = s.KeyStore.Store(k)
+	if err == nil && loadErr != nil {
+		s.record(k.KID, true)
+	}
+
+	return
+}
+
+func (s *ChangelogKeyStore) Delete(kid string) (err error) {
+	err = s.KeyStore.Delete(kid)
+	if err == nil {
+		s.record(kid, false)
+	}
+
+	return
+}
+
+// KeyChanges describes the net changes to the key set over a period of time.
+type KeyChanges struct {
+	// Since is the start of the period, exclusive.
+	Since time.Time `json:"since"`
+
+	// Now is the end of the period, which a client passes as the since of its next poll.
+	Now time.Time `json:"now"`
+
+	// Added are the kids that were added, or removed and then added again, during the period.
+	Added []string `json:"added"`
+
+	// Removed are the kids that were present at the start of the period and are now removed.
+	Removed []string `json:"removed"`
+}
+
+// Changes returns the net changes to the key set after the given time. This method
+// returns false if changes after that time are no longer retained.
+func (s *ChangelogKeyStore) Changes(since time.Time) (kc KeyChanges, ok bool) {
+	kc = KeyChanges{
+		Since:   since,
+		Added:   []string{},
+		Removed: []string{},
+	}
+
+	defer s.lock.Unlock()
+	s.lock.Lock()
+
+	kc.Now = s.now()
+	if since.Before(s.truncated) {
+		return
+	}
+
+	// a kid's first change tells whether it was present at the start of the
+	// period, and its last change tells whether it is present now
+	var (
+		order   []string
+		present = make(map[string]bool)
+		last    = make(map[string]bool)
+	)
+
+	for _, c := range s.changes {
+		if !c.changed.After(since) {
+			continue
+		}
+
+		if _, seen := last[c.kid]; !seen {
+			order = append(order, c.kid)
+			present[c.kid] = !c.added
+		}
+
+		last[c.kid] = c.added
+	}
+
+	for _, kid := range order {
+		switch {
+		case last[kid]:
+			kc.Added = append(kc.Added, kid)
+
+		case present[kid]:
+			kc.Removed = append(kc.Removed, kid)
+		}
+	}
+
+	ok = true
+	return
+}
+
+// KeyChangesHandler reports the kids added to and removed from the published key set
+// since a given time.
+type KeyChangesHandler struct {
+	logger    *zap.Logger
+	changelog *ChangelogKeyStore
+}
+
+func NewKeyChangesHandler(l *zap.Logger, keyStore KeyStore) *KeyChangesHandler {
+	kch := &KeyChangesHandler{
+		logger: l,
+	}
+
+	kch.changelog, _ = keyStoreAs[*ChangelogKeyStore](keyStore)
+	return kch
+}
+
+// ServeHTTP responds with the KeyChanges since the time in the since parameter. If
+// changes since then are no longer retained, this handler responds with 410, and the
+// client must fetch the full key set instead.
+func (kch *KeyChangesHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	since, err := time.Parse(time.RFC3339Nano, request.URL.Query().Get(SinceParameter))
+	if err != nil {
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte("since must be an RFC 3339 time"))
+		return
+	}
+
+	if kch.changelog == nil {
+		response.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	kc, ok := kch.changelog.Changes(since)
+	if !ok {
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusGone)
+		response.Write([]byte("changes since that time are no longer retained"))
+		return
+	}
+
+	var data []byte
+	if data, err = json.Marshal(kc); err == nil {
+		response.Header().Set("Cache-Control", "no-cache")
+		writeBody(response, "application/json", data)
+	} else {
+		kch.logger.Error("unable to render key changes", zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestChangelog creates a ChangelogKeyStore whose clock advances one second for
+// each change, and applies the given changes. A change is a kid prefixed by + to
+// store it or - to delete it. The clock starts at the returned time.
+func newTestChangelog(t *testing.T, changes ...string) (*ChangelogKeyStore, time.Time) {
+	start := time.Now().Truncate(time.Second)
+	current := start
+	s := NewChangelogKeyStore(NewInMemoryKeyStore())
+	s.now = func() time.Time { return current }
+
+	k, err := newTestKey(t).PublicKey()
+	require.NoError(t, err)
+
+	for _, c := range changes {
+		current = current.Add(time.Second)
+		k.KID = c[1:]
+		if c[0] == '+' {
+			require.NoError(t, s.Store(k))
+		} else {
+			require.NoError(t, s.Delete(k.KID))
+		}
+	}
+
+	return s, start
+}
+
+func TestChangelogKeyStore(t *testing.T) {
+	tests := []struct {
+		description string
+		changes     []string
+
+		// since is the number of changes that happened before the since time.
+		since           int
+		expectedAdded   []string
+		expectedRemoved []string
+	}{
+		{
+			description:     "no changes",
+			expectedAdded:   []string{},
+			expectedRemoved: []string{},
+		},
+		{
+			description:     "added",
+			changes:         []string{"+a", "+b"},
+			expectedAdded:   []string{"a", "b"},
+			expectedRemoved: []string{},
+		},
+		{
+			description:     "stored again",
+			changes:         []string{"+a", "+a"},
+			expectedAdded:   []string{"a"},
+			expectedRemoved: []string{},
+		},
+		{
+			description:     "removed",
+			changes:         []string{"+a", "+b", "-a"},
+			since:           2,
+			expectedAdded:   []string{},
+			expectedRemoved: []string{"a"},
+		},
+		{
+			description:     "added and removed during the period",
+			changes:         []string{"+a", "-a"},
+			expectedAdded:   []string{},
+			expectedRemoved: []string{},
+		},
+		{
+			description:     "removed and added again",
+			changes:         []string{"+a", "-a", "+a"},
+			since:           1,
+			expectedAdded:   []string{"a"},
+			expectedRemoved: []string{},
+		},
+		{
+			description:     "changes before the period",
+			changes:         []string{"+a", "+b", "+c"},
+			since:           2,
+			expectedAdded:   []string{"c"},
+			expectedRemoved: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			s, start := newTestChangelog(t, tc.changes...)
+			since := start.Add(time.Duration(tc.since) * time.Second)
+
+			kc, ok := s.Changes(since)
+			require.True(t, ok)
+			assert.Equal(since, kc.Since)
+			assert.Equal(start.Add(time.Duration(len(tc.changes))*time.Second), kc.Now)
+			assert.Equal(tc.expectedAdded, kc.Added)
+			assert.Equal(tc.expectedRemoved, kc.Removed)
+		})
+	}
+}
+
+func TestChangelogKeyStoreTruncated(t *testing.T) {
+	changes := make([]string, MaxKeyChanges+1)
+	for i := range changes {
+		changes[i] = "+" + strconv.Itoa(i)
+	}
+
+	s, start := newTestChangelog(t, changes...)
+	require.Len(t, s.changes, MaxKeyChanges)
+
+	_, ok := s.Changes(start)
+	assert.False(t, ok)
+
+	kc, ok := s.Changes(start.Add(time.Second))
+	require.True(t, ok)
+	assert.Len(t, kc.Added, MaxKeyChanges)
+}
+
+func TestKeyChangesHandler(t *testing.T) {
+	changes := make([]string, MaxKeyChanges+1)
+	for i := range changes {
+		changes[i] = "+" + strconv.Itoa(i)
+	}
+
+	s, start := newTestChangelog(t, changes...)
+	last := start.Add(time.Duration(MaxKeyChanges) * time.Second)
+
+	tests := []struct {
+		description   string
+		keyStore      KeyStore
+		since         string
+		expectedCode  int
+		expectedAdded []string
+	}{
+		{
+			description:   "changes",
+			keyStore:      s,
+			since:         last.Format(time.RFC3339Nano),
+			expectedCode:  http.StatusOK,
+			expectedAdded: []string{strconv.Itoa(MaxKeyChanges)},
+		},
+		{
+			description:  "no longer retained",
+			keyStore:     s,
+			since:        start.Format(time.RFC3339Nano),
+			expectedCode: http.StatusGone,
+		},
+		{
+			description:  "missing since",
+			keyStore:     s,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "invalid since",
+			keyStore:     s,
+			since:        "yesterday",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			description:  "no changelog",
+			keyStore:     NewInMemoryKeyStore(),
+			since:        last.Format(time.RFC3339Nano),
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			kch := NewKeyChangesHandler(zap.NewNop(), tc.keyStore)
+			response := serve(kch, http.MethodGet, "/keys/changes?"+SinceParameter+"="+url.QueryEscape(tc.since), nil)
+			require.Equal(t, tc.expectedCode, response.Code, response.Body.String())
+
+			if tc.expectedCode == http.StatusOK {
+				var kc KeyChanges
+				require.NoError(t, json.Unmarshal(response.Body.Bytes(), &kc))
+				assert.Equal(tc.expectedAdded, kc.Added)
+				assert.Empty(kc.Removed)
+				assert.True(kc.Now.Equal(start.Add(time.Duration(MaxKeyChanges+1) * time.Second)))
+			}
+		})
+	}
+}
+
+func TestServerKeyChanges(t *testing.T) {
+	h := newTestServer(t)
+	since := time.Now().Add(-time.Hour).Format(time.RFC3339Nano)
+	response := serve(h, http.MethodGet, "/keys/changes?"+SinceParameter+"="+url.QueryEscape(since), nil)
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	var kc KeyChanges
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &kc))
+	assert.Len(t, kc.Added, 1)
+	assert.Equal(t, "no-cache", response.Header().Get("Cache-Control"))
+}
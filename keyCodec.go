@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+)
+
+// KeyCodec converts Keys to and from the form persisted by a KeyStore.
+// A KeyCodec must preserve private key material along with all Key metadata.
+type KeyCodec interface {
+	// Encode produces the persistent form of the given Key.
+	Encode(Key) ([]byte, error)
+
+	// Decode reconstitutes a Key from its persistent form.
+	Decode([]byte) (Key, error)
+}
+
+// JSONKeyCodec is a KeyCodec that persists a Key as a single JWK, including
+// any private material. The Key's Alg is stored in the alg member, while Created
+// and Expires are stored in the CreatedParameter and ExpiresParameter members.
+type JSONKeyCodec struct{}
+
+// Encode marshals the given Key as a JWK.
+func (JSONKeyCodec) Encode(k Key) (data []byte, err error) {
+	var exported jwk.Key
+	exported, err = k.ExportJWK()
+	if err == nil && k.Alg != nil {
+		err = exported.Set(jwk.AlgorithmKey, k.Alg)
+	}
+
+	if err == nil && len(k.KID) > 0 {
+		err = exported.Set(jwk.KeyIDKey, k.KID)
+	}
+
+	if err == nil {
+		data, err = json.Marshal(exported)
+	}
+
+	return
+}
+
+// Decode parses a JWK produced by Encode.
+func (JSONKeyCodec) Decode(data []byte) (k Key, err error) {
+	k.Key, err = jwk.ParseKey(data)
+	if err == nil {
+		k.KID, _ = k.Key.KeyID()
+		k.Alg, _ = k.Key.Algorithm()
+		k.Created, err = decodeTime(k.Key, CreatedParameter)
+	}
+
+	if err == nil {
+		k.Expires, err = decodeTime(k.Key, ExpiresParameter)
+	}
+
+	return
+}
+
+// decodeTime extracts the named epoch seconds member from a JWK, then removes
+// that member. An absent member yields the zero time.
+func decodeTime(key jwk.Key, name string) (t time.Time, err error) {
+	if !key.Has(name) {
+		return
+	}
+
+	var seconds float64
+	err = key.Get(name, &seconds)
+	if err == nil {
+		t = time.Unix(int64(seconds), 0)
+		err = key.Remove(name)
+	}
+
+	return
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONKeyCodec(t *testing.T) {
+	created := time.Unix(1700000000, 0)
+	tests := []struct {
+		description string
+		keyType     string
+		public      bool
+		created     time.Time
+		expires     time.Time
+	}{
+		{
+			description: "EC private key",
+			keyType:     "EC",
+			created:     created,
+			expires:     created.Add(time.Hour),
+		},
+		{
+			description: "EC public key",
+			keyType:     "EC",
+			public:      true,
+			created:     created,
+		},
+		{
+			description: "RSA private key",
+			keyType:     "RSA",
+			created:     created,
+			expires:     created.Add(time.Hour),
+		},
+		{
+			description: "OKP private key without times",
+			keyType:     "OKP",
+		},
+		{
+			description: "oct key",
+			keyType:     "oct",
+			created:     created,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			k := newTestKey(t, "--key-type="+tc.keyType, "--key-size=2048")
+			k.Created, k.Expires = tc.created, tc.expires
+			if tc.public {
+				var err error
+				k, err = k.PublicKey()
+				require.NoError(t, err)
+			}
+
+			var codec JSONKeyCodec
+			data, err := codec.Encode(k)
+			require.NoError(t, err)
+
+			decoded, err := codec.Decode(data)
+			require.NoError(t, err)
+			assert.Equal(k.KID, decoded.KID)
+			assert.Equal(k.Alg.String(), decoded.Alg.String())
+			assert.True(tc.created.Equal(decoded.Created))
+			assert.True(tc.expires.Equal(decoded.Expires))
+			assert.False(decoded.Key.Has(CreatedParameter))
+			assert.False(decoded.Key.Has(ExpiresParameter))
+
+			private, err := jwk.IsPrivateKey(decoded.Key)
+			if tc.keyType != "oct" {
+				require.NoError(t, err)
+				assert.Equal(!tc.public, private)
+			}
+
+			assert.True(jwk.Equal(k.Key, decoded.Key))
+		})
+	}
+}
+
+func TestJSONKeyCodecDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		description string
+		data        string
+	}{
+		{description: "not JSON", data: "not a key"},
+		{description: "not a JWK", data: `{"kty":"nope"}`},
+		{description: "invalid created", data: `{"kty":"oct","k":"c2VjcmV0","utu_created":"yesterday"}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := JSONKeyCodec{}.Decode([]byte(tc.data))
+			assert.Error(t, err)
+		})
+	}
+}
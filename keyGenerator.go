@@ -5,66 +5,196 @@ package main
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/rsa"
+	"errors"
 	"fmt"
 	"io"
+	"regexp"
 	"time"
 
 	"github.com/lestrrat-go/jwx/v3/jwa"
 	"github.com/lestrrat-go/jwx/v3/jwk"
 	"go.uber.org/fx"
+	"go.uber.org/zap"
 )
 
-// KeyGenerator generates raw keys, e.g. EC and RSA.
+var (
+	// ErrWeakSecret indicates that a symmetric secret is shorter than its algorithm requires.
+	ErrWeakSecret = errors.New("the symmetric secret is too short for its algorithm")
+
+	// ErrInvalidKID indicates that a generated kid doesn't match the configured kid format.
+	ErrInvalidKID = errors.New("the generated kid doesn't match the kid format")
+
+	// ErrKeyImport indicates that a generated raw key could not be imported as a JWK.
+	ErrKeyImport = errors.New("unable to import the generated key as a JWK")
+
+	// minSecretBytes is the minimum secret length, in bytes, for each HMAC algorithm.
+	// RFC 7518 section 3.2 requires a key at least as long as the hash output.
+	minSecretBytes = map[string]int{
+		jwa.HS256().String(): 32,
+		jwa.HS384().String(): 48,
+		jwa.HS512().String(): 64,
+	}
+)
+
+// validateSecret checks that a symmetric secret of the given length, in bytes,
+// meets the minimum for the given HMAC algorithm.
+func validateSecret(alg jwa.KeyAlgorithm, length int) error {
+	minimum, ok := minSecretBytes[alg.String()]
+	switch {
+	case !ok:
+		return fmt.Errorf("%s is not an HMAC algorithm", alg)
+
+	case length < minimum:
+		return fmt.Errorf("%w: %s requires at least %d bytes, but the secret has %d bytes", ErrWeakSecret, alg, minimum, length)
+
+	default:
+		return nil
+	}
+}
+
+// KeyGenerator generates raw keys, e.g. EC, RSA, and Ed25519.
 //
 // A KeyGenerator sets an expires on all keys. The expires value
-// for keys is <key rotation> + <token expires> + <1 minute grace>.
+// for keys is <key rotation> + <token lifetime> + <1 minute grace>, where
+// the token lifetime is the longest that any request can obtain.
 // This allows for tokens signed by rotated keys to be validated
 // until they expire.
+//
+// A KeyGenerator may have an ordered chain of fallback generators. If generating
+// a key fails, each fallback is tried in turn until one succeeds.
 type KeyGenerator struct {
+	logger      *zap.Logger
 	random      io.Reader
 	now         func() time.Time
 	expires     time.Duration
 	idGenerator *IDGenerator
+	keyType     string
 	alg         jwa.KeyAlgorithm
 	ec          bool
+	oct         bool
+	okp         bool
 	bits        int
 	curve       elliptic.Curve
+	x5c         bool
+	kidFormat   *regexp.Regexp
+	fallbacks   []*KeyGenerator
+
+	// use and keyOps are the use and key_ops members of generated keys.
+	use    jwk.KeyUsageType
+	keyOps jwk.KeyOperationList
 }
 
-func NewKeyGenerator(idGenerator *IDGenerator, cli CLI) (kg *KeyGenerator, err error) {
+func NewKeyGenerator(l *zap.Logger, idGenerator *IDGenerator, random *RandomSource, cli CLI) (kg *KeyGenerator, err error) {
+	kg, err = newKeyGenerator(l, idGenerator, random, cli, cli.KeyType)
+	for i := 0; err == nil && i < len(cli.KeyFallback); i++ {
+		var fallback *KeyGenerator
+		if fallback, err = newKeyGenerator(l, idGenerator, random, cli, cli.KeyFallback[i]); err == nil {
+			kg.fallbacks = append(kg.fallbacks, fallback)
+		}
+	}
+
+	if err == nil {
+		l.Info("key generator",
+			zap.String("keyType", kg.keyType),
+			zap.String("alg", kg.alg.String()),
+			zap.Strings("fallbacks", cli.KeyFallback),
+			zap.Bool("selfSignedX5C", kg.x5c),
+			zap.Stringer("kidFormat", kg.kidFormat),
+		)
+	}
+
+	return
+}
+
+// newKeyGenerator creates a KeyGenerator for the given key type, using the remaining
+// key parameters from the command line.
+func newKeyGenerator(l *zap.Logger, idGenerator *IDGenerator, random io.Reader, cli CLI, keyType string) (kg *KeyGenerator, err error) {
 	kg = &KeyGenerator{
-		random:      rand.Reader,
+		logger:      l,
+		keyType:     keyType,
+		random:      random,
 		now:         time.Now,
-		expires:     cli.KeyRotate + cli.Expires + time.Minute,
+		expires:     cli.KeyRotate + cli.KeyActivationDelay + maxLifetime(cli.Expires, cli.MaxRequestExpires, cli.ScopeExpires) + time.Minute,
 		idGenerator: idGenerator,
+		x5c:         cli.SelfSignedX5C,
+		use:         jwk.ForSignature,
+		keyOps:      jwk.KeyOperationList{jwk.KeyOpSign, jwk.KeyOpVerify},
+	}
+
+	if len(cli.KIDFormat) > 0 {
+		// the format must match the entire kid, not just part of it
+		kg.kidFormat, err = regexp.Compile(`^(?:` + cli.KIDFormat + `)$`)
+		if err != nil {
+			err = fmt.Errorf("invalid kid format: %w", err)
+			return
+		}
 	}
 
 	switch {
-	case cli.KeyType == "EC" && cli.KeyCurve == "P-256":
+	case keyType == "EC" && cli.KeyCurve == "P-256":
 		kg.ec = true
 		kg.curve = elliptic.P256()
 		kg.alg = jwa.ES256()
 
-	case cli.KeyType == "EC" && cli.KeyCurve == "P-384":
+	case keyType == "EC" && cli.KeyCurve == "P-384":
 		kg.ec = true
 		kg.curve = elliptic.P384()
 		kg.alg = jwa.ES384()
 
-	case cli.KeyType == "EC" && cli.KeyCurve == "P-521":
+	case keyType == "EC" && cli.KeyCurve == "P-521":
 		kg.ec = true
 		kg.curve = elliptic.P521()
 		kg.alg = jwa.ES512()
 
-	case cli.KeyType == "RSA" && cli.KeySize > 0:
+	case keyType == "OKP":
+		// Ed25519 is the only supported OKP curve, so neither the size nor curve apply
+		kg.okp = true
+		kg.alg = jwa.EdDSA()
+
+	case keyType == "RSA" && cli.KeySize > 0:
 		kg.ec = false
 		kg.bits = cli.KeySize
 		kg.alg = jwa.RS256()
 
+	case keyType == "oct":
+		kg.oct = true
+		kg.bits = cli.KeySize
+		kg.alg, _ = jwa.LookupSignatureAlgorithm(cli.HMACAlg)
+		err = validateSecret(kg.alg, kg.bits/8)
+
 	default:
-		err = fmt.Errorf("unsupported key parameters: type=%s, size=%d, curve=%s", cli.KeyType, cli.KeySize, cli.KeyCurve)
+		err = fmt.Errorf("unsupported key parameters: type=%s, size=%d, curve=%s", keyType, cli.KeySize, cli.KeyCurve)
+	}
+
+	return
+}
+
+// newEncryptionKeyGenerator creates a KeyGenerator for encryption keys of the given
+// key type, which clients use to encrypt content for this server. EC keys are used
+// with ECDH-ES+A256KW, and RSA keys with RSA-OAEP-256.
+func newEncryptionKeyGenerator(l *zap.Logger, idGenerator *IDGenerator, random io.Reader, cli CLI, keyType string) (kg *KeyGenerator, err error) {
+	kg, err = newKeyGenerator(l, idGenerator, random, cli, keyType)
+	if err != nil {
+		return
+	}
+
+	// self-signed certificates only attest to signing keys
+	kg.x5c = false
+	kg.use = jwk.ForEncryption
+	switch keyType {
+	case "EC":
+		kg.alg = jwa.ECDH_ES_A256KW()
+		kg.keyOps = jwk.KeyOperationList{jwk.KeyOpDeriveKey}
+
+	case "RSA":
+		kg.alg = jwa.RSA_OAEP_256()
+		kg.keyOps = jwk.KeyOperationList{jwk.KeyOpWrapKey, jwk.KeyOpUnwrapKey}
+
+	default:
+		err = fmt.Errorf("unsupported encryption key type: %s", keyType)
 	}
 
 	return
@@ -72,11 +202,21 @@ func NewKeyGenerator(idGenerator *IDGenerator, cli CLI) (kg *KeyGenerator, err e
 
 // generateRaw generates the raw key appropriate for this instance's configuration.
 func (kg *KeyGenerator) generateRaw() (raw any, err error) {
-	// TODO: support other kinds of keys
 	switch {
 	case kg.ec:
 		raw, err = ecdsa.GenerateKey(kg.curve, kg.random)
 
+	case kg.okp:
+		_, raw, err = ed25519.GenerateKey(kg.random)
+
+	case kg.oct:
+		secret := make([]byte, kg.bits/8)
+		if _, err = io.ReadFull(kg.random, secret); err == nil {
+			err = validateSecret(kg.alg, len(secret))
+		}
+
+		raw = secret
+
 	default:
 		raw, err = rsa.GenerateKey(kg.random, kg.bits)
 	}
@@ -85,29 +225,113 @@ func (kg *KeyGenerator) generateRaw() (raw any, err error) {
 }
 
 // Generate creates a new, random key appropriate for signing and verification.
+// If this generator fails, each fallback generator is tried in order.
 func (kg *KeyGenerator) Generate() (k Key, err error) {
+	k, err = kg.generate()
+	for i := 0; err != nil && i < len(kg.fallbacks); i++ {
+		fallback := kg.fallbacks[i]
+		kg.logger.Warn("unable to generate key, trying fallback",
+			zap.String("fallback", fallback.keyType),
+			zap.Error(err),
+		)
+
+		if k, err = fallback.generate(); err == nil {
+			kg.logger.Info("generated key with fallback",
+				zap.String("keyType", fallback.keyType),
+				zap.String("alg", fallback.alg.String()),
+			)
+		}
+	}
+
+	return
+}
+
+// parameters describes this generator's key parameters, for error messages.
+func (kg *KeyGenerator) parameters() string {
+	switch {
+	case kg.ec:
+		return fmt.Sprintf("type=%s, alg=%s, curve=%s", kg.keyType, kg.alg, kg.curve.Params().Name)
+
+	case kg.okp:
+		return fmt.Sprintf("type=%s, alg=%s, curve=%s", kg.keyType, kg.alg, jwa.Ed25519())
+
+	default:
+		return fmt.Sprintf("type=%s, alg=%s, size=%d", kg.keyType, kg.alg, kg.bits)
+	}
+}
+
+// generate creates a key using only this generator's configuration. If any error
+// occurs, the returned Key is empty, so that no partially generated key is ever used.
+func (kg *KeyGenerator) generate() (k Key, err error) {
 	k = Key{
 		KID: kg.idGenerator.Generate(16),
 		Alg: kg.alg,
 	}
 
+	if kg.kidFormat != nil && !kg.kidFormat.MatchString(k.KID) {
+		err = fmt.Errorf("%w: %s", ErrInvalidKID, k.KID)
+	}
+
 	var raw any
-	raw, err = kg.generateRaw()
 	if err == nil {
-		k.Key, err = jwk.Import(raw)
+		if raw, err = kg.generateRaw(); err != nil {
+			err = fmt.Errorf("unable to generate key (%s): %w", kg.parameters(), err)
+		}
+	}
+
+	if err == nil {
+		if k.Key, err = jwk.Import(raw); err != nil {
+			err = fmt.Errorf("%w (%s, raw key %T): %w", ErrKeyImport, kg.parameters(), raw, err)
+		}
 	}
 
 	if err == nil {
 		k.Created = kg.now().UTC()
 		k.Expires = k.Created.Add(kg.expires)
-		k.Key.Set(jwk.KeyUsageKey, jwk.ForSignature)
-		k.Key.Set(jwk.KeyOpsKey, jwk.KeyOperationList{jwk.KeyOpSign, jwk.KeyOpVerify})
-		k.Key.Set(jwk.KeyIDKey, k.KID)
+		err = k.Key.Set(jwk.KeyUsageKey, kg.use)
+	}
+
+	if err == nil {
+		err = k.Key.Set(jwk.KeyOpsKey, kg.keyOps)
+	}
+
+	if err == nil {
+		err = k.Key.Set(jwk.KeyIDKey, k.KID)
+	}
+
+	if err == nil && kg.x5c {
+		err = setSelfSignedCertificate(kg.random, k, raw)
+	}
+
+	if err != nil {
+		k = Key{}
 	}
 
 	return
 }
 
+// SetKID assigns a new kid to a key produced by this generator. When this generator
+// embeds self-signed certificates, the certificate is regenerated, since its subject
+// names the kid.
+func (kg *KeyGenerator) SetKID(k *Key, kid string) (err error) {
+	k.KID = kid
+	err = k.Key.Set(jwk.KeyIDKey, kid)
+	if err == nil && kg.x5c {
+		var raw any
+		if err = jwk.Export(k.Key, &raw); err == nil {
+			err = setSelfSignedCertificate(kg.random, *k, raw)
+		}
+	}
+
+	return
+}
+
+// Alg returns the algorithm for keys produced by this generator, which is a signing
+// algorithm unless this generator produces encryption keys.
+func (kg *KeyGenerator) Alg() jwa.KeyAlgorithm {
+	return kg.alg
+}
+
 func ProvideKeyGenerator() fx.Option {
 	return fx.Provide(
 		NewKeyGenerator,
@@ -0,0 +1,435 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestKeyGeneratorOct(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expectedAlg jwa.SignatureAlgorithm
+		expectedLen int
+		expectedErr error
+	}{
+		{
+			description: "HS256",
+			args:        []string{"--key-size=256"},
+			expectedAlg: jwa.HS256(),
+			expectedLen: 32,
+		},
+		{
+			description: "HS256 with a longer secret",
+			args:        []string{"--key-size=2048"},
+			expectedAlg: jwa.HS256(),
+			expectedLen: 256,
+		},
+		{
+			description: "HS384",
+			args:        []string{"--key-size=384", "--hmac-alg=HS384"},
+			expectedAlg: jwa.HS384(),
+			expectedLen: 48,
+		},
+		{
+			description: "HS512",
+			args:        []string{"--key-size=512", "--hmac-alg=HS512"},
+			expectedAlg: jwa.HS512(),
+			expectedLen: 64,
+		},
+		{
+			description: "HS256 too short",
+			args:        []string{"--key-size=128"},
+			expectedErr: ErrWeakSecret,
+		},
+		{
+			description: "HS512 too short",
+			args:        []string{"--key-size=384", "--hmac-alg=HS512"},
+			expectedErr: ErrWeakSecret,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			kg, err := NewKeyGenerator(zap.NewNop(), NewIDGenerator(NewRandomSource()), NewRandomSource(), newTestCLI(t, append([]string{"--key-type=oct"}, tc.args...)...))
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			k, err := kg.Generate()
+			require.NoError(t, err)
+			assert.Equal(tc.expectedAlg.String(), k.Alg.String())
+			assert.Equal(jwa.OctetSeq(), k.Key.KeyType())
+
+			var secret []byte
+			require.NoError(t, k.Key.Get("k", &secret))
+			assert.Len(secret, tc.expectedLen)
+
+			_, err = k.PublicJWK()
+			assert.ErrorIs(err, ErrNoPublicKey)
+		})
+	}
+}
+
+func TestKeyGeneratorOKP(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+	}{
+		{
+			description: "defaults",
+		},
+		{
+			description: "key size ignored",
+			args:        []string{"--key-size=4096"},
+		},
+		{
+			description: "key curve ignored",
+			args:        []string{"--key-curve=P-521"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			k := newTestKey(t, append([]string{"--key-type=OKP"}, tc.args...)...)
+			assert.Equal(jwa.EdDSA().String(), k.Alg.String())
+			assert.Equal(jwa.OKP(), k.Key.KeyType())
+
+			var raw ed25519.PrivateKey
+			require.NoError(t, jwk.Export(k.Key, &raw))
+			assert.Len(raw, ed25519.PrivateKeySize)
+
+			public, err := k.PublicJWK()
+			require.NoError(t, err)
+
+			crv, ok := public.(jwk.OKPPublicKey).Crv()
+			require.True(t, ok)
+			assert.Equal(jwa.Ed25519(), crv)
+		})
+	}
+}
+
+// failingReader is a random source that always fails.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("no randomness")
+}
+
+func TestKeyGeneratorFallback(t *testing.T) {
+	tests := []struct {
+		description string
+		fallbacks   []string
+
+		// failing are the generators, by index with the primary first, that can't generate keys.
+		failing         []int
+		expectedKeyType jwa.KeyType
+		expectErr       bool
+	}{
+		{
+			description:     "primary succeeds",
+			fallbacks:       []string{"EC"},
+			expectedKeyType: jwa.OctetSeq(),
+		},
+		{
+			description:     "first fallback",
+			fallbacks:       []string{"EC", "RSA"},
+			failing:         []int{0},
+			expectedKeyType: jwa.EC(),
+		},
+		{
+			description:     "second fallback",
+			fallbacks:       []string{"oct", "OKP"},
+			failing:         []int{0, 1},
+			expectedKeyType: jwa.OKP(),
+		},
+		{
+			description: "every generator fails",
+			fallbacks:   []string{"oct"},
+			failing:     []int{0, 1},
+			expectErr:   true,
+		},
+		{
+			description: "no fallbacks",
+			failing:     []int{0},
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			args := []string{"--key-type=oct", "--key-size=256"}
+			for _, fallback := range tc.fallbacks {
+				args = append(args, "--key-fallback="+fallback)
+			}
+
+			kg, err := NewKeyGenerator(zap.NewNop(), NewIDGenerator(NewRandomSource()), NewRandomSource(), newTestCLI(t, args...))
+			require.NoError(t, err)
+			require.Len(t, kg.fallbacks, len(tc.fallbacks))
+
+			generators := append([]*KeyGenerator{kg}, kg.fallbacks...)
+			for _, i := range tc.failing {
+				generators[i].random = failingReader{}
+			}
+
+			k, err := kg.Generate()
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(tc.expectedKeyType, k.Key.KeyType())
+		})
+	}
+}
+
+func TestKeyGeneratorExpires(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expected    time.Duration
+	}{
+		{
+			description: "defaults",
+			expected:    24*time.Hour + time.Hour + time.Minute,
+		},
+		{
+			description: "default lifetime longer than expires_in",
+			args:        []string{"--expires=2h"},
+			expected:    24*time.Hour + 2*time.Hour + time.Minute,
+		},
+		{
+			description: "longer expires_in",
+			args:        []string{"--max-expires=6h"},
+			expected:    24*time.Hour + 6*time.Hour + time.Minute,
+		},
+		{
+			description: "longer scope lifetime",
+			args:        []string{"--scope-expires=offline=12h"},
+			expected:    24*time.Hour + 12*time.Hour + time.Minute,
+		},
+		{
+			description: "activation delay",
+			args:        []string{"--key-activation-delay=2h"},
+			expected:    24*time.Hour + 2*time.Hour + time.Hour + time.Minute,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			k := newTestKey(t, tc.args...)
+			assert.Equal(t, tc.expected, k.Expires.Sub(k.Created))
+		})
+	}
+}
+
+func TestKeyGeneratorKIDFormat(t *testing.T) {
+	tests := []struct {
+		description    string
+		args           []string
+		expectedNewErr bool
+		expectedErr    error
+	}{
+		{
+			description: "no format",
+		},
+		{
+			description: "matching format",
+			args:        []string{"--kid-format=[A-Za-z0-9_-]{22}"},
+		},
+		{
+			description: "partial match",
+			args:        []string{"--kid-format=[A-Za-z0-9_-]{4}"},
+			expectedErr: ErrInvalidKID,
+		},
+		{
+			description: "alternation matches entirely",
+			args:        []string{"--kid-format=x|[A-Za-z0-9_-]{22}"},
+		},
+		{
+			description: "nonmatching format",
+			args:        []string{"--kid-format=[0-9]+"},
+			expectedErr: ErrInvalidKID,
+		},
+		{
+			description:    "invalid format",
+			args:           []string{"--kid-format=("},
+			expectedNewErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			kg, err := NewKeyGenerator(zap.NewNop(), NewIDGenerator(NewRandomSource()), NewRandomSource(), newTestCLI(t, tc.args...))
+			if tc.expectedNewErr {
+				assert.Error(err)
+				return
+			}
+
+			require.NoError(t, err)
+			k, err := kg.Generate()
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(k.KID, 22)
+		})
+	}
+}
+
+func TestEncryptionKeyGenerator(t *testing.T) {
+	tests := []struct {
+		description    string
+		keyType        string
+		args           []string
+		expectedAlg    string
+		expectedKeyOps jwk.KeyOperationList
+	}{
+		{
+			description:    "EC",
+			keyType:        "EC",
+			expectedAlg:    jwa.ECDH_ES_A256KW().String(),
+			expectedKeyOps: jwk.KeyOperationList{jwk.KeyOpDeriveKey},
+		},
+		{
+			description:    "RSA",
+			keyType:        "RSA",
+			args:           []string{"--key-type=RSA", "--key-size=2048"},
+			expectedAlg:    jwa.RSA_OAEP_256().String(),
+			expectedKeyOps: jwk.KeyOperationList{jwk.KeyOpWrapKey, jwk.KeyOpUnwrapKey},
+		},
+		{
+			description:    "no self-signed certificate",
+			keyType:        "EC",
+			args:           []string{"--self-signed-x5c"},
+			expectedAlg:    jwa.ECDH_ES_A256KW().String(),
+			expectedKeyOps: jwk.KeyOperationList{jwk.KeyOpDeriveKey},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			kg, err := newEncryptionKeyGenerator(zap.NewNop(), NewIDGenerator(NewRandomSource()), NewRandomSource(), newTestCLI(t, tc.args...), tc.keyType)
+			require.NoError(t, err)
+			assert.Equal(tc.expectedAlg, kg.Alg().String())
+
+			k, err := kg.Generate()
+			require.NoError(t, err)
+			assert.True(k.ForEncryption())
+
+			keyOps, _ := k.Key.KeyOps()
+			assert.Equal(tc.expectedKeyOps, keyOps)
+
+			chain, _ := k.Key.X509CertChain()
+			assert.Nil(chain)
+		})
+	}
+}
+
+func TestKeyGeneratorErrors(t *testing.T) {
+	errRandom := errors.New("expected")
+	tests := []struct {
+		description string
+		args        []string
+
+		// random is the source of randomness for key material.
+		random             io.Reader
+		expectedErr        error
+		expectedParameters string
+	}{
+		{
+			description:        "oct without randomness",
+			args:               []string{"--key-type=oct", "--key-size=256"},
+			random:             iotest.ErrReader(errRandom),
+			expectedErr:        errRandom,
+			expectedParameters: "type=oct, alg=HS256, size=256",
+		},
+		{
+			description: "invalid kid",
+			args:        []string{"--kid-format=x"},
+			random:      NewRandomSource(),
+			expectedErr: ErrInvalidKID,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			cli := newTestCLI(t, tc.args...)
+			kg, err := newKeyGenerator(zap.NewNop(), NewIDGenerator(NewRandomSource()), tc.random, cli, cli.KeyType)
+			require.NoError(t, err)
+
+			k, err := kg.generate()
+			assert.ErrorIs(err, tc.expectedErr)
+			assert.Contains(err.Error(), tc.expectedParameters)
+
+			// no partially generated key is ever returned
+			assert.Equal(Key{}, k)
+		})
+	}
+}
+
+func TestKeyGeneratorParameters(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expected    string
+	}{
+		{
+			description: "EC",
+			expected:    "type=EC, alg=ES256, curve=P-256",
+		},
+		{
+			description: "EC P-521",
+			args:        []string{"--key-curve=P-521"},
+			expected:    "type=EC, alg=ES512, curve=P-521",
+		},
+		{
+			description: "RSA",
+			args:        []string{"--key-type=RSA", "--key-size=3072"},
+			expected:    "type=RSA, alg=RS256, size=3072",
+		},
+		{
+			description: "oct",
+			args:        []string{"--key-type=oct", "--key-size=256"},
+			expected:    "type=oct, alg=HS256, size=256",
+		},
+		{
+			description: "OKP",
+			args:        []string{"--key-type=OKP"},
+			expected:    "type=OKP, alg=EdDSA, curve=Ed25519",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			cli := newTestCLI(t, tc.args...)
+			kg, err := newKeyGenerator(zap.NewNop(), NewIDGenerator(NewRandomSource()), NewRandomSource(), cli, cli.KeyType)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, kg.parameters())
+		})
+	}
+}
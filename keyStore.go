@@ -4,16 +4,27 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+const (
+	// CurrentOnlyParameter is the /keys request parameter that, when true, restricts
+	// the key set to the current signing keys.
+	CurrentOnlyParameter = "current_only"
+)
+
 var (
 	ErrNoSuchKey = errors.New("no key exists with that KID")
 )
@@ -37,11 +48,54 @@ type KeyStore interface {
 	Delete(kid string) error
 }
 
+// CurrentKeyStore is an optional interface for persistent KeyStores that can
+// also hold the current signing key, including its private material. This allows
+// a restarted process to reuse the current key rather than orphaning it.
+type CurrentKeyStore interface {
+	// StoreCurrent persists the given key, including private material, as the current key.
+	StoreCurrent(Key) error
+
+	// LoadCurrent retrieves the last key passed to StoreCurrent. If no current key
+	// has been stored, this method returns ErrNoCurrentKey.
+	LoadCurrent() (Key, error)
+}
+
+// RotationLocker is an optional interface for KeyStores shared by several replicas.
+// Replicas use the rotation lock so that only one of them rotates keys per interval.
+type RotationLocker interface {
+	// TryLockRotation attempts to acquire the rotation lock for the given duration.
+	// If another holder has the lock, this method returns false.
+	TryLockRotation(ttl time.Duration) (bool, error)
+}
+
+// keyStoreAs finds the first KeyStore in a chain of decorators that implements an
+// optional interface, such as CurrentKeyStore. Decorators expose the KeyStore they
+// decorate through an Unwrap method.
+func keyStoreAs[T any](ks KeyStore) (t T, ok bool) {
+	for ks != nil {
+		if t, ok = ks.(T); ok {
+			return
+		}
+
+		u, isDecorator := ks.(interface{ Unwrap() KeyStore })
+		if !isDecorator {
+			break
+		}
+
+		ks = u.Unwrap()
+	}
+
+	return
+}
+
 // InMemoryKeyStore is a KeyStore that uses a simple map guarded
 // by a read/write mutex. Instances must be created with NewInMemoryKeyStore.
 type InMemoryKeyStore struct {
 	lock sync.RWMutex
 	keys map[string]Key
+
+	// rotationLockExpires is when the rotation lock is next available.
+	rotationLockExpires time.Time
 }
 
 func NewInMemoryKeyStore() *InMemoryKeyStore {
@@ -82,6 +136,20 @@ func (s *InMemoryKeyStore) LoadAll() (ks []Key, err error) {
 	return
 }
 
+// TryLockRotation implements RotationLocker. This is only useful when several
+// Rotators share the same InMemoryKeyStore.
+func (s *InMemoryKeyStore) TryLockRotation(ttl time.Duration) (acquired bool, err error) {
+	now := time.Now()
+	s.lock.Lock()
+	if !now.Before(s.rotationLockExpires) {
+		s.rotationLockExpires = now.Add(ttl)
+		acquired = true
+	}
+
+	s.lock.Unlock()
+	return
+}
+
 func (s *InMemoryKeyStore) Delete(kid string) (err error) {
 	s.lock.Lock()
 
@@ -110,23 +178,76 @@ func NewKeyHandler(logger *zap.Logger, keyAccessor *KeyAccessor, keyStore KeySto
 	}
 }
 
-func (kh *KeyHandler) writeKey(response http.ResponseWriter, key Key) {
-	response.Header().Set("Content-Type", "application/jwk+json")
-	key.WriteTo(response)
+// writeBody writes a precomputed response body along with its Content-Type and Content-Length.
+func writeBody(response http.ResponseWriter, contentType string, body []byte) {
+	response.Header().Set("Content-Type", contentType)
+	response.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	response.Write(body)
+}
+
+func (kh *KeyHandler) writeKey(response http.ResponseWriter, key Key, format string) {
+	switch format {
+	case "":
+		// the default JWK format
+
+	case KeyFormatSSH:
+		kh.writeSSHKey(response, key)
+		return
+
+	default:
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte("unsupported key format: " + format))
+		return
+	}
+
+	var body bytes.Buffer
+	if _, err := key.WriteTo(&body); err == nil {
+		writeBody(response, "application/jwk+json", body.Bytes())
+	} else if errors.Is(err, ErrNoPublicKey) {
+		response.WriteHeader(http.StatusNotFound)
+	} else {
+		kh.logger.Error("unable to render key", KeyField("key", key), zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// writeSSHKey renders a key as an OpenSSH authorized_keys line. Key types that SSH
+// cannot represent result in a 400.
+func (kh *KeyHandler) writeSSHKey(response http.ResponseWriter, key Key) {
+	line, err := MarshalAuthorizedKey(key)
+	switch {
+	case err == nil:
+		writeBody(response, "text/plain;charset=utf-8", line)
+
+	case errors.Is(err, ErrNoPublicKey):
+		response.WriteHeader(http.StatusNotFound)
+
+	case errors.Is(err, ErrNotSSHKey):
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(err.Error()))
+
+	default:
+		kh.logger.Error("unable to render ssh key", KeyField("key", key), zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+	}
 }
 
 // ServeHTTP serves up the JWK format of generated keys. If this handler receives a path variable
 // named "kid", that is used to lookup the key to render. Otherwise, this handler returns the current
-// verification key.
+// verification key. A format parameter of ssh renders the key as an OpenSSH authorized_keys
+// line instead.
 func (kh *KeyHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	format := request.URL.Query().Get(FormatParameter)
 	if kid := request.PathValue("kid"); len(kid) > 0 {
 		if key, err := kh.keyStore.Load(kid); err == nil {
-			kh.writeKey(response, key)
+			kh.writeKey(response, key, format)
 		} else {
 			response.WriteHeader(http.StatusNotFound)
 		}
 	} else if key, err := kh.keyAccessor.Load(); err == nil {
-		kh.writeKey(response, key)
+		kh.writeKey(response, key, format)
 	} else {
 		response.WriteHeader(http.StatusServiceUnavailable)
 	}
@@ -134,50 +255,116 @@ func (kh *KeyHandler) ServeHTTP(response http.ResponseWriter, request *http.Requ
 
 // KeysHandler serves up the set of all keys in a Keys.
 type KeysHandler struct {
-	logger   *zap.Logger
-	keyStore KeyStore
+	logger      *zap.Logger
+	keyAccessor *KeyAccessor
+	keyStore    KeyStore
 }
 
-func NewKeysHandler(l *zap.Logger, keyStore KeyStore) *KeysHandler {
+func NewKeysHandler(l *zap.Logger, keyAccessor *KeyAccessor, keyStore KeyStore) *KeysHandler {
 	return &KeysHandler{
-		logger:   l,
-		keyStore: keyStore,
+		logger:      l,
+		keyAccessor: keyAccessor,
+		keyStore:    keyStore,
 	}
 }
 
-func (kh *KeysHandler) fetchKeySet() (set jwk.Set, err error) {
+// fetchKeySet produces the published key set. When currentOnly is set, the set has only
+// the current signing keys, which is a single key unless the signing pool is larger.
+func (kh *KeysHandler) fetchKeySet(currentOnly bool) (set jwk.Set, err error) {
 	var keys []Key
-	keys, err = kh.keyStore.LoadAll()
+	if currentOnly {
+		keys, err = kh.keyAccessor.LoadAll()
+	} else {
+		keys, err = kh.keyStore.LoadAll()
+	}
+
 	if err == nil {
-		set, err = NewPublicSet(keys...)
+		set, err = NewPublicSet(kh.logger, keys...)
 	}
 
 	return
 }
 
-// ServeHTTP serves up the JWK key set in jwk-set format.
+// ServeHTTP serves up the JWK key set in jwk-set format. A current_only parameter of
+// true restricts the set to the current signing keys, for verifiers that don't want
+// the keys of previous rotations.
 func (kh *KeysHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
 	var data []byte
-	set, err := kh.fetchKeySet()
+	set, err := kh.fetchKeySet(request.URL.Query().Get(CurrentOnlyParameter) == "true")
 	if err == nil {
 		data, err = json.Marshal(set)
 	}
 
 	if err == nil {
-		response.Header().Set("Content-Type", "application/jwk-set+json")
-		response.Write(data)
+		writeBody(response, "application/jwk-set+json", data)
 	} else {
+		kh.logger.Error("unable to render key set", zap.Error(err))
 		response.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
+// KeyStoreIn defines the dependencies necessary to create the KeyStore.
+type KeyStoreIn struct {
+	fx.In
+
+	Logger     *zap.Logger
+	CLI        CLI
+	Registerer prometheus.Registerer
+}
+
+// NewKeyStore creates the KeyStore for this server, either in memory or in files as
+// selected on the command line. When a key cache TTL is configured,
+// the KeyStore is decorated with a CachingKeyStore. The outermost decorator is always
+// a ChangelogKeyStore, so that every change is recorded.
+func NewKeyStore(in KeyStoreIn) (ks KeyStore, err error) {
+	switch in.CLI.KeyStoreType {
+	case "memory":
+		ks = NewInMemoryKeyStore()
+
+	case "file":
+		ks = NewFileKeyStore(in.Logger, in.CLI.KeyStoreDir)
+
+	default:
+		err = fmt.Errorf("unsupported key store: %s", in.CLI.KeyStoreType)
+		return
+	}
+
+	if in.CLI.KeyDeleteGrace > 0 {
+		ks = NewTombstoneKeyStore(ks, in.CLI.KeyDeleteGrace)
+	}
+
+	if in.CLI.KeyMissCacheTTL > 0 {
+		ks = NewMissCachingKeyStore(ks, in.CLI.KeyMissCacheTTL, in.CLI.KeyMissCacheSize)
+	}
+
+	if in.CLI.KeyCacheTTL > 0 {
+		lookups := NewKeyLookupCounter()
+		if err = in.Registerer.Register(lookups); err == nil {
+			ks = NewCachingKeyStore(ks, in.CLI.KeyCacheTTL, lookups)
+		}
+	}
+
+	ks = NewChangelogKeyStore(ks)
+
+	if err == nil {
+		in.Logger.Info("key store",
+			zap.String("type", in.CLI.KeyStoreType),
+			zap.String("dir", in.CLI.KeyStoreDir),
+			zap.Duration("cacheTTL", in.CLI.KeyCacheTTL),
+			zap.Duration("deleteGrace", in.CLI.KeyDeleteGrace),
+			zap.Duration("missCacheTTL", in.CLI.KeyMissCacheTTL),
+			zap.Int("missCacheSize", in.CLI.KeyMissCacheSize),
+		)
+	}
+
+	return
+}
+
 func ProvideKeyStore() fx.Option {
 	return fx.Provide(
-		fx.Annotate(
-			NewInMemoryKeyStore,
-			fx.As(new(KeyStore)),
-		),
+		NewKeyStore,
 		NewKeyHandler,
 		NewKeysHandler,
+		NewKeyChangesHandler,
 	)
 }
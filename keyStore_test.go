@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyHandlersContentLength(t *testing.T) {
+	var ka *KeyAccessor
+	var s *http.Server
+	startTestApp(t, nil, &ka, &s)
+
+	current, err := ka.Load()
+	require.NoError(t, err)
+
+	tests := []struct {
+		description         string
+		target              string
+		expectedContentType string
+	}{
+		{
+			description:         "key set",
+			target:              "/keys",
+			expectedContentType: "application/jwk-set+json",
+		},
+		{
+			description:         "current key",
+			target:              "/key",
+			expectedContentType: "application/jwk+json",
+		},
+		{
+			description:         "key by kid",
+			target:              "/key/" + current.KID,
+			expectedContentType: "application/jwk+json",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			response := serve(s.Handler, http.MethodGet, tc.target, nil)
+			require.Equal(t, http.StatusOK, response.Code)
+			assert.Equal(tc.expectedContentType, response.Header().Get("Content-Type"))
+			assert.Equal(strconv.Itoa(response.Body.Len()), response.Header().Get("Content-Length"))
+			assert.Contains(response.Body.String(), current.KID)
+		})
+	}
+}
+
+func TestKeysHandlerCurrentOnly(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		target      string
+		expectedLen int
+
+		// expectedCurrent indicates that only the current signing keys are expected.
+		expectedCurrent bool
+	}{
+		{
+			description: "every key",
+			target:      "/keys",
+			expectedLen: 2,
+		},
+		{
+			description: "current_only false",
+			target:      "/keys?current_only=false",
+			expectedLen: 2,
+		},
+		{
+			description:     "current only",
+			target:          "/keys?current_only=true",
+			expectedLen:     1,
+			expectedCurrent: true,
+		},
+		{
+			description:     "current signing pool",
+			args:            []string{"--signing-pool-size=3"},
+			target:          "/keys?current_only=true",
+			expectedLen:     3,
+			expectedCurrent: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				s  *http.Server
+				r  *Rotator
+				ka *KeyAccessor
+			)
+
+			startTestApp(t, tc.args, &s, &r, &ka)
+			_, err := r.Rotate()
+			require.NoError(t, err)
+
+			response := serve(s.Handler, http.MethodGet, tc.target, nil)
+			require.Equal(t, http.StatusOK, response.Code)
+			set, err := jwk.Parse(response.Body.Bytes())
+			require.NoError(t, err)
+
+			if !tc.expectedCurrent {
+				// the initial pool and the rotated pool are both published
+				assert.Equal(tc.expectedLen, set.Len())
+				return
+			}
+
+			current, err := ka.LoadAll()
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedLen, set.Len())
+			for _, k := range current {
+				_, ok := set.LookupKeyID(k.KID)
+				assert.True(ok, k.KID)
+			}
+		})
+	}
+}
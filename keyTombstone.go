@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// tombstone is a deleted Key along with the time it stops being served.
+type tombstone struct {
+	key     Key
+	expires time.Time
+}
+
+// TombstoneKeyStore is a KeyStore decorator that keeps serving deleted keys for
+// a grace period. Verifiers that cached an older key set can then still verify
+// tokens signed by a deleted key until the grace period elapses.
+type TombstoneKeyStore struct {
+	KeyStore
+
+	grace time.Duration
+	now   func() time.Time
+
+	lock       sync.Mutex
+	tombstones map[string]tombstone
+}
+
+func NewTombstoneKeyStore(next KeyStore, grace time.Duration) *TombstoneKeyStore {
+	return &TombstoneKeyStore{
+		KeyStore:   next,
+		grace:      grace,
+		now:        time.Now,
+		tombstones: make(map[string]tombstone),
+	}
+}
+
+// prune removes expired tombstones. This method must be invoked under the lock.
+func (s *TombstoneKeyStore) prune(now time.Time) {
+	for kid, t := range s.tombstones {
+		if !now.Before(t.expires) {
+			delete(s.tombstones, kid)
+		}
+	}
+}
+
+// Unwrap returns the decorated KeyStore.
+func (s *TombstoneKeyStore) Unwrap() KeyStore {
+	return s.KeyStore
+}
+
+func (s *TombstoneKeyStore) Store(k Key) (err error) {
+	err = s.KeyStore.Store(k)
+	if err == nil {
+		s.lock.Lock()
+		delete(s.tombstones, k.KID)
+		s.lock.Unlock()
+	}
+
+	return
+}
+
+func (s *TombstoneKeyStore) Load(kid string) (k Key, err error) {
+	k, err = s.KeyStore.Load(kid)
+	if errors.Is(err, ErrNoSuchKey) {
+		now := s.now()
+		s.lock.Lock()
+		s.prune(now)
+		if t, exists := s.tombstones[kid]; exists {
+			k, err = t.key, nil
+		}
+
+		s.lock.Unlock()
+	}
+
+	return
+}
+
+func (s *TombstoneKeyStore) LoadAll() (ks []Key, err error) {
+	ks, err = s.KeyStore.LoadAll()
+	if err == nil {
+		now := s.now()
+		s.lock.Lock()
+		s.prune(now)
+		for _, t := range s.tombstones {
+			ks = append(ks, t.key)
+		}
+
+		s.lock.Unlock()
+	}
+
+	return
+}
+
+// Delete removes the key from the decorated KeyStore, then retains it as a
+// tombstone until the grace period elapses.
+func (s *TombstoneKeyStore) Delete(kid string) (err error) {
+	var k Key
+	k, err = s.KeyStore.Load(kid)
+	if err == nil {
+		err = s.KeyStore.Delete(kid)
+	}
+
+	if err == nil {
+		now := s.now()
+		s.lock.Lock()
+		s.prune(now)
+		s.tombstones[kid] = tombstone{key: k, expires: now.Add(s.grace)}
+		s.lock.Unlock()
+	}
+
+	return
+}
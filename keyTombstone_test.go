@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTombstoneKeyStore(t *testing.T) {
+	tests := []struct {
+		description string
+
+		// elapsed is the time between the delete and the lookups.
+		elapsed time.Duration
+
+		// restore stores the key again after deleting it.
+		restore        bool
+		expectedServed bool
+	}{
+		{
+			description:    "within the grace period",
+			elapsed:        59 * time.Second,
+			expectedServed: true,
+		},
+		{
+			description: "after the grace period",
+			elapsed:     time.Minute,
+		},
+		{
+			description:    "stored again",
+			elapsed:        2 * time.Minute,
+			restore:        true,
+			expectedServed: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			start := time.Now()
+			s := NewTombstoneKeyStore(NewInMemoryKeyStore(), time.Minute)
+			s.now = func() time.Time { return start }
+
+			k, err := newTestKey(t).PublicKey()
+			require.NoError(t, err)
+			require.NoError(t, s.Store(k))
+			require.NoError(t, s.Delete(k.KID))
+			assert.ErrorIs(s.Delete(k.KID), ErrNoSuchKey)
+
+			if tc.restore {
+				require.NoError(t, s.Store(k))
+			}
+
+			s.now = func() time.Time { return start.Add(tc.elapsed) }
+			loaded, err := s.Load(k.KID)
+			all, allErr := s.LoadAll()
+			require.NoError(t, allErr)
+
+			if tc.expectedServed {
+				require.NoError(t, err)
+				assert.Equal(k.KID, loaded.KID)
+				require.Len(t, all, 1)
+				assert.Equal(k.KID, all[0].KID)
+			} else {
+				assert.ErrorIs(err, ErrNoSuchKey)
+				assert.Empty(all)
+				assert.Empty(s.tombstones)
+			}
+		})
+	}
+}
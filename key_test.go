@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestKeyPublicJWKValidity(t *testing.T) {
+	created := time.Unix(1700000000, 0)
+	tests := []struct {
+		description     string
+		created         time.Time
+		expires         time.Time
+		expectedMembers map[string]any
+	}{
+		{
+			description: "both times",
+			created:     created,
+			expires:     created.Add(time.Hour),
+			expectedMembers: map[string]any{
+				CreatedParameter: float64(created.Unix()),
+				ExpiresParameter: float64(created.Add(time.Hour).Unix()),
+			},
+		},
+		{
+			description: "created only",
+			created:     created,
+			expectedMembers: map[string]any{
+				CreatedParameter: float64(created.Unix()),
+			},
+		},
+		{
+			description:     "neither time",
+			expectedMembers: map[string]any{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			k := newTestKey(t)
+			k.Created, k.Expires = tc.created, tc.expires
+
+			var buf bytes.Buffer
+			_, err := k.WriteTo(&buf)
+			require.NoError(t, err)
+
+			var members map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &members))
+			assert.NotContains(members, "d")
+			for _, name := range []string{CreatedParameter, ExpiresParameter} {
+				expected, ok := tc.expectedMembers[name]
+				if ok {
+					assert.Equal(expected, members[name])
+				} else {
+					assert.NotContains(members, name)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPublicSetValidity(t *testing.T) {
+	tests := []struct {
+		description string
+		keyType     string
+		expectedLen int
+	}{
+		{description: "EC", keyType: "EC", expectedLen: 1},
+		{description: "RSA", keyType: "RSA", expectedLen: 1},
+		{description: "OKP", keyType: "OKP", expectedLen: 1},
+		{description: "oct", keyType: "oct", expectedLen: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			k := newTestKey(t, "--key-type="+tc.keyType)
+			k.Created = time.Unix(1700000000, 0)
+			k.Expires = k.Created.Add(time.Hour)
+
+			set, err := NewPublicSet(zap.NewNop(), k)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedLen, set.Len())
+			if tc.expectedLen == 0 {
+				return
+			}
+
+			published, _ := set.Key(0)
+			var created, expires int64
+			assert.NoError(published.Get(CreatedParameter, &created))
+			assert.NoError(published.Get(ExpiresParameter, &expires))
+			assert.Equal(k.Created.Unix(), created)
+			assert.Equal(k.Expires.Unix(), expires)
+		})
+	}
+}
+
+func TestNewPublicSetMixed(t *testing.T) {
+	tests := []struct {
+		description      string
+		keys             []string
+		expectedLen      int
+		expectedWarnings int
+	}{
+		{
+			description: "EC and RSA",
+			keys:        []string{"EC", "RSA"},
+			expectedLen: 2,
+		},
+		{
+			description: "every key type",
+			keys:        []string{"EC", "RSA", "OKP", "oct"},
+			expectedLen: 3,
+		},
+		{
+			description:      "key without material",
+			keys:             []string{"RSA", "", "EC"},
+			expectedLen:      2,
+			expectedWarnings: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			keys := make([]Key, 0, len(tc.keys))
+			for _, keyType := range tc.keys {
+				if len(keyType) == 0 {
+					keys = append(keys, Key{KID: "empty"})
+				} else {
+					keys = append(keys, newTestKey(t, "--key-type="+keyType))
+				}
+			}
+
+			core, logs := observer.New(zap.WarnLevel)
+			set, err := NewPublicSet(zap.New(core), keys...)
+			require.NoError(t, err)
+			assert.Equal(tc.expectedLen, set.Len())
+			assert.Equal(tc.expectedWarnings, logs.Len())
+
+			for i := 0; i < set.Len(); i++ {
+				published, _ := set.Key(i)
+				private, err := jwk.IsPrivateKey(published)
+				assert.NoError(err)
+				assert.False(private)
+			}
+		})
+	}
+}
+
+func TestKeyForEncryption(t *testing.T) {
+	encryption, err := newEncryptionKeyGenerator(zap.NewNop(), NewIDGenerator(NewRandomSource()), NewRandomSource(), newTestCLI(t), "EC")
+	require.NoError(t, err)
+
+	tests := []struct {
+		description string
+		key         func(*testing.T) Key
+		expected    bool
+	}{
+		{
+			description: "no key material",
+			key:         func(*testing.T) Key { return Key{KID: "empty"} },
+		},
+		{
+			description: "signing key",
+			key:         func(t *testing.T) Key { return newTestKey(t) },
+		},
+		{
+			description: "encryption key",
+			key: func(t *testing.T) Key {
+				k, err := encryption.Generate()
+				require.NoError(t, err)
+				return k
+			},
+			expected: true,
+		},
+		{
+			description: "public encryption key",
+			key: func(t *testing.T) Key {
+				k, err := encryption.Generate()
+				require.NoError(t, err)
+				k, err = k.PublicKey()
+				require.NoError(t, err)
+				return k
+			},
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.key(t).ForEncryption())
+		})
+	}
+}
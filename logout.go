@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// BackChannelLogoutEvent is the events member that identifies an OIDC logout token.
+	BackChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+	// LogoutTokenType is the typ header for OIDC logout tokens.
+	LogoutTokenType = "logout+jwt"
+
+	// logoutNotifyTimeout is the deadline for delivering a logout token to a single relying party.
+	logoutNotifyTimeout = 5 * time.Second
+)
+
+// LogoutHandlerIn defines the dependencies necessary to create a LogoutHandler.
+type LogoutHandlerIn struct {
+	fx.In
+
+	Logger    *zap.Logger
+	Issuer    *Issuer
+	Signer    *Signer
+	Blacklist BlacklistStore
+	CLI       CLI
+}
+
+// LogoutHandler revokes every token for a sub or sid and, optionally, delivers an
+// OIDC back-channel logout token to each registered relying party.
+type LogoutHandler struct {
+	logger    *zap.Logger
+	issuer    *Issuer
+	signer    *Signer
+	blacklist BlacklistStore
+	now       func() time.Time
+	notify    []string
+	client    *http.Client
+}
+
+func NewLogoutHandler(in LogoutHandlerIn) *LogoutHandler {
+	lh := &LogoutHandler{
+		logger:    in.Logger,
+		issuer:    in.Issuer,
+		signer:    in.Signer,
+		blacklist: in.Blacklist,
+		now:       time.Now,
+		notify:    in.CLI.LogoutNotify,
+		client: &http.Client{
+			Timeout: logoutNotifyTimeout,
+		},
+	}
+
+	lh.logger.Info("logout",
+		zap.Strings("notify", lh.notify),
+	)
+
+	return lh
+}
+
+// logoutToken creates the OIDC back-channel logout token for the given sub and sid.
+func (lh *LogoutHandler) logoutToken(sub, sid string) (t jwt.Token, err error) {
+	var jti string
+	jti, err = lh.issuer.generateID()
+	if err == nil {
+		b := jwt.NewBuilder().
+			Issuer(lh.issuer.iss).
+			IssuedAt(lh.now().UTC()).
+			JwtID(jti).
+			Claim("events", map[string]any{BackChannelLogoutEvent: map[string]any{}})
+
+		if len(lh.issuer.aud) > 0 {
+			b.Audience(lh.issuer.aud)
+		}
+
+		if len(sub) > 0 {
+			b.Subject(sub)
+		}
+
+		if len(sid) > 0 {
+			b.Claim(SessionIDClaim, sid)
+		}
+
+		t, err = b.Build()
+	}
+
+	return
+}
+
+// notifyAll delivers the given logout token to each registered relying party.
+// Delivery failures are logged but otherwise ignored.
+func (lh *LogoutHandler) notifyAll(logoutToken []byte) {
+	body := url.Values{"logout_token": {string(logoutToken)}}.Encode()
+	for _, target := range lh.notify {
+		ctx, cancel := context.WithTimeout(context.Background(), logoutNotifyTimeout)
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(body))
+
+		var response *http.Response
+		if err == nil {
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			response, err = lh.client.Do(request)
+		}
+
+		if err == nil {
+			response.Body.Close()
+			if response.StatusCode >= 300 {
+				lh.logger.Error("relying party rejected logout token", zap.String("target", target), zap.Int("statusCode", response.StatusCode))
+			}
+		} else {
+			lh.logger.Error("unable to deliver logout token", zap.String("target", target), zap.Error(err))
+		}
+
+		cancel()
+	}
+}
+
+// ServeHTTP revokes the sub and/or sid form parameters. Revocations last as long as the
+// longest lived token this server issues.
+func (lh *LogoutHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if err := request.ParseForm(); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sub, sid := request.Form.Get(jwt.SubjectKey), request.Form.Get(SessionIDClaim)
+	if len(sub) == 0 && len(sid) == 0 {
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte("either sub or sid is required"))
+		return
+	}
+
+	var err error
+	expires := lh.now().Add(lh.issuer.MaxLifetime() + time.Minute)
+	if len(sub) > 0 {
+		err = lh.blacklist.Add(revocationID(jwt.SubjectKey, sub), expires)
+	}
+
+	if err == nil && len(sid) > 0 {
+		err = lh.blacklist.Add(revocationID(SessionIDClaim, sid), expires)
+	}
+
+	if err != nil {
+		lh.logger.Error("unable to revoke tokens", zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	lh.logger.Info("revoked tokens", zap.String("sub", sub), zap.String("sid", sid))
+	if len(lh.notify) > 0 {
+		var (
+			t      jwt.Token
+			signed []byte
+		)
+
+		t, err = lh.logoutToken(sub, sid)
+		if err == nil {
+			signed, err = lh.signer.SignTokenWithType(t, LogoutTokenType)
+		}
+
+		if err == nil {
+			go lh.notifyAll(signed)
+		} else {
+			lh.logger.Error("unable to create logout token", zap.Error(err))
+		}
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+func ProvideLogout() fx.Option {
+	return fx.Provide(
+		NewLogoutHandler,
+	)
+}
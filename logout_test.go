@@ -0,0 +1,171 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogoutHandler(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		logout      string
+
+		// revokedEarlier moves the revocation an hour into the past, before any token was issued.
+		revokedEarlier bool
+
+		expectedStatus int
+
+		// expectedRevoked maps the issue parameters of a token onto whether the logout revokes it.
+		expectedRevoked map[string]bool
+	}{
+		{
+			description:    "neither sub nor sid",
+			logout:         "",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:    "sid",
+			logout:         "sid=s1",
+			expectedStatus: http.StatusNoContent,
+			expectedRevoked: map[string]bool{
+				"sid=s1": true,
+				"sid=s2": false,
+				"":       false,
+			},
+		},
+		{
+			description:    "sub",
+			logout:         "sub=utu",
+			expectedStatus: http.StatusNoContent,
+			expectedRevoked: map[string]bool{
+				"sid=s1": true,
+				"":       true,
+			},
+		},
+		{
+			description:    "other sub",
+			logout:         "sub=someone-else",
+			expectedStatus: http.StatusNoContent,
+			expectedRevoked: map[string]bool{
+				"": false,
+			},
+		},
+		{
+			description:    "remapped sid",
+			args:           []string{"--claim-map=sid=session"},
+			logout:         "sid=s1",
+			expectedStatus: http.StatusNoContent,
+			expectedRevoked: map[string]bool{
+				"sid=s1": true,
+				"sid=s2": false,
+			},
+		},
+		{
+			description:    "tokens issued after the revocation",
+			logout:         "sid=s1",
+			revokedEarlier: true,
+			expectedStatus: http.StatusNoContent,
+			expectedRevoked: map[string]bool{
+				"sid=s1": false,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				s  *http.Server
+				bs BlacklistStore
+			)
+
+			startTestApp(t, append([]string{"--admin-token=" + testAdminToken}, tc.args...), &s, &bs)
+			tokens := make(map[string]string, len(tc.expectedRevoked))
+			for params := range tc.expectedRevoked {
+				tokens[params] = issueToken(t, s.Handler, params)
+			}
+
+			if tc.revokedEarlier {
+				bs.(*InMemoryBlacklistStore).now = func() time.Time { return time.Now().Add(-time.Hour) }
+			}
+
+			response := serve(s.Handler, http.MethodPost, "/logout", strings.NewReader(tc.logout), testAdminAuth)
+			require.Equal(t, tc.expectedStatus, response.Code)
+
+			for params, revoked := range tc.expectedRevoked {
+				response := serve(s.Handler, http.MethodPost, "/verify", strings.NewReader(tokens[params]))
+				if revoked {
+					assert.Equal(http.StatusUnauthorized, response.Code, params)
+					assert.Contains(response.Body.String(), ErrTokenRevoked.Error(), params)
+				} else {
+					assert.Equal(http.StatusOK, response.Code, params)
+				}
+			}
+		})
+	}
+}
+
+func TestLogoutHandlerNotify(t *testing.T) {
+	tests := []struct {
+		description string
+		logout      string
+		expectedSub any
+		expectedSID any
+	}{
+		{
+			description: "sid",
+			logout:      "sid=s1",
+			expectedSID: "s1",
+		},
+		{
+			description: "sub and sid",
+			logout:      "sub=alice&sid=s1",
+			expectedSub: "alice",
+			expectedSID: "s1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			logoutTokens := make(chan string, 1)
+			rp := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+				logoutTokens <- request.PostFormValue("logout_token")
+			}))
+
+			defer rp.Close()
+
+			h := newTestServer(t, "--admin-token="+testAdminToken, "--logout-notify="+rp.URL)
+			response := serve(h, http.MethodPost, "/logout", strings.NewReader(tc.logout), testAdminAuth)
+			require.Equal(t, http.StatusNoContent, response.Code)
+
+			var logoutToken string
+			select {
+			case logoutToken = <-logoutTokens:
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "no logout token was delivered")
+			}
+
+			_, err := jws.Verify([]byte(logoutToken), jws.WithKeySet(publishedKeys(t, h), jws.WithInferAlgorithmFromKey(true)))
+			assert.NoError(err)
+			assert.Equal(LogoutTokenType, tokenHeader(t, logoutToken)["typ"])
+
+			claims := tokenClaims(t, logoutToken)
+			assert.Contains(claims["events"], BackChannelLogoutEvent)
+			assert.Equal(tc.expectedSub, claims["sub"])
+			assert.Equal(tc.expectedSID, claims[SessionIDClaim])
+			assert.NotEmpty(claims["jti"])
+		})
+	}
+}
@@ -28,6 +28,7 @@ func run(args []string, options ...kong.Option) {
 	app := fx.New(
 		fx.Supply(cli, kctx),
 		ProvideLogging(),
+		ProvideMetrics(),
 		fx.Module(
 			"keys",
 			fx.Decorate(
@@ -37,12 +38,25 @@ func run(args []string, options ...kong.Option) {
 			),
 			ProvideKeyAccessor(),
 			ProvideKeyStore(),
+			ProvideBlacklistStore(),
+			ProvideRandomSource(),
 			ProvideIDGenerator(),
 			ProvideKeyGenerator(),
 			ProvideSigner(),
+			ProvideEncrypter(),
+			ProvidePostIssue(),
 			ProvideIssuer(),
+			ProvidePublisher(),
 			ProvideRotator(),
+			ProvideSelfTest(),
 			ProvideSwagger(),
+			ProvideExport(),
+			ProvideVerifier(),
+			ProvideLogout(),
+			ProvideInfo(),
+			ProvideServerMetadata(),
+			ProvideHealth(),
+			ProvideAlgorithms(),
 		),
 		fx.Module(
 			"http",
@@ -51,6 +65,7 @@ func run(args []string, options ...kong.Option) {
 					return l.Named("http")
 				},
 			),
+			ProvideAdminAuth(),
 			ProvideServer(),
 		),
 		fx.ErrorHook(errorHandler{}),
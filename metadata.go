@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// ServerMetadataPath is the RFC 8414 well-known path of the authorization server
+	// metadata document.
+	ServerMetadataPath = "/.well-known/oauth-authorization-server"
+)
+
+// ServerMetadata is the RFC 8414 authorization server metadata document.
+type ServerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+
+	// IDTokenSigningAlgValuesSupported holds every signing algorithm in the published
+	// key set, so that verifiers accept tokens signed by any key still in the set, even
+	// after the algorithm changes across rotations.
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+
+	// ResponseTypesSupported is required by RFC 8414. It is empty since the only
+	// supported grant doesn't use the authorization endpoint.
+	ResponseTypesSupported []string `json:"response_types_supported"`
+}
+
+// ServerMetadataHandler serves the RFC 8414 metadata document that OAuth clients
+// use to discover the token endpoint and key set.
+type ServerMetadataHandler struct {
+	logger      *zap.Logger
+	keyStore    KeyStore
+	issuer      string
+	externalURL string
+	authMethods []string
+
+	// cacheControl is the Cache-Control header of every metadata response.
+	cacheControl string
+}
+
+func NewServerMetadataHandler(l *zap.Logger, keyStore KeyStore, cli CLI) *ServerMetadataHandler {
+	smh := &ServerMetadataHandler{
+		logger:      l,
+		keyStore:    keyStore,
+		issuer:      cli.Issuer,
+		externalURL: cli.ExternalURL,
+		authMethods: []string{"client_secret_basic"},
+	}
+
+	smh.cacheControl = "no-cache"
+	if cli.MetadataMaxAge > 0 {
+		smh.cacheControl = fmt.Sprintf("public, max-age=%d", int64(cli.MetadataMaxAge/time.Second))
+	}
+
+	if len(cli.AdminClientCert) > 0 {
+		smh.authMethods = append(smh.authMethods, "tls_client_auth")
+	}
+
+	return smh
+}
+
+// baseURL returns the externally visible base URL of this server. When no external
+// URL is configured, the base URL is derived from the request.
+func (smh *ServerMetadataHandler) baseURL(request *http.Request) string {
+	if len(smh.externalURL) > 0 {
+		return smh.externalURL
+	}
+
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + request.Host
+}
+
+// signingAlgorithms returns the distinct signing algorithms of the keys in the published
+// key set, in sorted order.
+func (smh *ServerMetadataHandler) signingAlgorithms() (algs []string, err error) {
+	var keys []Key
+	keys, err = smh.keyStore.LoadAll()
+	algs = []string{}
+	for _, k := range keys {
+		if k.Alg != nil && !k.ForEncryption() {
+			algs = append(algs, k.Alg.String())
+		}
+	}
+
+	slices.Sort(algs)
+	algs = slices.Compact(algs)
+	return
+}
+
+// metadata builds the ServerMetadata for the given request.
+func (smh *ServerMetadataHandler) metadata(request *http.Request) (sm ServerMetadata, err error) {
+	base := smh.baseURL(request)
+	sm = ServerMetadata{
+		Issuer:                            smh.issuer,
+		TokenEndpointAuthMethodsSupported: smh.authMethods,
+		GrantTypesSupported:               []string{GrantTypeClientCredentials},
+		ResponseTypesSupported:            []string{},
+	}
+
+	sm.IDTokenSigningAlgValuesSupported, err = smh.signingAlgorithms()
+	if err == nil {
+		sm.JWKSURI, err = url.JoinPath(base, "keys")
+	}
+
+	if err == nil {
+		sm.TokenEndpoint, err = url.JoinPath(base, "token")
+	}
+
+	return
+}
+
+// metadataETag produces the strong ETag of a rendered metadata document. Since the
+// ETag is derived from the document itself, it changes whenever the configuration
+// that feeds the document does.
+func metadataETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// ServeHTTP renders the ServerMetadata as JSON. Conditional requests whose
+// If-None-Match matches the current ETag receive a 304.
+func (smh *ServerMetadataHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	var data []byte
+	sm, err := smh.metadata(request)
+	if err == nil {
+		data, err = json.Marshal(sm)
+	}
+
+	if err == nil {
+		response.Header().Set("Content-Type", "application/json")
+		response.Header().Set("Cache-Control", smh.cacheControl)
+		response.Header().Set("ETag", metadataETag(data))
+		http.ServeContent(response, request, "", time.Time{}, bytes.NewReader(data))
+	} else {
+		smh.logger.Error("unable to render server metadata", zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func ProvideServerMetadata() fx.Option {
+	return fx.Provide(
+		NewServerMetadataHandler,
+	)
+}
@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestServerMetadataHandler(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// clientCert allows a client certificate, which requires TLS.
+		clientCert bool
+		tls        bool
+
+		expected ServerMetadata
+	}{
+		{
+			description: "derived from the request",
+			expected: ServerMetadata{
+				Issuer:        "utu",
+				JWKSURI:       "http://example.com/keys",
+				TokenEndpoint: "http://example.com/token",
+			},
+		},
+		{
+			description: "derived from a TLS request",
+			tls:         true,
+			expected: ServerMetadata{
+				Issuer:        "utu",
+				JWKSURI:       "https://example.com/keys",
+				TokenEndpoint: "https://example.com/token",
+			},
+		},
+		{
+			description: "external URL",
+			args:        []string{"--issuer=https://utu.example.com", "--external-url=https://utu.example.com/base/"},
+			expected: ServerMetadata{
+				Issuer:        "https://utu.example.com",
+				JWKSURI:       "https://utu.example.com/base/keys",
+				TokenEndpoint: "https://utu.example.com/base/token",
+			},
+		},
+		{
+			description: "client certificate authentication",
+			clientCert:  true,
+			expected: ServerMetadata{
+				Issuer:                            "utu",
+				JWKSURI:                           "http://example.com/keys",
+				TokenEndpoint:                     "http://example.com/token",
+				TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "tls_client_auth"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			cli := newTestCLI(t, tc.args...)
+			if tc.clientCert {
+				cli.AdminClientCert = []string{"00"}
+			}
+
+			request := httptest.NewRequest(http.MethodGet, ServerMetadataPath, nil)
+			if tc.tls {
+				request.TLS = new(tls.ConnectionState)
+			}
+
+			response := httptest.NewRecorder()
+			NewServerMetadataHandler(zap.NewNop(), NewInMemoryKeyStore(), cli).ServeHTTP(response, request)
+			require.Equal(t, http.StatusOK, response.Code)
+			assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+			var sm ServerMetadata
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &sm))
+
+			expected := tc.expected
+			if expected.TokenEndpointAuthMethodsSupported == nil {
+				expected.TokenEndpointAuthMethodsSupported = []string{"client_secret_basic"}
+			}
+
+			expected.GrantTypesSupported = []string{GrantTypeClientCredentials}
+			expected.IDTokenSigningAlgValuesSupported = []string{}
+			expected.ResponseTypesSupported = []string{}
+			assert.Equal(t, expected, sm)
+		})
+	}
+}
+
+func TestServerMetadataRoute(t *testing.T) {
+	h := newTestServer(t)
+	response := serve(h, http.MethodGet, ServerMetadataPath, nil)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var sm ServerMetadata
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &sm))
+	assert.Equal(t, "http://example.com/keys", sm.JWKSURI)
+}
+
+func TestServerMetadataCaching(t *testing.T) {
+	tests := []struct {
+		description          string
+		args                 []string
+		ifNoneMatch          func(etag string) string
+		expectedStatus       int
+		expectedCacheControl string
+	}{
+		{
+			description:          "default max age",
+			expectedStatus:       http.StatusOK,
+			expectedCacheControl: "public, max-age=3600",
+		},
+		{
+			description:          "configured max age",
+			args:                 []string{"--metadata-max-age=90s"},
+			expectedStatus:       http.StatusOK,
+			expectedCacheControl: "public, max-age=90",
+		},
+		{
+			description:          "no max age",
+			args:                 []string{"--metadata-max-age=0s"},
+			expectedStatus:       http.StatusOK,
+			expectedCacheControl: "no-cache",
+		},
+		{
+			description:          "matching ETag",
+			ifNoneMatch:          func(etag string) string { return etag },
+			expectedStatus:       http.StatusNotModified,
+			expectedCacheControl: "public, max-age=3600",
+		},
+		{
+			description:          "one of several ETags matches",
+			ifNoneMatch:          func(etag string) string { return `"stale", ` + etag },
+			expectedStatus:       http.StatusNotModified,
+			expectedCacheControl: "public, max-age=3600",
+		},
+		{
+			description:          "stale ETag",
+			ifNoneMatch:          func(string) string { return `"stale"` },
+			expectedStatus:       http.StatusOK,
+			expectedCacheControl: "public, max-age=3600",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			smh := NewServerMetadataHandler(zap.NewNop(), NewInMemoryKeyStore(), newTestCLI(t, tc.args...))
+
+			first := serve(smh, http.MethodGet, ServerMetadataPath, nil)
+			require.Equal(t, http.StatusOK, first.Code)
+			etag := first.Header().Get("ETag")
+			assert.Equal(metadataETag(first.Body.Bytes()), etag)
+
+			var headers []string
+			if tc.ifNoneMatch != nil {
+				headers = append(headers, "If-None-Match: "+tc.ifNoneMatch(etag))
+			}
+
+			response := serve(smh, http.MethodGet, ServerMetadataPath, nil, headers...)
+			require.Equal(t, tc.expectedStatus, response.Code)
+			assert.Equal(tc.expectedCacheControl, response.Header().Get("Cache-Control"))
+			assert.Equal(etag, response.Header().Get("ETag"))
+			if tc.expectedStatus == http.StatusNotModified {
+				assert.Empty(response.Body.Bytes())
+			} else {
+				assert.Equal(first.Body.String(), response.Body.String())
+			}
+		})
+	}
+}
+
+// loadAllErrorKeyStore is a KeyStore whose LoadAll always fails.
+type loadAllErrorKeyStore struct {
+	KeyStore
+}
+
+func (loadAllErrorKeyStore) LoadAll() ([]Key, error) {
+	return nil, errors.New("expected")
+}
+
+func TestServerMetadataSigningAlgorithms(t *testing.T) {
+	tests := []struct {
+		description string
+
+		// keys are the command lines of the generated keys in the key set.
+		keys []string
+
+		// encryption is the command line of an encryption key in the key set.
+		encryption     string
+		keyStoreErr    bool
+		expectedStatus int
+		expected       []string
+	}{
+		{
+			description:    "no keys",
+			expectedStatus: http.StatusOK,
+			expected:       []string{},
+		},
+		{
+			description:    "one algorithm",
+			keys:           []string{"--key-type=EC"},
+			expectedStatus: http.StatusOK,
+			expected:       []string{"ES256"},
+		},
+		{
+			description:    "distinct algorithms, sorted",
+			keys:           []string{"--key-type=RSA", "--key-type=EC", "--key-type=EC --key-curve=P-384"},
+			expectedStatus: http.StatusOK,
+			expected:       []string{"ES256", "ES384", "RS256"},
+		},
+		{
+			description:    "duplicate algorithms",
+			keys:           []string{"--key-type=EC", "--key-type=EC"},
+			expectedStatus: http.StatusOK,
+			expected:       []string{"ES256"},
+		},
+		{
+			description:    "encryption keys excluded",
+			keys:           []string{"--key-type=EC"},
+			encryption:     "--key-type=RSA",
+			expectedStatus: http.StatusOK,
+			expected:       []string{"ES256"},
+		},
+		{
+			description:    "key store fails",
+			keyStoreErr:    true,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var ks KeyStore = NewInMemoryKeyStore()
+			store := func(args string, use jwk.KeyUsageType) {
+				k, err := newTestKey(t, strings.Fields(args)...).PublicKey()
+				require.NoError(t, err)
+				require.NoError(t, k.Key.Set(jwk.KeyUsageKey, use))
+				require.NoError(t, ks.Store(k))
+			}
+
+			for _, args := range tc.keys {
+				store(args, jwk.ForSignature)
+			}
+
+			if len(tc.encryption) > 0 {
+				store(tc.encryption, jwk.ForEncryption)
+			}
+
+			if tc.keyStoreErr {
+				ks = loadAllErrorKeyStore{KeyStore: ks}
+			}
+
+			smh := NewServerMetadataHandler(zap.NewNop(), ks, newTestCLI(t))
+			response := serve(smh, http.MethodGet, ServerMetadataPath, nil)
+			require.Equal(t, tc.expectedStatus, response.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var sm ServerMetadata
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &sm))
+			assert.Equal(t, tc.expected, sm.IDTokenSigningAlgValuesSupported)
+		})
+	}
+}
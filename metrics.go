@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/fx"
+)
+
+const (
+	// MetricsNamespace is the prometheus namespace for all utu metrics.
+	MetricsNamespace = "utu"
+)
+
+// NewMetricsRegistry creates the registry for all metrics exposed by this server.
+func NewMetricsRegistry() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return r
+}
+
+// NewMetricsHandler creates the handler that exposes metrics in the prometheus format.
+func NewMetricsHandler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
+
+func ProvideMetrics() fx.Option {
+	return fx.Provide(
+		fx.Annotate(
+			NewMetricsRegistry,
+			fx.As(new(prometheus.Registerer)),
+			fx.As(new(prometheus.Gatherer)),
+		),
+		fx.Annotate(
+			NewMetricsHandler,
+			fx.ResultTags(`name:"metricsHandler"`),
+		),
+	)
+}
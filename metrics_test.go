@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	tests := []struct {
+		description string
+		name        string
+	}{
+		{
+			description: "go runtime",
+			name:        "go_goroutines",
+		},
+		{
+			description: "issued tokens",
+			name:        MetricsNamespace + "_issued_token_expires_in_seconds",
+		},
+		{
+			description: "key rotation",
+			name:        MetricsNamespace + "_key_last_rotation_timestamp_seconds",
+		},
+	}
+
+	h := newTestServer(t)
+	issueToken(t, h, "")
+
+	// metrics are only collected in-process, and every scrape reflects them
+	response := serve(h, http.MethodGet, "/metrics", nil)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(response.Body)
+	require.NoError(t, err)
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Contains(t, families, tc.name)
+		})
+	}
+}
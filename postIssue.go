@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// IssuedToken is the metadata of an issued token. It never includes the
+// signature, so it cannot be used as a token itself.
+type IssuedToken struct {
+	// Claims is the complete claim set of the token.
+	Claims map[string]any
+}
+
+// NewIssuedToken extracts the metadata of the given token.
+func NewIssuedToken(t jwt.Token) (it IssuedToken, err error) {
+	var data []byte
+	data, err = json.Marshal(t)
+	if err == nil {
+		err = json.Unmarshal(data, &it.Claims)
+	}
+
+	return
+}
+
+// PostIssueHook is notified after each token is successfully issued, e.g. to publish
+// tokens to an external sink for downstream caching. Hooks are invoked asynchronously,
+// and a hook's failure never affects the response.
+type PostIssueHook interface {
+	PostIssue(context.Context, IssuedToken) error
+}
+
+// NopPostIssueHook is the default PostIssueHook, which does nothing.
+type NopPostIssueHook struct{}
+
+func (NopPostIssueHook) PostIssue(context.Context, IssuedToken) error {
+	return nil
+}
+
+// PostIssueNotifier invokes a PostIssueHook in the background.
+type PostIssueNotifier struct {
+	logger *zap.Logger
+	hook   PostIssueHook
+}
+
+func NewPostIssueNotifier(l *zap.Logger, hook PostIssueHook) *PostIssueNotifier {
+	return &PostIssueNotifier{
+		logger: l,
+		hook:   hook,
+	}
+}
+
+// Notify invokes the hook for the given token in a separate goroutine. Errors and
+// panics from the hook are logged and otherwise ignored.
+func (pin *PostIssueNotifier) Notify(t jwt.Token) {
+	if _, nop := pin.hook.(NopPostIssueHook); nop {
+		return
+	}
+
+	it, err := NewIssuedToken(t)
+	if err != nil {
+		pin.logger.Error("unable to extract issued token metadata", zap.Error(err))
+		return
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				pin.logger.Error("post-issue hook panicked", zap.Error(fmt.Errorf("%v", r)))
+			}
+		}()
+
+		if err := pin.hook.PostIssue(context.Background(), it); err != nil {
+			pin.logger.Error("post-issue hook failed", zap.Error(err))
+		}
+	}()
+}
+
+// ProvidePostIssue provides the default, no-op PostIssueHook. Use fx.Decorate
+// or fx.Replace to supply a different hook.
+func ProvidePostIssue() fx.Option {
+	return fx.Provide(
+		func() PostIssueHook {
+			return NopPostIssueHook{}
+		},
+		NewPostIssueNotifier,
+	)
+}
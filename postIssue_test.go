@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// recordingHook is a PostIssueHook that sends each IssuedToken to a channel, then
+// fails or panics as configured.
+type recordingHook struct {
+	issued chan IssuedToken
+	err    error
+	panics bool
+}
+
+func (h recordingHook) PostIssue(_ context.Context, it IssuedToken) error {
+	h.issued <- it
+	if h.panics {
+		panic("hook panic")
+	}
+
+	return h.err
+}
+
+func TestPostIssueNotifier(t *testing.T) {
+	tests := []struct {
+		description string
+		err         error
+		panics      bool
+		expectedLog string
+	}{
+		{
+			description: "success",
+		},
+		{
+			description: "hook error",
+			err:         errors.New("expected"),
+			expectedLog: "post-issue hook failed",
+		},
+		{
+			description: "hook panic",
+			panics:      true,
+			expectedLog: "post-issue hook panicked",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			core, logs := observer.New(zap.ErrorLevel)
+			hook := recordingHook{
+				issued: make(chan IssuedToken, 1),
+				err:    tc.err,
+				panics: tc.panics,
+			}
+
+			token, err := jwt.NewBuilder().Subject("test").Claim("tenant", "acme").Build()
+			require.NoError(t, err)
+
+			NewPostIssueNotifier(zap.New(core), hook).Notify(token)
+
+			select {
+			case it := <-hook.issued:
+				assert.Equal("test", it.Claims["sub"])
+				assert.Equal("acme", it.Claims["tenant"])
+
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "the hook was never invoked")
+			}
+
+			if len(tc.expectedLog) > 0 {
+				assert.Eventually(func() bool {
+					return logs.FilterMessage(tc.expectedLog).Len() == 1
+				}, 5*time.Second, 5*time.Millisecond)
+			} else {
+				assert.Never(func() bool {
+					return logs.Len() > 0
+				}, 50*time.Millisecond, 5*time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestPostIssueHookHandlers(t *testing.T) {
+	tests := []struct {
+		description string
+		target      string
+		body        string
+		headers     []string
+
+		// token extracts the issued token from the response body.
+		token func(*testing.T, []byte) string
+	}{
+		{
+			description: "issue",
+			target:      "/issue",
+			token: func(_ *testing.T, body []byte) string {
+				return string(body)
+			},
+		},
+		{
+			description: "token",
+			target:      "/token",
+			body:        "grant_type=client_credentials",
+			headers:     []string{testAdminAuth},
+			token: func(t *testing.T, body []byte) string {
+				var tr TokenResponse
+				require.NoError(t, json.Unmarshal(body, &tr))
+				return tr.AccessToken
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			hook := recordingHook{issued: make(chan IssuedToken, 1)}
+			var s *http.Server
+			startTestAppWith(t,
+				[]string{"--admin-token=" + testAdminToken},
+				[]fx.Option{
+					fx.Decorate(func(PostIssueHook) PostIssueHook { return hook }),
+				},
+				&s,
+			)
+
+			response := serve(s.Handler, http.MethodPost, tc.target, strings.NewReader(tc.body), tc.headers...)
+			require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+			select {
+			case it := <-hook.issued:
+				// the hook sees the claims of the token in the response
+				assert.Equal(t, tokenClaims(t, tc.token(t, response.Body.Bytes()))["jti"], it.Claims["jti"])
+
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "the hook was never invoked")
+			}
+		})
+	}
+}
+
+func TestProvidePostIssue(t *testing.T) {
+	var hook PostIssueHook
+	startTestApp(t, nil, &hook)
+	assert.IsType(t, NopPostIssueHook{}, hook)
+	assert.NoError(t, hook.PostIssue(context.Background(), IssuedToken{}))
+}
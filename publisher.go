@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// keySetPublishTimeout is the deadline for delivering the key set to a single webhook.
+	keySetPublishTimeout = 5 * time.Second
+)
+
+// PublishResult is the outcome of delivering the key set to a single webhook.
+type PublishResult struct {
+	Target     string `json:"target"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// KeySetPublisher pushes the published key set to webhooks, e.g. key set aggregators,
+// after every rotation. Publishes are serialized, so that a webhook never receives an
+// older key set after a newer one.
+type KeySetPublisher struct {
+	logger   *zap.Logger
+	keyStore KeyStore
+	targets  []string
+	client   *http.Client
+	lock     sync.Mutex
+}
+
+func NewKeySetPublisher(l *zap.Logger, keyStore KeyStore, cli CLI) *KeySetPublisher {
+	p := &KeySetPublisher{
+		logger:   l,
+		keyStore: keyStore,
+		targets:  cli.KeySetWebhook,
+		client: &http.Client{
+			Timeout: keySetPublishTimeout,
+		},
+	}
+
+	p.logger.Info("key set publisher",
+		zap.Strings("targets", p.targets),
+	)
+
+	return p
+}
+
+// Enabled tests if any webhooks are configured.
+func (p *KeySetPublisher) Enabled() bool {
+	return len(p.targets) > 0
+}
+
+// deliver POSTs the key set to a single webhook.
+func (p *KeySetPublisher) deliver(ctx context.Context, target string, body []byte) (result PublishResult) {
+	result.Target = target
+
+	ctx, cancel := context.WithTimeout(ctx, keySetPublishTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+
+	var response *http.Response
+	if err == nil {
+		request.Header.Set("Content-Type", "application/jwk-set+json")
+		response, err = p.client.Do(request)
+	}
+
+	if err == nil {
+		response.Body.Close()
+		result.StatusCode = response.StatusCode
+		if response.StatusCode >= 300 {
+			err = fmt.Errorf("the webhook responded with %d", response.StatusCode)
+		}
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		p.logger.Error("unable to publish key set", zap.String("target", target), zap.Error(err))
+	}
+
+	return
+}
+
+// Publish delivers the current public key set to every webhook, returning the
+// outcome for each. Delivery failures are reported in the results rather than as
+// an error, so that one unreachable webhook doesn't hide the others. Canceling the
+// given context abandons any remaining deliveries.
+func (p *KeySetPublisher) Publish(ctx context.Context) (results []PublishResult, err error) {
+	var (
+		keys []Key
+		set  jwk.Set
+		body []byte
+	)
+
+	// the key set is loaded under the lock, so each publish sends a set at least as new as the last
+	defer p.lock.Unlock()
+	p.lock.Lock()
+	keys, err = p.keyStore.LoadAll()
+	if err == nil {
+		set, err = NewPublicSet(p.logger, keys...)
+	}
+
+	if err == nil {
+		body, err = json.Marshal(set)
+	}
+
+	results = make([]PublishResult, 0, len(p.targets))
+	for i := 0; err == nil && i < len(p.targets); i++ {
+		results = append(results, p.deliver(ctx, p.targets[i], body))
+	}
+
+	return
+}
+
+// PublishHandler immediately pushes the key set to every webhook, e.g. after a
+// client reports a stale cache.
+type PublishHandler struct {
+	logger    *zap.Logger
+	publisher *KeySetPublisher
+}
+
+func NewPublishHandler(l *zap.Logger, publisher *KeySetPublisher) *PublishHandler {
+	return &PublishHandler{
+		logger:    l,
+		publisher: publisher,
+	}
+}
+
+// ServeHTTP responds with the outcome for each webhook. If any delivery failed,
+// the response status is 502.
+func (ph *PublishHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	var data []byte
+	results, err := ph.publisher.Publish(request.Context())
+	if err == nil {
+		data, err = json.Marshal(results)
+	}
+
+	if err != nil {
+		ph.logger.Error("unable to publish key set", zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	statusCode := http.StatusOK
+	for _, r := range results {
+		if len(r.Error) > 0 {
+			statusCode = http.StatusBadGateway
+		}
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(statusCode)
+	response.Write(data)
+}
+
+func ProvidePublisher() fx.Option {
+	return fx.Provide(
+		NewKeySetPublisher,
+		NewPublishHandler,
+	)
+}
@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestReceiver starts a webhook that responds with the given status code and
+// sends each key set it receives on the returned channel.
+func newTestReceiver(t *testing.T, statusCode int) (*httptest.Server, <-chan jwk.Set) {
+	received := make(chan jwk.Set, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		if assert.NoError(t, err) && assert.Equal(t, "application/jwk-set+json", request.Header.Get("Content-Type")) {
+			set, err := jwk.Parse(body)
+			if assert.NoError(t, err) {
+				received <- set
+			}
+		}
+
+		response.WriteHeader(statusCode)
+	}))
+
+	t.Cleanup(receiver.Close)
+	return receiver, received
+}
+
+// unreachableTarget returns a URL that refuses connections.
+func unreachableTarget(t *testing.T) string {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	return closed.URL
+}
+
+func TestKeySetPublisher(t *testing.T) {
+	tests := []struct {
+		description string
+
+		// statusCodes are the responses of each receiver. A zero status code is an
+		// unreachable receiver.
+		statusCodes   []int
+		expectedError []bool
+	}{
+		{
+			description: "no webhooks",
+		},
+		{
+			description:   "delivered",
+			statusCodes:   []int{http.StatusNoContent},
+			expectedError: []bool{false},
+		},
+		{
+			description:   "rejected",
+			statusCodes:   []int{http.StatusInternalServerError},
+			expectedError: []bool{true},
+		},
+		{
+			description:   "one unreachable webhook doesn't hide the others",
+			statusCodes:   []int{0, http.StatusOK},
+			expectedError: []bool{true, false},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			k := newTestKey(t)
+			ks := NewInMemoryKeyStore()
+			require.NoError(t, ks.Store(k))
+
+			var (
+				args     []string
+				targets  []string
+				received []<-chan jwk.Set
+			)
+
+			for _, statusCode := range tc.statusCodes {
+				target := unreachableTarget(t)
+				if statusCode != 0 {
+					receiver, ch := newTestReceiver(t, statusCode)
+					target = receiver.URL
+					received = append(received, ch)
+				}
+
+				targets = append(targets, target)
+				args = append(args, "--key-set-webhook="+target)
+			}
+
+			p := NewKeySetPublisher(zap.NewNop(), ks, newTestCLI(t, args...))
+			assert.Equal(len(targets) > 0, p.Enabled())
+
+			results, err := p.Publish(context.Background())
+			require.NoError(t, err)
+			require.Len(t, results, len(targets))
+			for i, result := range results {
+				assert.Equal(targets[i], result.Target)
+				assert.Equal(tc.expectedError[i], len(result.Error) > 0, result.Error)
+			}
+
+			for _, ch := range received {
+				set := <-ch
+				require.Equal(t, 1, set.Len())
+				published, _ := set.Key(0)
+				kid, _ := published.KeyID()
+				assert.Equal(k.KID, kid)
+			}
+		})
+	}
+}
+
+func TestPublishHandler(t *testing.T) {
+	tests := []struct {
+		description    string
+		statusCode     int
+		headers        []string
+		expectedStatus int
+	}{
+		{
+			description:    "unauthenticated",
+			statusCode:     http.StatusOK,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "delivered",
+			statusCode:     http.StatusOK,
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "rejected",
+			statusCode:     http.StatusServiceUnavailable,
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			receiver, _ := newTestReceiver(t, tc.statusCode)
+			h := newTestServer(t, "--admin-token="+testAdminToken, "--key-set-webhook="+receiver.URL)
+			response := serve(h, http.MethodPost, "/admin/publish", nil, tc.headers...)
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus == http.StatusUnauthorized {
+				return
+			}
+
+			var results []PublishResult
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &results))
+			require.Len(t, results, 1)
+			assert.Equal(t, tc.statusCode, results[0].StatusCode)
+		})
+	}
+}
+
+func TestRotatorPublishes(t *testing.T) {
+	receiver, received := newTestReceiver(t, http.StatusOK)
+
+	var r *Rotator
+	startTestApp(t, []string{"--key-set-webhook=" + receiver.URL}, &r)
+
+	k, err := r.Rotate()
+	require.NoError(t, err)
+
+	// the receiver eventually gets a key set that includes the rotated key
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case set := <-received:
+			if _, ok := set.LookupKeyID(k.KID); ok {
+				return
+			}
+
+		case <-deadline:
+			require.FailNow(t, "the rotated key set was not published")
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// PurgeResponse is the body returned by PurgeHandler.
+type PurgeResponse struct {
+	Purged int `json:"purged"`
+}
+
+// PurgeHandler immediately deletes expired keys, rather than waiting for the next
+// scheduled rotation to do so.
+type PurgeHandler struct {
+	logger  *zap.Logger
+	rotator *Rotator
+}
+
+func NewPurgeHandler(l *zap.Logger, rotator *Rotator) *PurgeHandler {
+	return &PurgeHandler{
+		logger:  l,
+		rotator: rotator,
+	}
+}
+
+// ServeHTTP purges expired keys and responds with the number of keys purged.
+func (ph *PurgeHandler) ServeHTTP(response http.ResponseWriter, _ *http.Request) {
+	var data []byte
+	purged, err := ph.rotator.Purge()
+	if err == nil {
+		data, err = json.Marshal(PurgeResponse{Purged: purged})
+	}
+
+	if err == nil {
+		writeBody(response, "application/json", data)
+	} else {
+		ph.logger.Error("unable to purge expired keys", zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeHandler(t *testing.T) {
+	tests := []struct {
+		description    string
+		headers        []string
+		expired        int
+		expectedStatus int
+		expectedPurged int
+	}{
+		{
+			description:    "unauthenticated",
+			expired:        1,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "nothing to purge",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "expired keys",
+			headers:        []string{testAdminAuth},
+			expired:        2,
+			expectedStatus: http.StatusOK,
+			expectedPurged: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var (
+				s  *http.Server
+				ks KeyStore
+			)
+
+			startTestApp(t, []string{"--admin-token=" + testAdminToken}, &s, &ks)
+			for range tc.expired {
+				k, err := newTestKey(t).PublicKey()
+				require.NoError(t, err)
+				k.Expires = time.Now().Add(-time.Second)
+				require.NoError(t, ks.Store(k))
+			}
+
+			response := serve(s.Handler, http.MethodPost, "/admin/purge", nil, tc.headers...)
+			require.Equal(t, tc.expectedStatus, response.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var pr PurgeResponse
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &pr))
+			assert.Equal(t, tc.expectedPurged, pr.Purged)
+
+			keys, err := ks.LoadAll()
+			require.NoError(t, err)
+			assert.Len(t, keys, 1)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// RandomSourceSystem names the operating system's random source, i.e. crypto/rand.
+	RandomSourceSystem = "system"
+
+	// RandomSourceFile names the configured random file, such as a hardware RNG device.
+	RandomSourceFile = "file"
+)
+
+var (
+	// ErrNoRandomFile indicates that the file random source was requested, but
+	// no --random-file is configured.
+	ErrNoRandomFile = errors.New("no random file is configured")
+)
+
+// RandomSource is the io.Reader that supplies all randomness for ids and keys.
+// The underlying reader can be swapped at runtime, e.g. to reseed from a hardware
+// RNG in a long-running process, without restarting.
+type RandomSource struct {
+	lock   sync.RWMutex
+	name   string
+	reader io.Reader
+	closer io.Closer
+}
+
+// NewRandomSource creates a RandomSource that reads from crypto/rand.
+func NewRandomSource() *RandomSource {
+	return &RandomSource{
+		name:   RandomSourceSystem,
+		reader: rand.Reader,
+	}
+}
+
+// Read reads from the current underlying reader.
+func (rs *RandomSource) Read(p []byte) (int, error) {
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+	return rs.reader.Read(p)
+}
+
+// Name returns the name of the current underlying reader.
+func (rs *RandomSource) Name() string {
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+	return rs.name
+}
+
+// Swap replaces the underlying reader. If closer is not nil, it is closed when
+// the reader is swapped out in turn.
+func (rs *RandomSource) Swap(name string, reader io.Reader, closer io.Closer) {
+	rs.lock.Lock()
+	previous := rs.closer
+	rs.name, rs.reader, rs.closer = name, reader, closer
+	rs.lock.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+}
+
+// RandomSourceHandler swaps the RandomSource at runtime. The source form value
+// selects the new source, and defaults to the random file when one is configured.
+type RandomSourceHandler struct {
+	logger       *zap.Logger
+	randomSource *RandomSource
+	randomFile   string
+}
+
+func NewRandomSourceHandler(l *zap.Logger, randomSource *RandomSource, cli CLI) *RandomSourceHandler {
+	return &RandomSourceHandler{
+		logger:       l,
+		randomSource: randomSource,
+		randomFile:   cli.RandomFile,
+	}
+}
+
+// open swaps in the named source.
+func (rsh *RandomSourceHandler) open(name string) (err error) {
+	switch {
+	case name == RandomSourceSystem:
+		rsh.randomSource.Swap(RandomSourceSystem, rand.Reader, nil)
+
+	case name == RandomSourceFile && len(rsh.randomFile) == 0:
+		err = ErrNoRandomFile
+
+	case name == RandomSourceFile:
+		var f *os.File
+		if f, err = os.Open(rsh.randomFile); err == nil {
+			rsh.randomSource.Swap(RandomSourceFile, f, f)
+		}
+
+	default:
+		err = fmt.Errorf("unknown random source: %s", name)
+	}
+
+	return
+}
+
+func (rsh *RandomSourceHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if err := request.ParseForm(); err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	name := request.Form.Get("source")
+	if len(name) == 0 {
+		name = RandomSourceSystem
+		if len(rsh.randomFile) > 0 {
+			name = RandomSourceFile
+		}
+	}
+
+	if err := rsh.open(name); err != nil {
+		rsh.logger.Error("unable to swap random source", zap.String("source", name), zap.Error(err))
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(err.Error()))
+		return
+	}
+
+	rsh.logger.Info("swapped random source", zap.String("source", name))
+	writeBody(response, "text/plain;charset=utf-8", []byte(name))
+}
+
+// ProvideRandomSource provides the shared RandomSource. When a random file is
+// configured, it is opened as the initial source.
+func ProvideRandomSource() fx.Option {
+	return fx.Provide(
+		func(l *zap.Logger, cli CLI) (rs *RandomSource, err error) {
+			rs = NewRandomSource()
+			if len(cli.RandomFile) > 0 {
+				var f *os.File
+				if f, err = os.Open(cli.RandomFile); err == nil {
+					rs.Swap(RandomSourceFile, f, f)
+				}
+			}
+
+			l.Info("random source", zap.String("source", rs.Name()), zap.String("randomFile", cli.RandomFile))
+			return
+		},
+		NewRandomSourceHandler,
+	)
+}
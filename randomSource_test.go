@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeRandomFile writes a file of repeated bytes to stand in for a hardware RNG.
+func writeRandomFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "random")
+	require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte{0x42}, 64*1024), 0o600))
+	return path
+}
+
+// closeRecorder records whether it was closed.
+type closeRecorder struct {
+	closed bool
+}
+
+func (cr *closeRecorder) Close() error {
+	cr.closed = true
+	return nil
+}
+
+func TestRandomSourceSwap(t *testing.T) {
+	rs := NewRandomSource()
+	assert.Equal(t, RandomSourceSystem, rs.Name())
+
+	first := new(closeRecorder)
+	rs.Swap("first", bytes.NewReader([]byte("abc")), first)
+	assert.Equal(t, "first", rs.Name())
+
+	p := make([]byte, 3)
+	_, err := io.ReadFull(rs, p)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", string(p))
+
+	// the previous reader is closed once it is swapped out
+	rs.Swap("second", bytes.NewReader(nil), nil)
+	assert.True(t, first.closed)
+	assert.Equal(t, "second", rs.Name())
+}
+
+func TestRandomSourceHandler(t *testing.T) {
+	randomFile := writeRandomFile(t)
+	tests := []struct {
+		description    string
+		args           []string
+		body           string
+		headers        []string
+		expectedStatus int
+		expectedSource string
+	}{
+		{
+			description:    "default without a random file",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusOK,
+			expectedSource: RandomSourceSystem,
+		},
+		{
+			description:    "default with a random file",
+			args:           []string{"--random-file=" + randomFile},
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusOK,
+			expectedSource: RandomSourceFile,
+		},
+		{
+			description:    "system",
+			args:           []string{"--random-file=" + randomFile},
+			body:           "source=system",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusOK,
+			expectedSource: RandomSourceSystem,
+		},
+		{
+			description:    "file without a random file",
+			body:           "source=file",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusBadRequest,
+			expectedSource: RandomSourceSystem,
+		},
+		{
+			description:    "unknown source",
+			body:           "source=dice",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusBadRequest,
+			expectedSource: RandomSourceSystem,
+		},
+		{
+			description:    "unauthenticated",
+			args:           []string{"--random-file=" + randomFile},
+			body:           "source=system",
+			expectedStatus: http.StatusUnauthorized,
+			expectedSource: RandomSourceFile,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var (
+				s  *http.Server
+				rs *RandomSource
+			)
+
+			startTestApp(t, append([]string{"--admin-token=" + testAdminToken}, tc.args...), &s, &rs)
+			response := serve(s.Handler, http.MethodPost, "/admin/random", strings.NewReader(tc.body), tc.headers...)
+			assert.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			assert.Equal(t, tc.expectedSource, rs.Name())
+		})
+	}
+}
+
+func TestProvideRandomSource(t *testing.T) {
+	var rs *RandomSource
+	startTestApp(t, []string{"--random-file=" + writeRandomFile(t)}, &rs)
+	assert.Equal(t, RandomSourceFile, rs.Name())
+
+	// ids and keys draw from the file rather than the system source
+	p := make([]byte, 16)
+	_, err := io.ReadFull(rs, p)
+	require.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{0x42}, len(p)), p)
+}
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"sync"
+)
+
+const (
+	// maxJTIAttempts is how many jtis are generated for a single token before a
+	// collision with a recently issued jti is treated as an error.
+	maxJTIAttempts = 3
+)
+
+var (
+	// ErrJTICollision indicates that every jti generated for a token collided with a
+	// recently issued jti, which suggests the random source is broken.
+	ErrJTICollision = errors.New("unable to generate a jti that wasn't recently issued")
+)
+
+// RecentJTIs is a bounded set of recently issued jtis, used to detect collisions
+// before a duplicate jti is issued. Once full, the oldest jti is forgotten to make
+// room for each new one.
+type RecentJTIs struct {
+	lock  sync.Mutex
+	seen  map[string]struct{}
+	order []string
+	next  int
+}
+
+func NewRecentJTIs(size int) *RecentJTIs {
+	return &RecentJTIs{
+		seen:  make(map[string]struct{}, size),
+		order: make([]string, 0, size),
+	}
+}
+
+// Add remembers the given jti, returning false without changing anything if the
+// jti was already recently issued.
+func (r *RecentJTIs) Add(jti string) bool {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	if _, exists := r.seen[jti]; exists {
+		return false
+	}
+
+	if len(r.order) < cap(r.order) {
+		r.order = append(r.order, jti)
+	} else {
+		delete(r.seen, r.order[r.next])
+		r.order[r.next] = jti
+		r.next = (r.next + 1) % len(r.order)
+	}
+
+	r.seen[jti] = struct{}{}
+	return true
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentJTIs(t *testing.T) {
+	tests := []struct {
+		description string
+		size        int
+		jtis        []string
+		expected    []bool
+	}{
+		{
+			description: "distinct",
+			size:        3,
+			jtis:        []string{"a", "b", "c"},
+			expected:    []bool{true, true, true},
+		},
+		{
+			description: "collision",
+			size:        3,
+			jtis:        []string{"a", "b", "a"},
+			expected:    []bool{true, true, false},
+		},
+		{
+			description: "oldest forgotten",
+			size:        2,
+			jtis:        []string{"a", "b", "c", "a", "c"},
+			expected:    []bool{true, true, true, true, false},
+		},
+		{
+			description: "wraps around",
+			size:        2,
+			jtis:        []string{"a", "b", "c", "d", "e", "d", "b"},
+			expected:    []bool{true, true, true, true, true, false, true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			r := NewRecentJTIs(tc.size)
+			for i, jti := range tc.jtis {
+				assert.Equal(t, tc.expected[i], r.Add(jti), "jti %d", i)
+			}
+
+			assert.LessOrEqual(t, len(r.seen), tc.size)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisTimeout is the deadline for any single redis operation.
+	redisTimeout = 2 * time.Second
+)
+
+// RedisBlacklistStore is a BlacklistStore backed by redis, which allows the
+// blacklist to be shared across replicas. Expiry is delegated to redis. Each
+// id's value is the epoch second at which it was added.
+type RedisBlacklistStore struct {
+	client redis.UniversalClient
+	prefix string
+	now    func() time.Time
+}
+
+func NewRedisBlacklistStore(address, prefix string) *RedisBlacklistStore {
+	return &RedisBlacklistStore{
+		client: redis.NewClient(&redis.Options{
+			Addr: address,
+		}),
+		prefix: prefix,
+		now:    time.Now,
+	}
+}
+
+func (s *RedisBlacklistStore) Add(id string, expires time.Time) error {
+	if !s.now().Before(expires) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return s.client.SetArgs(ctx, s.prefix+id, s.now().Unix(), redis.SetArgs{ExpireAt: expires}).Err()
+}
+
+// AddIfAbsent uses SET NX, so that the check and the add are a single redis operation.
+func (s *RedisBlacklistStore) AddIfAbsent(id string, expires time.Time) (bool, error) {
+	if !s.now().Before(expires) {
+		return true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	err := s.client.SetArgs(ctx, s.prefix+id, s.now().Unix(), redis.SetArgs{Mode: "NX", ExpireAt: expires}).Err()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (s *RedisBlacklistStore) Contains(id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	n, err := s.client.Exists(ctx, s.prefix+id).Result()
+	return n > 0, err
+}
+
+func (s *RedisBlacklistStore) AddedAt(id string) (added time.Time, ok bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	var seconds int64
+	seconds, err = s.client.Get(ctx, s.prefix+id).Int64()
+	switch {
+	case errors.Is(err, redis.Nil):
+		err = nil
+
+	case err == nil:
+		added, ok = time.Unix(seconds, 0), true
+	}
+
+	return
+}
@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRedisBlacklistStore creates a RedisBlacklistStore backed by an in-process
+// redis server. The returned advance function moves both the store's clock and the
+// server's clock forward, so that redis expires keys as a real server would.
+func newTestRedisBlacklistStore(t *testing.T, start time.Time) (s *RedisBlacklistStore, m *miniredis.Miniredis, advance func(time.Duration)) {
+	m = miniredis.RunT(t)
+	m.SetTime(start)
+
+	now := start
+	s = NewRedisBlacklistStore(m.Addr(), "test:")
+	s.now = func() time.Time { return now }
+	t.Cleanup(func() { s.client.Close() })
+
+	advance = func(d time.Duration) {
+		now = now.Add(d)
+		m.SetTime(now)
+		m.FastForward(d)
+	}
+
+	return
+}
+
+func TestRedisBlacklistStoreContains(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		description string
+		expires     time.Duration
+		elapsed     time.Duration
+		expected    bool
+	}{
+		{
+			description: "not yet expired",
+			expires:     time.Minute,
+			elapsed:     59 * time.Second,
+			expected:    true,
+		},
+		{
+			description: "expired",
+			expires:     time.Minute,
+			elapsed:     time.Minute,
+		},
+		{
+			description: "already expired when added",
+			expires:     -time.Second,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			s, m, advance := newTestRedisBlacklistStore(t, start)
+
+			require.NoError(t, s.Add("id", start.Add(tc.expires)))
+			advance(tc.elapsed)
+
+			contains, err := s.Contains("id")
+			assert.NoError(err)
+			assert.Equal(tc.expected, contains)
+
+			contains, err = s.Contains("other")
+			assert.NoError(err)
+			assert.False(contains)
+
+			// ids are stored under the prefix, and expired ids are gone from redis
+			assert.Equal(tc.expected, m.Exists("test:id"))
+		})
+	}
+}
+
+func TestRedisBlacklistStoreAddedAt(t *testing.T) {
+	start := time.Unix(1700000000, 0).Add(500 * time.Millisecond)
+	tests := []struct {
+		description   string
+		elapsed       time.Duration
+		expectedAdded time.Time
+		expectedOK    bool
+	}{
+		{
+			description:   "revoked",
+			elapsed:       time.Second,
+			expectedAdded: time.Unix(1700000000, 0),
+			expectedOK:    true,
+		},
+		{
+			description: "expired",
+			elapsed:     time.Hour,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			s, _, advance := newTestRedisBlacklistStore(t, start)
+
+			require.NoError(t, s.Add("id", start.Add(time.Minute)))
+			advance(tc.elapsed)
+
+			added, ok, err := s.AddedAt("id")
+			assert.NoError(err)
+			assert.Equal(tc.expectedOK, ok)
+			assert.True(tc.expectedAdded.Equal(added), "added at %s", added)
+		})
+	}
+}
+
+func TestRedisBlacklistStoreAddIfAbsent(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		description string
+
+		// elapsed is the time between the first and second add.
+		elapsed       time.Duration
+		expectedAdded bool
+	}{
+		{
+			description: "still present",
+			elapsed:     59 * time.Second,
+		},
+		{
+			description:   "expired",
+			elapsed:       time.Minute,
+			expectedAdded: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			s, m, advance := newTestRedisBlacklistStore(t, start)
+
+			added, err := s.AddIfAbsent("id", start.Add(time.Minute))
+			require.NoError(t, err)
+			assert.True(added)
+
+			advance(tc.elapsed)
+			added, err = s.AddIfAbsent("id", start.Add(tc.elapsed+time.Minute))
+			require.NoError(t, err)
+			assert.Equal(tc.expectedAdded, added)
+
+			// the id now expires a minute after whichever add succeeded
+			assert.True(m.Exists("test:id"))
+			expected := time.Minute - tc.elapsed
+			if tc.expectedAdded {
+				expected = time.Minute
+			}
+
+			assert.Equal(expected, m.TTL("test:id"))
+		})
+	}
+}
+
+func TestRedisBlacklistStoreAddIfAbsentExpired(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	s, m, _ := newTestRedisBlacklistStore(t, start)
+
+	// an already expired id counts as added, without reaching redis
+	added, err := s.AddIfAbsent("id", start)
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.False(t, m.Exists("test:id"))
+}
+
+func TestRedisBlacklistStoreAddIfAbsentConcurrent(t *testing.T) {
+	const workers = 16
+	var (
+		s, _, _ = newTestRedisBlacklistStore(t, time.Now())
+		wg      sync.WaitGroup
+		added   atomic.Int32
+		expires = time.Now().Add(time.Minute)
+	)
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, err := s.AddIfAbsent("id", expires); assert.NoError(t, err) && ok {
+				added.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, int32(1), added.Load())
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"go.uber.org/zap"
+)
+
+const (
+	// ReturnParameter is the /rotate request parameter that selects what is returned
+	// after rotating. When unset, only the new current kid is returned.
+	ReturnParameter = "return"
+
+	// ReturnSet is the ReturnParameter value that returns the public key set.
+	ReturnSet = "set"
+)
+
+// RotateResponse is the body returned by RotateHandler when no key set is requested.
+type RotateResponse struct {
+	KID string `json:"kid"`
+}
+
+// RotateHandler immediately rotates the primary keys, e.g. when an orchestrator
+// wants to control the rotation schedule itself.
+type RotateHandler struct {
+	logger   *zap.Logger
+	rotator  *Rotator
+	keyStore KeyStore
+}
+
+func NewRotateHandler(l *zap.Logger, rotator *Rotator, keyStore KeyStore) *RotateHandler {
+	return &RotateHandler{
+		logger:   l,
+		rotator:  rotator,
+		keyStore: keyStore,
+	}
+}
+
+// rotatedSet produces the public key set after a rotation. The rotated key is
+// always in the set, even if the KeyStore doesn't yet return it from LoadAll.
+func (rh *RotateHandler) rotatedSet(current Key) (set jwk.Set, err error) {
+	var keys []Key
+	keys, err = rh.keyStore.LoadAll()
+
+	found := false
+	for i := 0; err == nil && !found && i < len(keys); i++ {
+		found = keys[i].KID == current.KID
+	}
+
+	if err == nil && !found {
+		keys = append(keys, current)
+	}
+
+	if err == nil {
+		set, err = NewPublicSet(rh.logger, keys...)
+	}
+
+	return
+}
+
+// ServeHTTP rotates the primary keys and responds with the new kid, which is pending
+// rather than current when an activation delay is configured. A return
+// parameter of set responds with the updated public key set instead, so that a caller
+// can rotate and fetch the resulting set in one request.
+func (rh *RotateHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	returnSet := false
+	switch r := request.URL.Query().Get(ReturnParameter); r {
+	case "":
+
+	case ReturnSet:
+		returnSet = true
+
+	default:
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte("unsupported return value: " + r))
+		return
+	}
+
+	var (
+		set  jwk.Set
+		data []byte
+	)
+
+	k, err := rh.rotator.Rotate()
+	switch {
+	case err != nil:
+		// nothing else to do
+
+	case returnSet:
+		set, err = rh.rotatedSet(k)
+		if err == nil {
+			data, err = json.Marshal(set)
+		}
+
+	default:
+		data, err = json.Marshal(RotateResponse{KID: k.KID})
+	}
+
+	switch {
+	case err != nil:
+		rh.logger.Error("unable to rotate keys", zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+
+	case returnSet:
+		rh.logger.Info("rotated key on request", KeyField("key", k))
+		writeBody(response, "application/jwk-set+json", data)
+
+	default:
+		rh.logger.Info("rotated key on request", KeyField("key", k))
+		writeBody(response, "application/json", data)
+	}
+}
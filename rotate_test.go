@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRotateHandler(t *testing.T) {
+	tests := []struct {
+		description         string
+		target              string
+		headers             []string
+		expectedStatus      int
+		expectedContentType string
+		expectSet           bool
+	}{
+		{
+			description:    "unauthenticated",
+			target:         "/rotate",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:         "kid",
+			target:              "/rotate",
+			headers:             []string{testAdminAuth},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+		},
+		{
+			description:         "set",
+			target:              "/rotate?" + ReturnParameter + "=" + ReturnSet,
+			headers:             []string{testAdminAuth},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/jwk-set+json",
+			expectSet:           true,
+		},
+		{
+			description:    "unsupported return",
+			target:         "/rotate?" + ReturnParameter + "=everything",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				s  *http.Server
+				ka *KeyAccessor
+			)
+
+			startTestApp(t, []string{"--admin-token=" + testAdminToken}, &s, &ka)
+			previous, err := ka.Load()
+			require.NoError(t, err)
+
+			response := serve(s.Handler, http.MethodPost, tc.target, nil, tc.headers...)
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+
+			current, err := ka.Load()
+			require.NoError(t, err)
+			if tc.expectedStatus != http.StatusOK {
+				assert.Equal(previous.KID, current.KID)
+				return
+			}
+
+			assert.NotEqual(previous.KID, current.KID)
+			assert.Equal(tc.expectedContentType, response.Header().Get("Content-Type"))
+			if !tc.expectSet {
+				var rr RotateResponse
+				require.NoError(t, json.Unmarshal(response.Body.Bytes(), &rr))
+				assert.Equal(current.KID, rr.KID)
+				return
+			}
+
+			set, err := jwk.Parse(response.Body.Bytes())
+			require.NoError(t, err)
+			_, ok := set.LookupKeyID(current.KID)
+			assert.True(ok)
+			_, ok = set.LookupKeyID(previous.KID)
+			assert.True(ok)
+		})
+	}
+}
+
+// rotatedSetKeyStore is a KeyStore that never returns stored keys from LoadAll,
+// like an eventually consistent store.
+type rotatedSetKeyStore struct {
+	KeyStore
+}
+
+func (rotatedSetKeyStore) LoadAll() ([]Key, error) {
+	return nil, nil
+}
+
+func TestRotateHandlerRotatedSet(t *testing.T) {
+	current, err := newTestKey(t).PublicKey()
+	require.NoError(t, err)
+
+	rh := NewRotateHandler(zap.NewNop(), nil, rotatedSetKeyStore{KeyStore: NewInMemoryKeyStore()})
+	set, err := rh.rotatedSet(current)
+	require.NoError(t, err)
+	assert.Equal(t, 1, set.Len())
+
+	_, ok := set.LookupKeyID(current.KID)
+	assert.True(t, ok)
+}
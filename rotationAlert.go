@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// rotationAlertInterval is how often a RotationAlerter checks for an overdue rotation.
+	rotationAlertInterval = time.Minute
+
+	// rotationAlertTimeout is the deadline for delivering an alert to a single webhook.
+	rotationAlertTimeout = 5 * time.Second
+)
+
+// RotationOverdueAlert is the body POSTed to webhooks when key rotation is overdue.
+type RotationOverdueAlert struct {
+	LastRotation time.Time `json:"last_rotation"`
+	CurrentKID   string    `json:"current_kid"`
+}
+
+// RotationAlerterIn defines the dependencies necessary to create a RotationAlerter.
+type RotationAlerterIn struct {
+	fx.In
+
+	Logger      *zap.Logger
+	Rotator     *Rotator
+	KeyAccessor *KeyAccessor
+	CLI         CLI
+	Lifecycle   fx.Lifecycle
+	Registerer  prometheus.Registerer
+}
+
+// RotationAlerter POSTs an alert to webhooks when key rotation becomes overdue, which
+// indicates that the Rotator is stuck. An alert fires once per overdue period, and then
+// not again until a rotation succeeds and rotation later becomes overdue again.
+type RotationAlerter struct {
+	logger      *zap.Logger
+	rotator     *Rotator
+	keyAccessor *KeyAccessor
+	targets     []string
+	client      *http.Client
+	alerts      prometheus.Counter
+
+	// alerted indicates whether an alert has fired for the current overdue period.
+	lock    sync.Mutex
+	alerted bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewRotationAlerter(in RotationAlerterIn) (ra *RotationAlerter, err error) {
+	ra = &RotationAlerter{
+		logger:      in.Logger,
+		rotator:     in.Rotator,
+		keyAccessor: in.KeyAccessor,
+		targets:     in.CLI.RotationOverdueWebhook,
+		client: &http.Client{
+			Timeout: rotationAlertTimeout,
+		},
+		alerts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "key_rotation_overdue_alerts_total",
+			Help:      "the number of times an overdue key rotation alert has fired",
+		}),
+	}
+
+	if err = in.Registerer.Register(ra.alerts); err == nil {
+		ra.logger.Info("rotation alerter",
+			zap.Strings("targets", ra.targets),
+		)
+
+		in.Lifecycle.Append(
+			fx.StartStopHook(ra.Start, ra.Stop),
+		)
+	}
+
+	return
+}
+
+// Check fires an alert if rotation is overdue and no alert has fired since rotation
+// became overdue, returning whether an alert fired. Once rotation is no longer
+// overdue, the next overdue period fires a new alert.
+func (ra *RotationAlerter) Check() (fired bool) {
+	defer ra.lock.Unlock()
+	ra.lock.Lock()
+
+	switch overdue := ra.rotator.Overdue(); {
+	case !overdue:
+		ra.alerted = false
+
+	case !ra.alerted:
+		ra.alerted, fired = true, true
+		ra.alerts.Inc()
+		ra.alert()
+	}
+
+	return
+}
+
+// alert delivers a RotationOverdueAlert to every webhook.
+func (ra *RotationAlerter) alert() {
+	alert := RotationOverdueAlert{
+		LastRotation: ra.rotator.LastRotation().UTC(),
+	}
+
+	if current, err := ra.keyAccessor.Load(); err == nil {
+		alert.CurrentKID = current.KID
+	}
+
+	ra.logger.Error("key rotation is overdue",
+		zap.Time("lastRotation", alert.LastRotation),
+		zap.String("currentKID", alert.CurrentKID),
+	)
+
+	body, _ := json.Marshal(alert)
+	for _, target := range ra.targets {
+		if err := ra.deliver(target, body); err != nil {
+			ra.logger.Error("unable to deliver rotation alert", zap.String("target", target), zap.Error(err))
+		}
+	}
+}
+
+// deliver POSTs an alert to a single webhook.
+func (ra *RotationAlerter) deliver(target string, body []byte) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rotationAlertTimeout)
+	defer cancel()
+
+	var (
+		request  *http.Request
+		response *http.Response
+	)
+
+	request, err = http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err == nil {
+		request.Header.Set("Content-Type", "application/json")
+		response, err = ra.client.Do(request)
+	}
+
+	if err == nil {
+		response.Body.Close()
+		if response.StatusCode >= 300 {
+			err = fmt.Errorf("the webhook responded with %d", response.StatusCode)
+		}
+	}
+
+	return
+}
+
+// Start begins checking for overdue rotations in the background. When no webhooks
+// are configured, this method does nothing.
+func (ra *RotationAlerter) Start() error {
+	if len(ra.targets) == 0 {
+		return nil
+	}
+
+	var ctx context.Context
+	ctx, ra.cancel = context.WithCancel(context.Background())
+	ra.done = make(chan struct{})
+	go func() {
+		defer close(ra.done)
+		ticker := time.NewTicker(rotationAlertInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+				ra.Check()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops checking for overdue rotations.
+func (ra *RotationAlerter) Stop() error {
+	if ra.cancel != nil {
+		ra.cancel()
+		<-ra.done
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx/fxtest"
+	"go.uber.org/zap"
+)
+
+// newTestAlertReceiver starts a webhook that responds with the given status code and
+// sends each alert it receives to the returned channel.
+func newTestAlertReceiver(t *testing.T, statusCode int) (*httptest.Server, <-chan RotationOverdueAlert) {
+	received := make(chan RotationOverdueAlert, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		var alert RotationOverdueAlert
+		if assert.Equal(t, "application/json", request.Header.Get("Content-Type")) &&
+			assert.NoError(t, json.NewDecoder(request.Body).Decode(&alert)) {
+			received <- alert
+		}
+
+		response.WriteHeader(statusCode)
+	}))
+
+	t.Cleanup(receiver.Close)
+	return receiver, received
+}
+
+func TestRotationAlerterCheck(t *testing.T) {
+	type step struct {
+		// advance is how far the clock moves before this step.
+		advance time.Duration
+
+		// rotate indicates whether the keys rotate before checking.
+		rotate        bool
+		expectedFired bool
+	}
+
+	tests := []struct {
+		description string
+		args        []string
+		notRotated  bool
+		steps       []step
+	}{
+		{
+			description: "never rotated",
+			notRotated:  true,
+			steps:       []step{{advance: 100 * time.Hour}},
+		},
+		{
+			description: "on schedule",
+			steps:       []step{{advance: time.Hour}, {advance: 47 * time.Hour}},
+		},
+		{
+			description: "fires once per overdue period",
+			steps: []step{
+				{advance: 49 * time.Hour, expectedFired: true},
+				{advance: time.Minute},
+				{advance: 24 * time.Hour},
+			},
+		},
+		{
+			description: "fires again after recovering",
+			steps: []step{
+				{advance: 49 * time.Hour, expectedFired: true},
+				{rotate: true},
+				{advance: 49 * time.Hour, expectedFired: true},
+			},
+		},
+		{
+			description: "overdue detection disabled",
+			args:        []string{"--rotation-overdue-factor=0"},
+			steps:       []step{{advance: 100 * time.Hour}},
+		},
+		{
+			description: "unreachable webhook still counts",
+			args:        []string{"--rotation-overdue-webhook=" + unreachableTarget(t)},
+			steps:       []step{{advance: 49 * time.Hour, expectedFired: true}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			receiver, received := newTestAlertReceiver(t, http.StatusNoContent)
+			args := append([]string{"--rotation-overdue-webhook=" + receiver.URL}, tc.args...)
+			r, ka := newTestRotator(t, NewInMemoryKeyStore(), args...)
+
+			now := time.Now()
+			r.now = func() time.Time { return now }
+			if !tc.notRotated {
+				_, err := r.Rotate()
+				require.NoError(t, err)
+			}
+
+			registry := prometheus.NewRegistry()
+			ra, err := NewRotationAlerter(RotationAlerterIn{
+				Logger:      zap.NewNop(),
+				Rotator:     r,
+				KeyAccessor: ka,
+				CLI:         newTestCLI(t, args...),
+				Lifecycle:   fxtest.NewLifecycle(t),
+				Registerer:  registry,
+			})
+
+			require.NoError(t, err)
+
+			fired := 0
+			for i, s := range tc.steps {
+				now = now.Add(s.advance)
+				if s.rotate {
+					_, err := r.Rotate()
+					require.NoError(t, err)
+				}
+
+				require.Equal(t, s.expectedFired, ra.Check(), "step %d", i)
+				if !s.expectedFired {
+					continue
+				}
+
+				fired++
+				select {
+				case alert := <-received:
+					current, err := ka.Load()
+					require.NoError(t, err)
+					assert.Equal(current.KID, alert.CurrentKID)
+					assert.True(r.LastRotation().Equal(alert.LastRotation))
+
+				case <-time.After(time.Second):
+					assert.Fail("no alert was received", "step %d", i)
+				}
+			}
+
+			assert.Empty(received)
+			assert.Equal(float64(fired), gatherMetric(t, registry, "key_rotation_overdue_alerts_total").GetMetric()[0].GetCounter().GetValue())
+		})
+	}
+}
@@ -7,20 +7,152 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+const (
+	// adoptRetryInterval is how long a replica that lost the rotation lock first waits
+	// before checking again for the lock holder's new current key. The wait doubles
+	// after each check, up to maxAdoptRetryInterval.
+	adoptRetryInterval    = 100 * time.Millisecond
+	maxAdoptRetryInterval = time.Minute
+)
+
 var (
 	// ErrRotatorStarted is returned by Rotator.Start to indicate that Start has already been called.
 	ErrRotatorStarted = errors.New("the key rotator has already been started")
 
 	// ErrRotatorStopped is returned by Rotator.Stop to indicate that Stop has already been called.
 	ErrRotatorStopped = errors.New("the key rotator has already been stopped")
+
+	// ErrRotationSkipped indicates that a scheduled rotation was skipped because
+	// another replica holds the rotation lock.
+	ErrRotationSkipped = errors.New("another replica holds the rotation lock")
+
+	// ErrCurrentKey is returned by Rotator.Delete to indicate that the key is a current key.
+	ErrCurrentKey = errors.New("a current key cannot be deleted")
+
+	// ErrKeyInGrace is returned by Rotator.Delete to indicate that the key stopped being
+	// a current key too recently, so tokens it signed may still be in flight.
+	ErrKeyInGrace = errors.New("the key was demoted too recently to be deleted")
 )
 
+// AdditionalKey is a current key, beyond the primary signing key, that a Rotator
+// rotates alongside the primary key.
+type AdditionalKey struct {
+	KeyGenerator *KeyGenerator
+	KeyAccessor  *KeyAccessor
+}
+
+// MultiSignKeys are the additional current keys that cosign /sign payloads.
+type MultiSignKeys []AdditionalKey
+
+// NewMultiSignKeys creates an AdditionalKey for each configured multi-sign key type.
+func NewMultiSignKeys(l *zap.Logger, idGenerator *IDGenerator, random *RandomSource, cli CLI) (msk MultiSignKeys, err error) {
+	msk = make(MultiSignKeys, 0, len(cli.MultiSign))
+	for i := 0; err == nil && i < len(cli.MultiSign); i++ {
+		var kg *KeyGenerator
+		kg, err = newKeyGenerator(l, idGenerator, random, cli, cli.MultiSign[i])
+		if err == nil {
+			msk = append(msk, AdditionalKey{
+				KeyGenerator: kg,
+				KeyAccessor:  new(KeyAccessor),
+			})
+		}
+	}
+
+	return
+}
+
+// SignKey is the additional current key that signs /sign payloads in place of
+// the primary signing key, which allows /sign to use a different key type than /issue.
+type SignKey AdditionalKey
+
+// NewSignKey creates the SignKey for the configured sign key type. If no sign key
+// type is configured, this function returns a nil SignKey.
+func NewSignKey(l *zap.Logger, idGenerator *IDGenerator, random *RandomSource, cli CLI) (sk *SignKey, err error) {
+	if len(cli.SignKeyType) == 0 {
+		return
+	}
+
+	var kg *KeyGenerator
+	kg, err = newKeyGenerator(l, idGenerator, random, cli, cli.SignKeyType)
+	if err == nil {
+		sk = &SignKey{
+			KeyGenerator: kg,
+			KeyAccessor:  new(KeyAccessor),
+		}
+	}
+
+	return
+}
+
+// EncryptionKey is the additional current key that clients use to encrypt content
+// for this server. It is published with a use of enc, and never signs anything.
+type EncryptionKey AdditionalKey
+
+// NewEncryptionKey creates the EncryptionKey for the configured encryption key type.
+// If no encryption key type is configured, this function returns a nil EncryptionKey.
+func NewEncryptionKey(l *zap.Logger, idGenerator *IDGenerator, random *RandomSource, cli CLI) (ek *EncryptionKey, err error) {
+	if len(cli.EncryptionKeyType) == 0 {
+		return
+	}
+
+	var kg *KeyGenerator
+	kg, err = newEncryptionKeyGenerator(l, idGenerator, random, cli, cli.EncryptionKeyType)
+	if err == nil {
+		ek = &EncryptionKey{
+			KeyGenerator: kg,
+			KeyAccessor:  new(KeyAccessor),
+		}
+	}
+
+	return
+}
+
+// IssueKeys are the additional current keys that /issue signs with when a request
+// selects their key type, which allows clients to opt into a key type during a migration.
+type IssueKeys []AdditionalKey
+
+// NewIssueKeys creates an AdditionalKey for each configured issue key type other than
+// the primary key type, which is always available.
+func NewIssueKeys(l *zap.Logger, idGenerator *IDGenerator, random *RandomSource, cli CLI) (ik IssueKeys, err error) {
+	ik = make(IssueKeys, 0, len(cli.IssueKeyTypes))
+	for i := 0; err == nil && i < len(cli.IssueKeyTypes); i++ {
+		if cli.IssueKeyTypes[i] == cli.KeyType {
+			continue
+		}
+
+		var kg *KeyGenerator
+		kg, err = newKeyGenerator(l, idGenerator, random, cli, cli.IssueKeyTypes[i])
+		if err == nil {
+			ik = append(ik, AdditionalKey{
+				KeyGenerator: kg,
+				KeyAccessor:  new(KeyAccessor),
+			})
+		}
+	}
+
+	return
+}
+
+// Lookup returns the issue key for the given key type.
+func (ik IssueKeys) Lookup(keyType string) (AdditionalKey, bool) {
+	for _, ak := range ik {
+		if ak.KeyGenerator.keyType == keyType {
+			return ak, true
+		}
+	}
+
+	return AdditionalKey{}, false
+}
+
 // RotatorIn defines the dependencies necessary to create a Rotator.
 type RotatorIn struct {
 	fx.In
@@ -29,15 +161,24 @@ type RotatorIn struct {
 	KeyGenerator *KeyGenerator
 	KeyAccessor  *KeyAccessor
 	KeyStore     KeyStore
+	Issuer       *Issuer
 	CLI          CLI
 	Lifecycle    fx.Lifecycle
+	Registerer   prometheus.Registerer
+
+	MultiSignKeys MultiSignKeys    `optional:"true"`
+	SignKey       *SignKey         `optional:"true"`
+	IssueKeys     IssueKeys        `optional:"true"`
+	EncryptionKey *EncryptionKey   `optional:"true"`
+	Publisher     *KeySetPublisher `optional:"true"`
 }
 
 // Rotator manages a set of background processes for key rotation.
 //
 // The current key in a Keys is rotated according to the configured
-// rotation interval. The previous current key will be deleted after
-// the token expire time plus a grace period elapses.
+// rotation interval. A previous current key may only be deleted, via Delete,
+// after the longest token lifetime plus a grace period elapses. Expired keys
+// are purged after each scheduled rotation, or on demand via Purge.
 //
 // Rotated keys will expire based on not only the rotation period but
 // also the token expires.  The basic formula for a key's expire is
@@ -49,39 +190,208 @@ type Rotator struct {
 	keyAccessor  *KeyAccessor
 	keyStore     KeyStore
 	rotate       time.Duration
+	poolSize     int
+	workers      int
+	now          func() time.Time
+	additional   []AdditionalKey
+
+	// keepKID indicates whether rotation swaps the key material of the current
+	// keys while keeping their kids.
+	keepKID bool
+
+	// lastRotation is the time, in unix nanoseconds, that the current pool was
+	// created. This is atomic so that health checks never wait on a rotation.
+	lastRotation atomic.Int64
+
+	// activationDelay is how long a rotated pool of signing keys is published before it
+	// becomes current. While pending, its keys verify tokens but sign nothing, so that
+	// verifiers across a fleet can fetch them before they are used.
+	activationDelay time.Duration
+
+	// pending is the rotated pool of signing keys awaiting activation at the activation
+	// time, and promotion is the timer that promotes it.
+	pending    []Key
+	activation time.Time
+	promotion  *time.Timer
+
+	// rotationLocker, when set, coordinates scheduled rotations with other replicas
+	// that share the KeyStore. adoptRetry is how long a replica that lost the lock
+	// first waits before checking again for the lock holder's new current key.
+	rotationLocker RotationLocker
+	adoptRetry     time.Duration
+
+	// overdueFactor is the multiple of the rotation interval after which a
+	// rotation is considered overdue.
+	overdueFactor float64
+
+	// currentKeyStore is set when the current key should be persisted, either to be
+	// reused across restarts or to be adopted by replicas that lose the rotation lock.
+	currentKeyStore CurrentKeyStore
+
+	// reuseCurrentKey indicates whether a persisted current key is reused on startup.
+	reuseCurrentKey bool
+
+	// publisher, when set, pushes the key set to webhooks after each rotation.
+	// publishes signals the publishing goroutine, which runs between Start and Stop,
+	// and publishDone is closed when that goroutine exits.
+	publisher   *KeySetPublisher
+	publishes   chan struct{}
+	publishDone chan struct{}
+
+	// demoted holds the times at which keys stopped being current keys. A demoted
+	// key cannot be deleted until demotionGrace has elapsed, since tokens it signed
+	// just before its demotion may still be in flight.
+	demoted       map[string]time.Time
+	demotionGrace time.Duration
+
+	// recoverLock serializes Recover, so that concurrent signers that find the same
+	// current key missing cause only one rotation.
+	recoverLock sync.Mutex
 
 	lock   sync.Mutex
 	ctx    context.Context
 	cancel context.CancelFunc
+	done   chan struct{}
 }
 
-func NewRotator(in RotatorIn) (r *Rotator) {
+func NewRotator(in RotatorIn) (r *Rotator, err error) {
 	r = &Rotator{
-		logger:       in.Logger,
-		keyGenerator: in.KeyGenerator,
-		keyAccessor:  in.KeyAccessor,
-		keyStore:     in.KeyStore,
-		rotate:       in.CLI.KeyRotate,
+		logger:          in.Logger,
+		keyGenerator:    in.KeyGenerator,
+		keyAccessor:     in.KeyAccessor,
+		keyStore:        in.KeyStore,
+		rotate:          in.CLI.KeyRotate,
+		poolSize:        in.CLI.SigningPoolSize,
+		workers:         max(in.CLI.KeyGenerationWorkers, 1),
+		keepKID:         in.CLI.RekeyKeepKID,
+		activationDelay: in.CLI.KeyActivationDelay,
+		overdueFactor:   in.CLI.RotationOverdueFactor,
+		demoted:         make(map[string]time.Time),
+		publisher:       in.Publisher,
+		demotionGrace:   in.Issuer.MaxLifetime() + time.Minute,
+		adoptRetry:      adoptRetryInterval,
+		now:             time.Now,
+	}
+
+	r.additional = append(r.additional, in.MultiSignKeys...)
+	if in.SignKey != nil {
+		r.additional = append(r.additional, AdditionalKey(*in.SignKey))
+	}
+
+	r.additional = append(r.additional, in.IssueKeys...)
+	if in.EncryptionKey != nil {
+		r.additional = append(r.additional, AdditionalKey(*in.EncryptionKey))
+	}
+
+	if in.CLI.ReuseCurrentKey || in.CLI.RotationLock {
+		var ok bool
+		if r.currentKeyStore, ok = keyStoreAs[CurrentKeyStore](in.KeyStore); !ok {
+			r.logger.Warn("the key store cannot persist the current key, so it will be neither reused on startup nor shared with other replicas")
+		}
+
+		r.reuseCurrentKey = ok && in.CLI.ReuseCurrentKey
+	}
+
+	if in.CLI.RotationLock {
+		var ok bool
+		if r.rotationLocker, ok = keyStoreAs[RotationLocker](in.KeyStore); !ok {
+			r.logger.Warn("the key store cannot lock rotations, so every replica will rotate keys")
+		}
 	}
 
 	r.logger.Info("rotator",
 		zap.Duration("rotate", r.rotate),
+		zap.Int("poolSize", r.poolSize),
+		zap.Int("workers", r.workers),
+		zap.Bool("keepKID", r.keepKID),
+		zap.Duration("activationDelay", r.activationDelay),
+		zap.Int("additional", len(r.additional)),
+		zap.Bool("reuseCurrentKey", r.reuseCurrentKey),
+		zap.Float64("overdueFactor", r.overdueFactor),
+		zap.Bool("rotationLock", r.rotationLocker != nil),
+		zap.Duration("demotionGrace", r.demotionGrace),
 	)
 
+	err = r.registerMetrics(in.Registerer)
+	if err != nil {
+		return
+	}
+
+	// Stop is registered separately, so that the rotator can be stopped
+	// before the server begins draining. See ProvideServer.
 	in.Lifecycle.Append(
-		fx.StartStopHook(
-			r.Start,
-			r.Stop,
-		),
+		fx.StartHook(r.Start),
 	)
 
 	return
 }
 
+// registerMetrics registers the rotation health metrics, which are computed
+// whenever they are gathered.
+func (r *Rotator) registerMetrics(registerer prometheus.Registerer) (err error) {
+	err = registerer.Register(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "key_last_rotation_timestamp_seconds",
+			Help:      "the time, in seconds since the epoch, that the current signing keys were created",
+		},
+		func() float64 {
+			if last := r.LastRotation(); !last.IsZero() {
+				return float64(last.UnixNano()) / float64(time.Second)
+			}
+
+			return 0
+		},
+	))
+
+	if err == nil {
+		err = registerer.Register(prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace: MetricsNamespace,
+				Name:      "key_rotation_overdue",
+				Help:      "1 if the last successful key rotation is older than the rotation interval times the overdue factor, 0 otherwise",
+			},
+			func() float64 {
+				if r.Overdue() {
+					return 1
+				}
+
+				return 0
+			},
+		))
+	}
+
+	return
+}
+
+// LastRotation returns the time that the current pool of signing keys was created.
+// Before the first rotation, this method returns the zero time.
+func (r *Rotator) LastRotation() time.Time {
+	if nanos := r.lastRotation.Load(); nanos != 0 {
+		return time.Unix(0, nanos)
+	}
+
+	return time.Time{}
+}
+
+// Overdue tests if the last successful rotation is older than the rotation interval
+// times the overdue factor. A Rotator that has not yet rotated is never overdue.
+func (r *Rotator) Overdue() bool {
+	last := r.LastRotation()
+	if last.IsZero() || r.overdueFactor <= 0 {
+		return false
+	}
+
+	threshold := time.Duration(float64(r.rotate) * r.overdueFactor)
+	return r.now().Sub(last) > threshold
+}
+
 // unsafeStoreKey handles storing a key in the KeyStore and then, if
-// no error occurred, updating the CurrentKey. This method is not atomic,
+// no error occurred, updating the given KeyAccessor. This method is not atomic,
 // and must be executed under the lock.
-func (r *Rotator) unsafeStoreKey(k Key) (err error) {
+//
+// The primary signing keys are stored with unsafeStorePool instead.
+func (r *Rotator) unsafeStoreKey(ka *KeyAccessor, k Key) (err error) {
 	var pk Key
 	pk, err = k.PublicKey()
 	if err == nil {
@@ -91,21 +401,536 @@ func (r *Rotator) unsafeStoreKey(k Key) (err error) {
 
 	if err == nil {
 		// stash the private key in our access point
-		r.keyAccessor.Store(k)
+		previous, _ := ka.LoadAll()
+		ka.Store(k)
+		r.unsafeDemote(previous, k)
+	}
+
+	return
+}
+
+// loadReusableKey attempts to load a persisted current key that has not yet reached
+// its rotation time. If no such key exists, this method returns false.
+func (r *Rotator) loadReusableKey() (k Key, ok bool) {
+	if !r.reuseCurrentKey {
+		return
+	}
+
+	var (
+		err     error
+		private bool
+	)
+
+	k, err = r.currentKeyStore.LoadCurrent()
+	if err == nil {
+		private, err = jwk.IsPrivateKey(k.Key)
+	}
+
+	switch {
+	case err != nil:
+		r.logger.Info("no reusable current key", zap.Error(err))
+
+	case !private:
+		r.logger.Warn("the persisted current key has no private material", KeyField("key", k))
+
+	case !r.now().Before(k.Created.Add(r.rotate)):
+		r.logger.Info("the persisted current key is due for rotation", KeyField("key", k))
+
+	default:
+		ok = true
+	}
+
+	return
+}
+
+// generatePool generates a full pool of signing keys, using up to the configured
+// number of workers.
+func (r *Rotator) generatePool() (pool []Key, err error) {
+	workers := min(r.workers, r.poolSize)
+	if workers == 1 {
+		pool = make([]Key, 0, r.poolSize)
+		for i := 0; err == nil && i < r.poolSize; i++ {
+			var k Key
+			if k, err = r.keyGenerator.Generate(); err == nil {
+				pool = append(pool, k)
+			}
+		}
+
+		return
+	}
+
+	// each worker fills distinct slots, so the pool needs no lock
+	var (
+		wg   sync.WaitGroup
+		next = make(chan int, r.poolSize)
+		errs = make([]error, r.poolSize)
+	)
+
+	pool = make([]Key, r.poolSize)
+	for i := range r.poolSize {
+		next <- i
+	}
+
+	close(next)
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				pool[i], errs[i] = r.keyGenerator.Generate()
+			}
+		}()
+	}
+
+	wg.Wait()
+	if err = errors.Join(errs...); err != nil {
+		pool = nil
+	}
+
+	return
+}
+
+// unsafeStorePool stores each key in the pool in the KeyStore and then, if no error
+// occurred, replaces the current pool of signing keys. This method must be executed
+// under the lock.
+func (r *Rotator) unsafeStorePool(pool []Key) (err error) {
+	err = r.unsafePublishPool(pool)
+	if err == nil {
+		err = r.unsafeActivatePool(pool)
+	}
+
+	return
+}
+
+// unsafePublishPool stores the public portion of each key in the pool in the KeyStore.
+// This method must be executed under the lock.
+func (r *Rotator) unsafePublishPool(pool []Key) (err error) {
+	for i := 0; err == nil && i < len(pool); i++ {
+		var pk Key
+		if pk, err = pool[i].PublicKey(); err == nil {
+			err = r.keyStore.Store(pk)
+		}
+	}
+
+	return
+}
+
+// unsafeActivatePool replaces the current pool of signing keys with an already published
+// pool. This method must be executed under the lock.
+func (r *Rotator) unsafeActivatePool(pool []Key) (err error) {
+	if r.currentKeyStore != nil {
+		err = r.currentKeyStore.StoreCurrent(pool[0])
+	}
+
+	if err == nil {
+		previous, _ := r.keyAccessor.LoadAll()
+		r.keyAccessor.StoreAll(pool...)
+		r.lastRotation.Store(r.now().UnixNano())
+		r.unsafeDemote(previous, pool...)
+	}
+
+	return
+}
+
+// unsafeStagePool publishes the pool and holds it as the pending pool until the
+// activation delay elapses. A pool that is still pending is replaced, and its keys
+// stay published until they expire. This method must be executed under the lock.
+func (r *Rotator) unsafeStagePool(pool []Key) (err error) {
+	err = r.unsafePublishPool(pool)
+	if err == nil {
+		if len(r.pending) > 0 {
+			r.logger.Warn("replacing pending keys that were never activated", KeyField("key", r.pending[0]))
+		}
+
+		r.pending = pool
+		r.activation = r.now().Add(r.activationDelay)
+		r.unsafeSchedulePromotion()
+		r.logger.Info("published pending keys", KeyField("key", pool[0]), zap.Time("activation", r.activation))
+	}
+
+	return
+}
+
+// unsafeSchedulePromotion arms the timer that promotes the pending pool at the activation
+// time. This method must be executed under the lock.
+func (r *Rotator) unsafeSchedulePromotion() {
+	if r.promotion != nil {
+		r.promotion.Stop()
+	}
+
+	r.promotion = time.AfterFunc(r.activation.Sub(r.now()), func() {
+		if _, err := r.Promote(); err != nil {
+			r.logger.Error("unable to promote pending keys", zap.Error(err))
+		}
+	})
+}
+
+// Promote makes the pending pool of signing keys current if its activation time has
+// arrived, returning whether it did. Pending keys are promoted automatically, so
+// this method only needs to be called to promote them without waiting on the timer.
+// If the activation time hasn't arrived, the promotion is rescheduled.
+func (r *Rotator) Promote() (promoted bool, err error) {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	switch {
+	case len(r.pending) == 0:
+		// nothing to promote
+
+	case r.now().Before(r.activation):
+		r.unsafeSchedulePromotion()
+
+	default:
+		if err = r.unsafeActivatePool(r.pending); err == nil {
+			r.logger.Info("promoted pending keys", KeyField("key", r.pending[0]))
+			r.pending, promoted = nil, true
+		}
+	}
+
+	return
+}
+
+// unsafeDemote records the demotion of each previous key that is not among the
+// current keys. Demotions older than the grace period are forgotten, since those
+// keys may already be deleted. This method must be executed under the lock.
+func (r *Rotator) unsafeDemote(previous []Key, current ...Key) {
+	now := r.now()
+	for kid, demoted := range r.demoted {
+		if !now.Before(demoted.Add(r.demotionGrace)) {
+			delete(r.demoted, kid)
+		}
+	}
+
+	for _, p := range previous {
+		if !containsKID(current, p.KID) {
+			r.demoted[p.KID] = now
+		}
+	}
+}
+
+// containsKID tests if any of the given keys has the given kid.
+func containsKID(keys []Key, kid string) bool {
+	for _, k := range keys {
+		if k.KID == kid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unsafeIsCurrent tests if the given kid is any current key, either primary or
+// additional. Pending keys count as current keys, since they are about to become
+// current. This method must be executed under the lock.
+func (r *Rotator) unsafeIsCurrent(kid string) bool {
+	if _, ok := r.keyAccessor.Lookup(kid); ok {
+		return true
+	}
+
+	if containsKID(r.pending, kid) {
+		return true
+	}
+
+	for _, a := range r.additional {
+		if _, ok := a.KeyAccessor.Lookup(kid); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Delete removes a key from the KeyStore. Deletion is coordinated with rotation,
+// so a current key can never be deleted, and neither can a key that was demoted by
+// a rotation less than the longest token lifetime plus a grace period ago. Tokens
+// signed by such a key may still be in flight.
+func (r *Rotator) Delete(kid string) error {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+	return r.unsafeDelete(kid)
+}
+
+// unsafeDelete implements Delete. This method must be executed under the lock.
+func (r *Rotator) unsafeDelete(kid string) (err error) {
+	demoted, wasDemoted := r.demoted[kid]
+	switch {
+	case r.unsafeIsCurrent(kid):
+		err = ErrCurrentKey
+
+	case wasDemoted && r.now().Before(demoted.Add(r.demotionGrace)):
+		err = ErrKeyInGrace
+
+	default:
+		err = r.keyStore.Delete(kid)
+		delete(r.demoted, kid)
 	}
 
 	return
 }
 
-// Rotate generates a new key, updates the KeyStore, and then updates the CurrentKey.
-// This method returns the new current key. If this method returns any error, the key
-// was not rotated.
+// unsafeRotateAdditional generates and stores a new key for each additional key.
+// When kids are kept, each new key takes the kid of the key it replaces. This
+// method must be executed under the lock.
+func (r *Rotator) unsafeRotateAdditional() (err error) {
+	for i := 0; err == nil && i < len(r.additional); i++ {
+		var k Key
+		k, err = r.additional[i].KeyGenerator.Generate()
+		if current, loadErr := r.additional[i].KeyAccessor.Load(); err == nil && r.keepKID && loadErr == nil {
+			err = r.additional[i].KeyGenerator.SetKID(&k, current.KID)
+		}
+
+		if err == nil {
+			err = r.unsafeStoreKey(r.additional[i].KeyAccessor, k)
+		}
+
+		if err == nil {
+			r.logger.Info("rotated additional key", KeyField("key", k))
+		}
+	}
+
+	return
+}
+
+// unsafeKeepKIDs assigns the kids of the current pool to the corresponding keys of the
+// given pool, so that a rotation re-keys the current kids. This method must be executed
+// under the lock.
+func (r *Rotator) unsafeKeepKIDs(pool []Key) (err error) {
+	current, _ := r.keyAccessor.LoadAll()
+	for i := 0; err == nil && i < min(len(current), len(pool)); i++ {
+		err = r.keyGenerator.SetKID(&pool[i], current[i].KID)
+	}
+
+	return
+}
+
+// Rotate generates a new pool of keys, updates the KeyStore, and then updates the CurrentKey.
+// Any additional keys are rotated as well. This method returns the new current key.
+// If this method returns any error, the primary key was not rotated.
+//
+// When an activation delay is configured, the new pool is published but stays pending
+// until the delay elapses, and the returned key is the first pending key. Additional
+// keys are never pending.
+//
+// When kids are kept, the new keys replace the key material of the current kids. The
+// published JWK for each kid changes in place, so a verifier that cached the old JWK
+// rejects new tokens until it refreshes, and tokens signed with the old material no
+// longer verify once the verifier does refresh.
 func (r *Rotator) Rotate() (k Key, err error) {
-	k, err = r.keyGenerator.Generate()
+	return r.rotatePrimary(r.activationDelay > 0)
+}
+
+// rotatePrimary implements Rotate. When staged is set, the new pool becomes pending rather
+// than current.
+func (r *Rotator) rotatePrimary(staged bool) (k Key, err error) {
+	var pool []Key
+	pool, err = r.generatePool()
 	if err == nil {
 		defer r.lock.Unlock()
 		r.lock.Lock()
-		err = r.unsafeStoreKey(k)
+		if r.keepKID {
+			err = r.unsafeKeepKIDs(pool)
+		}
+	}
+
+	switch {
+	case err != nil:
+		// the pool was not generated
+
+	case staged:
+		err = r.unsafeStagePool(pool)
+
+	default:
+		err = r.unsafeStorePool(pool)
+	}
+
+	if err == nil {
+		k = pool[0]
+	}
+
+	if err == nil {
+		if additionalErr := r.unsafeRotateAdditional(); additionalErr != nil {
+			r.logger.Error("unable to rotate additional keys", zap.Error(additionalErr))
+		}
+
+		r.publish()
+	}
+
+	return
+}
+
+// Recover rotates the primary keys if the given kid is still a current key. Signers
+// use this when a current key has been deleted from the KeyStore out from under this
+// Rotator, since tokens signed with it could not be verified against the published
+// key set. If the kid is no longer current, e.g. because a concurrent Recover already
+// rotated, this method does nothing.
+func (r *Rotator) Recover(kid string) (err error) {
+	defer r.recoverLock.Unlock()
+	r.recoverLock.Lock()
+
+	r.lock.Lock()
+	current := r.unsafeIsCurrent(kid)
+	r.lock.Unlock()
+
+	if current {
+		// a signer is waiting on this, so the new keys can't wait for activation
+		var k Key
+		if k, err = r.rotatePrimary(false); err == nil {
+			r.logger.Warn("rotated to recover from a missing current key",
+				zap.String("missing", kid),
+				KeyField("key", k),
+			)
+		}
+	}
+
+	return
+}
+
+// publish asynchronously pushes the key set to any webhooks. Requests that arrive
+// while a publish is pending are coalesced, since each publish loads the latest key
+// set. This method must be executed under the lock.
+func (r *Rotator) publish() {
+	if r.publishes != nil {
+		select {
+		case r.publishes <- struct{}{}:
+		default:
+			// a pending publish will include this rotation
+		}
+	}
+}
+
+// runPublisher publishes the key set each time publish is called, one publish at a
+// time, until the given context is canceled.
+func (r *Rotator) runPublisher(ctx context.Context, publishes <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-publishes:
+			r.publisher.Publish(ctx)
+		}
+	}
+}
+
+// scheduledRotate is invoked on each rotation tick. When a rotation lock is in use,
+// only the replica that acquires the lock rotates. Other replicas never rotate while
+// the lock is held. Instead, they wait for the lock holder to persist its new current
+// key, adopt it, and return ErrRotationSkipped. Waiting ends when the given context,
+// which is the rotation task's, is canceled.
+func (r *Rotator) scheduledRotate(ctx context.Context) (k Key, err error) {
+	if r.rotationLocker == nil {
+		return r.Rotate()
+	}
+
+	var acquired bool
+	// the lock expires well before the next tick, so a crashed holder never blocks rotation
+	ttl := r.rotate / 2
+	acquired, err = r.rotationLocker.TryLockRotation(ttl)
+	switch {
+	case err != nil:
+		// fall through and return the lock error
+
+	case acquired:
+		k, err = r.Rotate()
+
+	default:
+		k, err = r.awaitCurrentKey(ctx, ttl)
+	}
+
+	return
+}
+
+// awaitCurrentKey waits, for at most the given lock lifetime, for the replica that holds
+// the rotation lock to persist a newer current key, and adopts that key. The holder may
+// still be generating its key when this replica's tick fires, so the persisted key is
+// checked repeatedly. This method always returns ErrRotationSkipped. If no newer key
+// appears, e.g. because the holder crashed, or the context is canceled, the current key
+// is kept and the next tick tries again.
+func (r *Rotator) awaitCurrentKey(ctx context.Context, ttl time.Duration) (k Key, err error) {
+	err = ErrRotationSkipped
+	if r.currentKeyStore == nil {
+		r.logger.Warn("another replica holds the rotation lock, but there is no persisted current key to adopt")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ttl)
+	defer cancel()
+
+	for retry := r.adoptRetry; ; retry = min(2*retry, maxAdoptRetryInterval) {
+		var adopted bool
+		if k, adopted = r.adoptCurrentKey(); adopted {
+			return
+		}
+
+		timer := time.NewTimer(retry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			r.logger.Warn("another replica holds the rotation lock, but never persisted a newer current key")
+			return
+
+		case <-timer.C:
+		}
+	}
+}
+
+// adoptCurrentKey makes the persisted current key this Rotator's current key, provided
+// that it is newer than this Rotator's current key.
+func (r *Rotator) adoptCurrentKey() (k Key, adopted bool) {
+	if r.currentKeyStore == nil {
+		return
+	}
+
+	current, err := r.currentKeyStore.LoadCurrent()
+	switch {
+	case errors.Is(err, ErrNoCurrentKey):
+		return
+
+	case err != nil:
+		r.logger.Warn("unable to load the persisted current key", zap.Error(err))
+		return
+	}
+
+	defer r.lock.Unlock()
+	r.lock.Lock()
+	previous, _ := r.keyAccessor.LoadAll()
+	if len(previous) > 0 && !current.Created.After(previous[0].Created) {
+		return
+	}
+
+	r.keyAccessor.Store(current)
+	r.lastRotation.Store(current.Created.UnixNano())
+	r.unsafeDemote(previous, current)
+	return current, true
+}
+
+// Purge deletes every expired key that Delete allows to be deleted, i.e. that is
+// neither current nor still within its demotion grace period. This method returns
+// the number of keys deleted.
+func (r *Rotator) Purge() (purged int, err error) {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	var keys []Key
+	keys, err = r.keyStore.LoadAll()
+	now := r.now()
+	for i := 0; err == nil && i < len(keys); i++ {
+		if keys[i].Expires.IsZero() || now.Before(keys[i].Expires) {
+			continue
+		}
+
+		switch deleteErr := r.unsafeDelete(keys[i].KID); {
+		case deleteErr == nil:
+			purged++
+			r.logger.Info("purged expired key", KeyField("key", keys[i]))
+
+		case errors.Is(deleteErr, ErrCurrentKey), errors.Is(deleteErr, ErrKeyInGrace), errors.Is(deleteErr, ErrNoSuchKey):
+			// not eligible, or already deleted
+
+		default:
+			err = deleteErr
+		}
 	}
 
 	return
@@ -115,13 +940,17 @@ func (r *Rotator) Rotate() (k Key, err error) {
 type rotateTask struct {
 	ctx    context.Context
 	logger *zap.Logger
-	rotate func() (Key, error)
+	rotate func(context.Context) (Key, error)
+	purge  func() (int, error)
 	ch     <-chan time.Time
+	reset  func()
 	stop   func()
+	done   chan<- struct{}
 }
 
 // run is a goroutine that rotates keys in the background.
 func (rt rotateTask) run() {
+	defer close(rt.done)
 	defer rt.stop()
 
 	for {
@@ -130,19 +959,39 @@ func (rt rotateTask) run() {
 			return
 
 		case <-rt.ch:
-			if newKey, err := rt.rotate(); err == nil {
+			if rt.ctx.Err() != nil {
+				// a tick raced with Stop
+				return
+			}
+
+			switch newKey, err := rt.rotate(rt.ctx); {
+			case err == nil:
 				rt.logger.Info("rotated key", KeyField("key", newKey))
-			} else {
+
+			case errors.Is(err, ErrRotationSkipped):
+				rt.logger.Info("skipped key rotation", KeyField("current", newKey), zap.Error(err))
+
+			default:
 				rt.logger.Error("unable to rotate key", zap.Error(err))
 			}
 
-			// TODO: handle cleanup
+			if purged, err := rt.purge(); err != nil {
+				rt.logger.Error("unable to purge expired keys", zap.Error(err))
+			} else if purged > 0 {
+				rt.logger.Info("purged expired keys", zap.Int("purged", purged))
+			}
+
+			rt.reset()
 		}
 	}
 }
 
 // Start immediately rotates the current key and then starts a background goroutine to
 // rotate the key on the configured interval. This method is idempotent.
+//
+// If the current key is reused and the persisted current key is still within its
+// rotation interval, that key becomes the current key and the first rotation happens
+// when its interval elapses.
 func (r *Rotator) Start() (err error) {
 	defer r.lock.Unlock()
 	r.lock.Lock()
@@ -152,38 +1001,90 @@ func (r *Rotator) Start() (err error) {
 		err = ErrRotatorStarted
 	}
 
-	var initialKey Key
+	var (
+		initialKey Key
+		reused     bool
+		firstTick  = r.rotate
+	)
+
 	if err == nil {
+		initialKey, reused = r.loadReusableKey()
+	}
+
+	switch {
+	case err != nil:
+		// already started
+
+	case reused:
+		r.keyAccessor.Store(initialKey)
+		r.lastRotation.Store(initialKey.Created.UnixNano())
+		firstTick = initialKey.Created.Add(r.rotate).Sub(r.now())
+
+	default:
 		// immediately rotate the key
-		initialKey, err = r.keyGenerator.Generate()
-		err = r.unsafeStoreKey(initialKey)
+		var pool []Key
+		pool, err = r.generatePool()
+		if err == nil {
+			err = r.unsafeStorePool(pool)
+		}
+
+		if err == nil {
+			initialKey = pool[0]
+		}
 	}
 
 	if err == nil {
-		r.logger.Info("initial key", KeyField("key", initialKey))
-		r.logger.Info("starting key rotation task", zap.Duration("interval", r.rotate))
+		err = r.unsafeRotateAdditional()
+	}
+
+	if err == nil {
+		r.logger.Info("initial key", KeyField("key", initialKey), zap.Bool("reused", reused))
+		r.logger.Info("starting key rotation task", zap.Duration("interval", r.rotate), zap.Duration("firstTick", firstTick))
 		r.ctx, r.cancel = context.WithCancel(context.Background())
-		ticker := time.NewTicker(r.rotate)
+		r.done = make(chan struct{})
+		if r.publisher != nil && r.publisher.Enabled() {
+			r.publishes, r.publishDone = make(chan struct{}, 1), make(chan struct{})
+			go r.runPublisher(r.ctx, r.publishes, r.publishDone)
+		}
+
+		r.publish()
+		ticker := time.NewTicker(firstTick)
 		go rotateTask{
 			ctx:    r.ctx,
 			logger: r.logger,
-			rotate: r.Rotate,
+			rotate: r.scheduledRotate,
+			purge:  r.Purge,
 			ch:     ticker.C,
+			reset:  func() { ticker.Reset(r.rotate) },
 			stop:   ticker.Stop,
+			done:   r.done,
 		}.run()
 	}
 
 	return
 }
 
-// Stop stops all background processes started by this Rotator. This method is idempotent.
+// Stop stops all background processes started by this Rotator. This method waits
+// for any in-progress rotation to finish, and no rotation happens once it returns.
+// This method is idempotent.
 func (r *Rotator) Stop() (err error) {
-	defer r.lock.Unlock()
 	r.lock.Lock()
+	cancel, done, publishDone := r.cancel, r.done, r.publishDone
+	r.ctx, r.cancel, r.done = nil, nil, nil
+	r.publishes, r.publishDone = nil, nil
+	if r.promotion != nil {
+		r.promotion.Stop()
+	}
 
-	if r.cancel != nil {
-		r.cancel()
-		r.ctx, r.cancel = nil, nil
+	r.lock.Unlock()
+
+	if cancel != nil {
+		// the rotation task acquires the lock, so wait for it outside the lock
+		cancel()
+		<-done
+		if publishDone != nil {
+			<-publishDone
+		}
 	} else {
 		err = ErrRotatorStopped
 	}
@@ -194,11 +1095,18 @@ func (r *Rotator) Stop() (err error) {
 func ProvideRotator() fx.Option {
 	return fx.Options(
 		fx.Provide(
+			NewMultiSignKeys,
+			NewSignKey,
+			NewEncryptionKey,
+			NewIssueKeys,
 			NewRotator,
+			NewPurgeHandler,
+			NewRotateHandler,
+			NewRotationAlerter,
 		),
 		fx.Invoke(
-			// ensure the Rotator starts
-			func(*Rotator) {},
+			// ensure the Rotator and its alerter start
+			func(*Rotator, *RotationAlerter) {},
 		),
 	)
 }
@@ -0,0 +1,906 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx/fxtest"
+	"go.uber.org/zap"
+)
+
+func TestRotatorReuseCurrentKey(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// current produces the persisted current key, if any.
+		current        func(*testing.T) Key
+		expectedReused bool
+	}{
+		{
+			description: "fresh current key",
+			args:        []string{"--reuse-current-key"},
+			current: func(t *testing.T) Key {
+				return newTestKey(t)
+			},
+			expectedReused: true,
+		},
+		{
+			description: "reuse disabled",
+			current: func(t *testing.T) Key {
+				return newTestKey(t)
+			},
+		},
+		{
+			description: "no current key",
+			args:        []string{"--reuse-current-key"},
+		},
+		{
+			description: "current key due for rotation",
+			args:        []string{"--reuse-current-key"},
+			current: func(t *testing.T) Key {
+				k := newTestKey(t)
+				k.Created = time.Now().Add(-25 * time.Hour)
+				return k
+			},
+		},
+		{
+			description: "current key without private material",
+			args:        []string{"--reuse-current-key"},
+			current: func(t *testing.T) Key {
+				k, err := newTestKey(t).PublicKey()
+				require.NoError(t, err)
+				return k
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			dir := t.TempDir()
+			var current Key
+			if tc.current != nil {
+				current = tc.current(t)
+				require.NoError(t, NewFileKeyStore(zap.NewNop(), dir).StoreCurrent(current))
+			}
+
+			var ka *KeyAccessor
+			startTestApp(t, append([]string{"--keystore=file", "--keystore-dir=" + dir}, tc.args...), &ka)
+
+			k, err := ka.Load()
+			require.NoError(t, err)
+			if tc.expectedReused {
+				assert.Equal(t, current.KID, k.KID)
+			} else {
+				assert.NotEqual(t, current.KID, k.KID)
+			}
+
+			// the key in use is what a restarted process would reuse
+			if len(tc.args) > 0 {
+				persisted, err := NewFileKeyStore(zap.NewNop(), dir).LoadCurrent()
+				require.NoError(t, err)
+				assert.Equal(t, k.KID, persisted.KID)
+			}
+		})
+	}
+}
+
+func TestRotatorStop(t *testing.T) {
+	tests := []struct {
+		description string
+
+		// stopApp stops the whole app rather than just the Rotator.
+		stopApp bool
+	}{
+		{description: "rotator"},
+		{description: "app", stopApp: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				r  *Rotator
+				ka *KeyAccessor
+			)
+
+			app := startTestApp(t, []string{"--key-rotate=20ms"}, &r, &ka)
+			initial := r.LastRotation()
+			require.Eventually(t, func() bool {
+				return r.LastRotation().After(initial)
+			}, 5*time.Second, 5*time.Millisecond, "the rotator never rotated")
+
+			if tc.stopApp {
+				app.RequireStop()
+			} else {
+				require.NoError(t, r.Stop())
+			}
+
+			assert.ErrorIs(r.Stop(), ErrRotatorStopped)
+
+			// no rotation happens once Stop returns
+			stopped := r.LastRotation()
+			current, err := ka.Load()
+			require.NoError(t, err)
+
+			time.Sleep(100 * time.Millisecond)
+			assert.Equal(stopped, r.LastRotation())
+
+			k, err := ka.Load()
+			require.NoError(t, err)
+			assert.Equal(current.KID, k.KID)
+
+			if !tc.stopApp {
+				// the app's own stop hook would report the Rotator as already stopped
+				assert.Error(app.Stop(t.Context()))
+			}
+		})
+	}
+}
+
+func TestRotatorRekeyKeepKID(t *testing.T) {
+	tests := []struct {
+		description  string
+		args         []string
+		expectedKept bool
+	}{
+		{
+			description: "new kids",
+			args:        []string{"--signing-pool-size=2"},
+		},
+		{
+			description:  "kept kids",
+			args:         []string{"--signing-pool-size=2", "--rekey-keep-kid"},
+			expectedKept: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				r  *Rotator
+				ka *KeyAccessor
+			)
+
+			startTestApp(t, tc.args, &r, &ka)
+			before, err := ka.LoadAll()
+			require.NoError(t, err)
+			require.Len(t, before, 2)
+
+			_, err = r.Rotate()
+			require.NoError(t, err)
+			after, err := ka.LoadAll()
+			require.NoError(t, err)
+			require.Len(t, after, 2)
+
+			for i := range after {
+				if tc.expectedKept {
+					assert.Equal(before[i].KID, after[i].KID)
+					kid, ok := after[i].Key.KeyID()
+					assert.True(ok)
+					assert.Equal(after[i].KID, kid)
+				} else {
+					assert.NotEqual(before[i].KID, after[i].KID)
+				}
+
+				// the key material always changes
+				assert.False(jwk.Equal(before[i].Key, after[i].Key))
+			}
+		})
+	}
+}
+
+// newTestRotator creates an unstarted Rotator over the given KeyStore, configured by
+// the given command line.
+func newTestRotator(t *testing.T, ks KeyStore, args ...string) (*Rotator, *KeyAccessor) {
+	cli := newTestCLI(t, args...)
+	kg, err := NewKeyGenerator(zap.NewNop(), NewIDGenerator(NewRandomSource()), NewRandomSource(), cli)
+	require.NoError(t, err)
+
+	i, err := newTestIssuer(t, args...)
+	require.NoError(t, err)
+
+	ka := new(KeyAccessor)
+	r, err := NewRotator(RotatorIn{
+		Logger:       zap.NewNop(),
+		KeyGenerator: kg,
+		KeyAccessor:  ka,
+		KeyStore:     ks,
+		Issuer:       i,
+		CLI:          cli,
+		Lifecycle:    fxtest.NewLifecycle(t),
+		Registerer:   prometheus.NewRegistry(),
+	})
+
+	require.NoError(t, err)
+	return r, ka
+}
+
+func TestRotatorRotationLock(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// keyStore creates the KeyStore shared by both replicas.
+		keyStore       func(*testing.T) KeyStore
+		expectedLocked bool
+	}{
+		{
+			description: "lock disabled",
+			keyStore: func(*testing.T) KeyStore {
+				return NewInMemoryKeyStore()
+			},
+		},
+		{
+			description: "lock enabled",
+			args:        []string{"--rotation-lock"},
+			keyStore: func(*testing.T) KeyStore {
+				return NewInMemoryKeyStore()
+			},
+			expectedLocked: true,
+		},
+		{
+			description: "decorated key store",
+			args:        []string{"--rotation-lock"},
+			keyStore: func(*testing.T) KeyStore {
+				return NewTombstoneKeyStore(NewMissCachingKeyStore(NewInMemoryKeyStore(), time.Minute, 10), time.Minute)
+			},
+			expectedLocked: true,
+		},
+		{
+			description: "key store without locking",
+			args:        []string{"--rotation-lock"},
+			keyStore: func(*testing.T) KeyStore {
+				return struct{ KeyStore }{NewInMemoryKeyStore()}
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			// the command line must name a key store that can lock, but the replicas
+			// share the KeyStore of the test case
+			args := append([]string{"--keystore=file", "--keystore-dir=" + t.TempDir()}, tc.args...)
+			ks := tc.keyStore(t)
+			first, _ := newTestRotator(t, ks, args...)
+			second, _ := newTestRotator(t, ks, args...)
+
+			_, err := first.scheduledRotate(context.Background())
+			require.NoError(t, err)
+
+			if !tc.expectedLocked {
+				assert.Nil(second.rotationLocker)
+				_, err = second.scheduledRotate(context.Background())
+				assert.NoError(err)
+				return
+			}
+
+			// the first replica holds the lock for the rest of the interval
+			require.NotNil(t, second.rotationLocker)
+			acquired, err := second.rotationLocker.TryLockRotation(time.Minute)
+			assert.NoError(err)
+			assert.False(acquired)
+
+			// so the second replica skips its tick, and only one key was generated
+			_, err = second.scheduledRotate(context.Background())
+			assert.ErrorIs(err, ErrRotationSkipped)
+
+			keys, err := ks.LoadAll()
+			require.NoError(t, err)
+			assert.Len(keys, 1)
+		})
+	}
+}
+
+func TestRotatorRotationLockAdoptsCurrentKey(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{"--keystore=file", "--keystore-dir=" + dir, "--rotation-lock", "--reuse-current-key"}
+	first, firstKeys := newTestRotator(t, NewFileKeyStore(zap.NewNop(), dir), args...)
+	second, secondKeys := newTestRotator(t, NewFileKeyStore(zap.NewNop(), dir), args...)
+
+	rotated, err := first.scheduledRotate(context.Background())
+	require.NoError(t, err)
+
+	adopted, err := second.scheduledRotate(context.Background())
+	assert.ErrorIs(t, err, ErrRotationSkipped)
+	assert.Equal(t, rotated.KID, adopted.KID)
+
+	firstCurrent, err := firstKeys.Load()
+	require.NoError(t, err)
+	secondCurrent, err := secondKeys.Load()
+	require.NoError(t, err)
+	assert.Equal(t, firstCurrent.KID, secondCurrent.KID)
+	assert.True(t, adopted.Created.Equal(second.LastRotation()))
+}
+
+// newTestLockedRotators starts two Rotators that share a file key store and a rotation
+// lock, and returns them along with the shared KeyStore.
+func newTestLockedRotators(t *testing.T) (first, second *Rotator, ks KeyStore) {
+	dir := t.TempDir()
+	args := []string{"--keystore=file", "--keystore-dir=" + dir, "--rotation-lock", "--reuse-current-key"}
+	ks = NewFileKeyStore(zap.NewNop(), dir)
+	first, _ = newTestRotator(t, ks, args...)
+	second, _ = newTestRotator(t, ks, args...)
+	for _, r := range []*Rotator{first, second} {
+		r.adoptRetry = time.Millisecond
+		require.NoError(t, r.Start())
+		t.Cleanup(func() { r.Stop() })
+	}
+
+	// persisted keys are created to the second, so rotated keys must be created
+	// in a later second for a replica to adopt them as newer
+	for _, r := range []*Rotator{first, second} {
+		r.keyGenerator.now = func() time.Time { return time.Now().Add(time.Hour) }
+	}
+
+	return
+}
+
+// loadKIDs returns the kids of every key in the given KeyStore.
+func loadKIDs(t *testing.T, ks KeyStore) map[string]bool {
+	keys, err := ks.LoadAll()
+	require.NoError(t, err)
+
+	kids := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		kids[k.KID] = true
+	}
+
+	return kids
+}
+
+func TestRotatorRotationLockSimultaneousTicks(t *testing.T) {
+	first, second, ks := newTestLockedRotators(t)
+	before := loadKIDs(t, ks)
+	require.Len(t, before, 1)
+
+	var (
+		wg      sync.WaitGroup
+		results [2]Key
+		errs    [2]error
+	)
+
+	for i, r := range []*Rotator{first, second} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = r.scheduledRotate(context.Background())
+		}()
+	}
+
+	wg.Wait()
+
+	// exactly one replica rotates, and the other adopts its key
+	skipped := 0
+	for _, err := range errs {
+		if errors.Is(err, ErrRotationSkipped) {
+			skipped++
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+
+	assert.Equal(t, 1, skipped)
+	assert.Equal(t, results[0].KID, results[1].KID)
+
+	after := loadKIDs(t, ks)
+	assert.Len(t, after, len(before)+1)
+	assert.True(t, after[results[0].KID])
+}
+
+func TestRotatorRotationLockAwaitsHolder(t *testing.T) {
+	first, second, ks := newTestLockedRotators(t)
+	before := loadKIDs(t, ks)
+
+	// the first replica wins the lock, but hasn't rotated yet when the second ticks
+	acquired, err := first.rotationLocker.TryLockRotation(time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	type result struct {
+		k   Key
+		err error
+	}
+
+	waited := make(chan result, 1)
+	go func() {
+		k, err := second.scheduledRotate(context.Background())
+		waited <- result{k: k, err: err}
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	rotated, err := first.Rotate()
+	require.NoError(t, err)
+
+	select {
+	case r := <-waited:
+		assert.ErrorIs(t, r.err, ErrRotationSkipped)
+		assert.Equal(t, rotated.KID, r.k.KID)
+
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the second replica never adopted the new key")
+	}
+
+	assert.Len(t, loadKIDs(t, ks), len(before)+1)
+}
+
+func TestRotatorRotationLockAwaitCanceled(t *testing.T) {
+	first, second, ks := newTestLockedRotators(t)
+	previous, err := second.keyAccessor.Load()
+	require.NoError(t, err)
+
+	acquired, err := first.rotationLocker.TryLockRotation(time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// the holder never rotates, so the second replica gives up without rotating
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	k, err := second.scheduledRotate(ctx)
+	assert.ErrorIs(t, err, ErrRotationSkipped)
+	assert.Empty(t, k.KID)
+
+	current, err := second.keyAccessor.Load()
+	require.NoError(t, err)
+	assert.Equal(t, previous.KID, current.KID)
+	assert.Len(t, loadKIDs(t, ks), 1)
+}
+
+func TestRotatorDelete(t *testing.T) {
+	tests := []struct {
+		description string
+
+		// kid selects the kid to delete from the current and demoted keys.
+		kid func(current, demoted Key) string
+
+		// elapsed is how long after the demotion the deletion happens, as a
+		// multiple of the demotion grace period.
+		elapsed     float64
+		expectedErr error
+	}{
+		{
+			description: "current key",
+			kid:         func(current, _ Key) string { return current.KID },
+			elapsed:     2,
+			expectedErr: ErrCurrentKey,
+		},
+		{
+			description: "just demoted key",
+			kid:         func(_, demoted Key) string { return demoted.KID },
+			expectedErr: ErrKeyInGrace,
+		},
+		{
+			description: "demoted key within grace",
+			kid:         func(_, demoted Key) string { return demoted.KID },
+			elapsed:     0.99,
+			expectedErr: ErrKeyInGrace,
+		},
+		{
+			description: "demoted key after grace",
+			kid:         func(_, demoted Key) string { return demoted.KID },
+			elapsed:     1,
+		},
+		{
+			description: "unknown key",
+			kid:         func(Key, Key) string { return "unknown" },
+			expectedErr: ErrNoSuchKey,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			ks := NewInMemoryKeyStore()
+			r, ka := newTestRotator(t, ks)
+
+			now := time.Now()
+			r.now = func() time.Time { return now }
+
+			demoted, err := r.Rotate()
+			require.NoError(t, err)
+			current, err := r.Rotate()
+			require.NoError(t, err)
+
+			now = now.Add(time.Duration(tc.elapsed * float64(r.demotionGrace)))
+			kid := tc.kid(current, demoted)
+			err = r.Delete(kid)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			_, err = ks.Load(kid)
+			assert.ErrorIs(t, err, ErrNoSuchKey)
+
+			k, err := ka.Load()
+			require.NoError(t, err)
+			assert.Equal(t, current.KID, k.KID)
+		})
+	}
+}
+
+func TestRotatorPurge(t *testing.T) {
+	tests := []struct {
+		description string
+
+		// expires is when the additional key expires, relative to now. When zero,
+		// the additional key has no expiry.
+		expires time.Duration
+
+		// elapsed is how long after the keys are stored the purge happens.
+		elapsed        time.Duration
+		expectedPurged int
+	}{
+		{
+			description: "unexpired key",
+			expires:     time.Hour,
+		},
+		{
+			description:    "expired key",
+			expires:        -time.Second,
+			expectedPurged: 1,
+		},
+		{
+			description:    "key expires after the keys are stored",
+			expires:        time.Minute,
+			elapsed:        time.Minute,
+			expectedPurged: 1,
+		},
+		{
+			description: "no expiry",
+			elapsed:     MaxExpires,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			ks := NewInMemoryKeyStore()
+			r, ka := newTestRotator(t, ks)
+
+			now := time.Now()
+			r.now = func() time.Time { return now }
+
+			current, err := r.Rotate()
+			require.NoError(t, err)
+
+			extra := newTestKey(t)
+			extra.Expires = time.Time{}
+			if tc.expires != 0 {
+				extra.Expires = now.Add(tc.expires)
+			}
+
+			require.NoError(t, ks.Store(extra))
+
+			now = now.Add(tc.elapsed)
+			purged, err := r.Purge()
+			require.NoError(t, err)
+			assert.Equal(tc.expectedPurged, purged)
+
+			_, err = ks.Load(extra.KID)
+			if tc.expectedPurged > 0 {
+				assert.ErrorIs(err, ErrNoSuchKey)
+			} else {
+				assert.NoError(err)
+			}
+
+			// the current key is never purged, even once it has expired
+			now = current.Expires.Add(time.Second)
+			_, err = r.Purge()
+			require.NoError(t, err)
+
+			k, err := ka.Load()
+			require.NoError(t, err)
+			assert.Equal(current.KID, k.KID)
+			_, err = ks.Load(current.KID)
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestRotatorPurgeDemoted(t *testing.T) {
+	tests := []struct {
+		description string
+
+		// elapsed is how long after the demotion the purge happens, as a multiple
+		// of the demotion grace period. The demoted key has already expired.
+		elapsed        float64
+		expectedPurged int
+	}{
+		{
+			description: "within grace",
+		},
+		{
+			description:    "after grace",
+			elapsed:        1,
+			expectedPurged: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			ks := NewInMemoryKeyStore()
+			r, _ := newTestRotator(t, ks)
+
+			now := time.Now()
+			r.now = func() time.Time { return now }
+
+			demoted, err := r.Rotate()
+			require.NoError(t, err)
+			_, err = r.Rotate()
+			require.NoError(t, err)
+
+			demoted.Expires = now
+			require.NoError(t, ks.Store(demoted))
+
+			now = now.Add(time.Duration(tc.elapsed * float64(r.demotionGrace)))
+
+			purged, err := r.Purge()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPurged, purged)
+		})
+	}
+}
+
+func TestRotatorGeneratePool(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expectErr   bool
+	}{
+		{
+			description: "one worker",
+			args:        []string{"--signing-pool-size=4"},
+		},
+		{
+			description: "fewer workers than keys",
+			args:        []string{"--signing-pool-size=5", "--key-generation-workers=2"},
+		},
+		{
+			description: "more workers than keys",
+			args:        []string{"--signing-pool-size=3", "--key-generation-workers=8"},
+		},
+		{
+			description: "generation fails with one worker",
+			args:        []string{"--signing-pool-size=3", "--kid-format=x"},
+			expectErr:   true,
+		},
+		{
+			description: "generation fails with several workers",
+			args:        []string{"--signing-pool-size=3", "--key-generation-workers=3", "--kid-format=x"},
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			r, _ := newTestRotator(t, NewInMemoryKeyStore(), tc.args...)
+
+			pool, err := r.generatePool()
+			if tc.expectErr {
+				assert.Error(err)
+				assert.Empty(pool)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, pool, r.poolSize)
+
+			// every slot is filled with a distinct key
+			kids := make(map[string]bool, len(pool))
+			for _, k := range pool {
+				require.NotEmpty(t, k.KID)
+				kids[k.KID] = true
+			}
+
+			assert.Len(kids, r.poolSize)
+		})
+	}
+}
+
+func TestRotatorEncryptionKey(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expectedAlg string
+	}{
+		{
+			description: "no encryption key",
+		},
+		{
+			description: "EC",
+			args:        []string{"--encryption-key-type=EC"},
+			expectedAlg: jwa.ECDH_ES_A256KW().String(),
+		},
+		{
+			description: "RSA",
+			args:        []string{"--encryption-key-type=RSA"},
+			expectedAlg: jwa.RSA_OAEP_256().String(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				s *http.Server
+				r *Rotator
+			)
+
+			startTestApp(t, tc.args, &s, &r)
+			encryptionKIDs := func() (kids []string) {
+				set := publishedKeys(t, s.Handler)
+				for i := 0; i < set.Len(); i++ {
+					k, _ := set.Key(i)
+					if use, _ := k.KeyUsage(); use == jwk.ForEncryption.String() {
+						keyOps, _ := k.KeyOps()
+						assert.NotContains(keyOps, jwk.KeyOpSign)
+						kid, _ := k.KeyID()
+						kids = append(kids, kid)
+					}
+				}
+
+				return
+			}
+
+			before := encryptionKIDs()
+			if len(tc.expectedAlg) == 0 {
+				assert.Empty(before)
+				return
+			}
+
+			require.Len(t, before, 1)
+
+			// the encryption key never signs anything, nor is its algorithm advertised
+			token := issueToken(t, s.Handler, "")
+			assert.NotEqual(before[0], tokenHeader(t, token)["kid"])
+
+			var a Algorithms
+			response := serve(s.Handler, http.MethodGet, "/algorithms", nil)
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &a))
+			assert.NotContains(a.SigningAlgorithms, tc.expectedAlg)
+
+			// the encryption key rotates along with the signing keys
+			_, err := r.Rotate()
+			require.NoError(t, err)
+			after := encryptionKIDs()
+			assert.Len(after, 2)
+			assert.Contains(after, before[0])
+		})
+	}
+}
+
+func TestRotatorKeyActivationDelay(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		rotations   int
+
+		// advance is how far the clock moves after the rotations, before promoting.
+		advance          time.Duration
+		expectedPromoted bool
+		expectedPending  bool
+	}{
+		{
+			description: "no delay",
+			rotations:   1,
+		},
+		{
+			description:     "before activation",
+			args:            []string{"--key-activation-delay=1h"},
+			rotations:       1,
+			advance:         time.Hour - time.Second,
+			expectedPending: true,
+		},
+		{
+			description:      "at activation",
+			args:             []string{"--key-activation-delay=1h"},
+			rotations:        1,
+			advance:          time.Hour,
+			expectedPromoted: true,
+		},
+		{
+			description:      "pending keys replaced",
+			args:             []string{"--key-activation-delay=1h"},
+			rotations:        2,
+			advance:          time.Hour,
+			expectedPromoted: true,
+		},
+		{
+			description: "nothing pending",
+			args:        []string{"--key-activation-delay=1h"},
+			advance:     time.Hour,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			ks := NewInMemoryKeyStore()
+			r, ka := newTestRotator(t, ks, tc.args...)
+			t.Cleanup(func() {
+				if r.promotion != nil {
+					r.promotion.Stop()
+				}
+			})
+
+			now := time.Now()
+			r.now = func() time.Time { return now }
+
+			initial, err := r.rotatePrimary(false)
+			require.NoError(t, err)
+
+			var rotated []Key
+			for range tc.rotations {
+				k, err := r.Rotate()
+				require.NoError(t, err)
+				rotated = append(rotated, k)
+
+				// rotated keys are published right away, whether or not they're pending
+				_, err = ks.Load(k.KID)
+				assert.NoError(err)
+			}
+
+			if r.activationDelay > 0 {
+				current, err := ka.Load()
+				require.NoError(t, err)
+				assert.Equal(initial.KID, current.KID)
+			}
+
+			now = now.Add(tc.advance)
+			promoted, err := r.Promote()
+			require.NoError(t, err)
+			assert.Equal(tc.expectedPromoted, promoted)
+			assert.Equal(tc.expectedPending, len(r.pending) > 0)
+
+			current, err := ka.Load()
+			require.NoError(t, err)
+			switch {
+			case len(rotated) > 0 && (tc.expectedPromoted || r.activationDelay == 0):
+				assert.Equal(rotated[len(rotated)-1].KID, current.KID)
+
+			default:
+				assert.Equal(initial.KID, current.KID)
+			}
+
+			if tc.expectedPending {
+				// pending keys count as current, so they aren't treated as demoted
+				r.lock.Lock()
+				assert.True(r.unsafeIsCurrent(rotated[len(rotated)-1].KID))
+				r.lock.Unlock()
+			}
+		})
+	}
+}
+
+func TestRotatorRecoverSkipsActivationDelay(t *testing.T) {
+	ks := NewInMemoryKeyStore()
+	r, ka := newTestRotator(t, ks, "--key-activation-delay=1h")
+	initial, err := r.rotatePrimary(false)
+	require.NoError(t, err)
+
+	require.NoError(t, ks.Delete(initial.KID))
+	require.NoError(t, r.Recover(initial.KID))
+
+	current, err := ka.Load()
+	require.NoError(t, err)
+	assert.NotEqual(t, initial.KID, current.KID)
+	assert.Empty(t, r.pending)
+}
@@ -0,0 +1,260 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// selfTestAudience is the audience of self test tokens when an audience is required
+	// but none is configured.
+	selfTestAudience = "utu-self-test"
+)
+
+var (
+	// ErrSelfTestFailed is the error wrapped by all self test failures.
+	ErrSelfTestFailed = errors.New("startup self test failed")
+)
+
+// SelfTestIn defines the dependencies necessary to create a SelfTest.
+type SelfTestIn struct {
+	fx.In
+
+	Logger      *zap.Logger
+	Issuer      *Issuer
+	Signer      *Signer
+	KeyAccessor *KeyAccessor
+	KeyStore    KeyStore
+	CLI         CLI
+
+	// Rotator is required so that the initial key exists before
+	// the self test runs.
+	Rotator   *Rotator
+	Lifecycle fx.Lifecycle
+}
+
+// SelfTest issues a token and verifies it against the published key set.
+// Verification goes through both the jwx key set path and an independent
+// set of manual header and claims checks.
+type SelfTest struct {
+	logger      *zap.Logger
+	issuer      *Issuer
+	signer      *Signer
+	keyAccessor *KeyAccessor
+	keyStore    KeyStore
+	now         func() time.Time
+	typ         string
+
+	// request is the IssueRequest for self test tokens.
+	request IssueRequest
+
+	// retries is the number of times a failed self test is retried at startup, and
+	// backoff is the delay before the first retry.
+	retries int
+	backoff time.Duration
+}
+
+func NewSelfTest(in SelfTestIn) (st *SelfTest) {
+	st = &SelfTest{
+		logger:      in.Logger,
+		issuer:      in.Issuer,
+		signer:      in.Signer,
+		keyAccessor: in.KeyAccessor,
+		keyStore:    in.KeyStore,
+		now:         time.Now,
+		typ:         in.CLI.Type,
+		retries:     in.CLI.SelfTestRetries,
+		backoff:     in.CLI.SelfTestBackoff,
+	}
+
+	if in.CLI.RequireAudience && len(in.CLI.Audience) == 0 {
+		st.request.Audience = []string{selfTestAudience}
+	}
+
+	if in.CLI.StrictStartup {
+		in.Lifecycle.Append(
+			fx.StartHook(st.RunWithRetries),
+		)
+	}
+
+	return
+}
+
+// publishedSet produces the same key set that is served over HTTP.
+func (st *SelfTest) publishedSet() (set jwk.Set, err error) {
+	var keys []Key
+	keys, err = st.keyStore.LoadAll()
+	if err == nil {
+		set, err = NewPublicSet(st.logger, keys...)
+	}
+
+	return
+}
+
+// decodeSegment decodes a single base64url segment of a compact JWS as JSON.
+func decodeSegment(segment []byte, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(string(segment))
+	if err == nil {
+		err = json.Unmarshal(raw, v)
+	}
+
+	return err
+}
+
+// checkManually verifies the signed token without going through the jwx
+// key set path. The header and claims are decoded by hand, and the signature
+// is verified directly against the published key named by the kid.
+func (st *SelfTest) checkManually(signed []byte, issued jwt.Token, set jwk.Set) error {
+	segments := bytes.Split(signed, []byte{'.'})
+	if len(segments) != 3 {
+		return fmt.Errorf("%w: the signed token has %d segments", ErrSelfTestFailed, len(segments))
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		Typ string `json:"typ"`
+	}
+
+	if err := decodeSegment(segments[0], &header); err != nil {
+		return fmt.Errorf("%w: unable to decode header: %w", ErrSelfTestFailed, err)
+	}
+
+	currentKey, ok := st.keyAccessor.Lookup(header.Kid)
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: header kid [%s] is not a current kid", ErrSelfTestFailed, header.Kid)
+
+	case header.Alg != currentKey.Alg.String():
+		return fmt.Errorf("%w: header alg [%s] does not match the key alg [%s]", ErrSelfTestFailed, header.Alg, currentKey.Alg)
+
+	case header.Typ != st.typ:
+		return fmt.Errorf("%w: header typ [%s] does not match the configured typ [%s]", ErrSelfTestFailed, header.Typ, st.typ)
+	}
+
+	published, ok := set.LookupKeyID(header.Kid)
+	if !ok {
+		return fmt.Errorf("%w: kid [%s] is not in the published key set", ErrSelfTestFailed, header.Kid)
+	}
+
+	if _, err := jws.Verify(signed, jws.WithKey(currentKey.Alg, published)); err != nil {
+		return fmt.Errorf("%w: the published key does not verify the signature: %w", ErrSelfTestFailed, err)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(segments[1], &claims); err != nil {
+		return fmt.Errorf("%w: unable to decode claims: %w", ErrSelfTestFailed, err)
+	}
+
+	var issuedJTI any
+	issued.Get(st.issuer.ClaimName(jwt.JwtIDKey), &issuedJTI)
+	if jti := claims[st.issuer.ClaimName(jwt.JwtIDKey)]; jti != issuedJTI {
+		return fmt.Errorf("%w: claims jti [%v] does not match the issued jti [%v]", ErrSelfTestFailed, jti, issuedJTI)
+	}
+
+	if exp, _ := claims[st.issuer.ClaimName(jwt.ExpirationKey)].(float64); int64(exp) <= st.now().Unix() {
+		return fmt.Errorf("%w: the issued token has already expired", ErrSelfTestFailed)
+	}
+
+	return nil
+}
+
+// Run issues a token, signs it with the current key, and verifies it against
+// the published key set. Any mismatch produces an error wrapping ErrSelfTestFailed.
+func (st *SelfTest) Run() (err error) {
+	var (
+		issued jwt.Token
+		signed []byte
+		set    jwk.Set
+	)
+
+	issued, err = st.issuer.Issue(st.request)
+	if err == nil {
+		signed, err = st.signer.SignToken(issued)
+	}
+
+	if err == nil {
+		set, err = st.publishedSet()
+	}
+
+	if err == nil {
+		_, err = jwt.Parse(
+			signed,
+			jwt.WithKeySet(set, jws.WithInferAlgorithmFromKey(true)),
+			jwt.WithValidate(true),
+		)
+
+		if err != nil {
+			err = fmt.Errorf("%w: the published key set does not verify the issued token: %w", ErrSelfTestFailed, err)
+		}
+	}
+
+	if err == nil {
+		err = st.checkManually(signed, issued, set)
+	}
+
+	if err == nil {
+		st.logger.Info("startup self test passed")
+	} else {
+		st.logger.Error("startup self test failed", zap.Error(err))
+	}
+
+	return
+}
+
+// RunWithRetries runs the self test, retrying a failure up to the configured number of
+// times with exponential backoff. The last failure is returned if every attempt fails,
+// or the context's error if it is canceled while waiting to retry. Since the self test
+// reads the published key set straight from the key store, retries only help with transient
+// key store errors; they never wait on the HTTP listener.
+func (st *SelfTest) RunWithRetries(ctx context.Context) (err error) {
+	err = st.Run()
+	backoff := st.backoff
+	for attempt := 1; err != nil && attempt <= st.retries; attempt++ {
+		st.logger.Warn("retrying startup self test",
+			zap.Int("attempt", attempt),
+			zap.Int("retries", st.retries),
+			zap.Duration("backoff", backoff),
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+
+		case <-timer.C:
+		}
+
+		err = st.Run()
+		backoff *= 2
+	}
+
+	return
+}
+
+func ProvideSelfTest() fx.Option {
+	return fx.Options(
+		fx.Provide(
+			NewSelfTest,
+		),
+		fx.Invoke(
+			// ensure the self test runs, if enabled
+			func(*SelfTest) {},
+		),
+	)
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSelfTestRun(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// setup breaks the self test when it is expected to fail.
+		setup       func(*testing.T, *SelfTest)
+		expectedErr error
+	}{
+		{
+			description: "EC",
+			args:        []string{"--strict-startup"},
+		},
+		{
+			description: "RSA",
+			args:        []string{"--strict-startup", "--key-type=RSA"},
+		},
+		{
+			description: "OKP",
+			args:        []string{"--strict-startup", "--key-type=OKP"},
+		},
+		{
+			description: "required audience",
+			args:        []string{"--strict-startup", "--require-audience"},
+		},
+		{
+			description: "current key not published",
+			args:        []string{"--strict-startup", "--missing-current-key=ignore"},
+			setup: func(t *testing.T, st *SelfTest) {
+				k, err := st.keyAccessor.Load()
+				require.NoError(t, err)
+				require.NoError(t, st.keyStore.Delete(k.KID))
+			},
+			expectedErr: ErrSelfTestFailed,
+		},
+		{
+			description: "issued token already expired",
+			args:        []string{"--strict-startup"},
+			setup: func(_ *testing.T, st *SelfTest) {
+				st.now = func() time.Time { return time.Now().Add(time.Hour) }
+			},
+			expectedErr: ErrSelfTestFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var st *SelfTest
+			startTestApp(t, tc.args, &st)
+			if tc.setup != nil {
+				tc.setup(t, st)
+			}
+
+			assert.ErrorIs(t, st.Run(), tc.expectedErr)
+		})
+	}
+}
+
+func TestSelfTestRunWithRetries(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// failures is the number of attempts that fail before the self test passes.
+		failures         int
+		canceled         bool
+		expectedAttempts int
+		expectedRetries  int
+		expectedErr      error
+	}{
+		{
+			description:      "passes",
+			expectedAttempts: 1,
+		},
+		{
+			description:      "fails without retries",
+			failures:         1,
+			expectedAttempts: 1,
+			expectedErr:      ErrSelfTestFailed,
+		},
+		{
+			description:      "passes on a retry",
+			args:             []string{"--self-test-retries=3"},
+			failures:         2,
+			expectedAttempts: 3,
+			expectedRetries:  2,
+		},
+		{
+			description:      "every retry fails",
+			args:             []string{"--self-test-retries=2"},
+			failures:         5,
+			expectedAttempts: 3,
+			expectedRetries:  2,
+			expectedErr:      ErrSelfTestFailed,
+		},
+		{
+			description:      "canceled while waiting to retry",
+			args:             []string{"--self-test-retries=2"},
+			failures:         5,
+			canceled:         true,
+			expectedAttempts: 1,
+			expectedRetries:  1,
+			expectedErr:      context.Canceled,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var st *SelfTest
+			startTestApp(t, append([]string{"--self-test-backoff=1ms"}, tc.args...), &st)
+
+			// each attempt checks the expiry once, and a failing attempt checks it an hour from now
+			var attempts int
+			st.now = func() time.Time {
+				attempts++
+				if attempts <= tc.failures {
+					return time.Now().Add(time.Hour)
+				}
+
+				return time.Now()
+			}
+
+			core, logs := observer.New(zap.WarnLevel)
+			st.logger = zap.New(core)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.canceled {
+				cancel()
+			}
+
+			assert.ErrorIs(st.RunWithRetries(ctx), tc.expectedErr)
+			assert.Equal(tc.expectedAttempts, attempts)
+			assert.Equal(tc.expectedRetries, logs.FilterMessage("retrying startup self test").Len())
+		})
+	}
+}
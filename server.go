@@ -5,52 +5,201 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"time"
 
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+const (
+	// TrailingSlashServe is the trailing slash mode in which trailing-slash variants
+	// of routes are served just like the routes themselves.
+	TrailingSlashServe = "serve"
+
+	// TrailingSlashRedirect is the trailing slash mode in which trailing-slash variants
+	// of routes are permanently redirected to the routes.
+	TrailingSlashRedirect = "redirect"
+)
+
 type ServerIn struct {
 	fx.In
 
-	Logger         *zap.Logger
-	CLI            CLI
-	ListenConfig   *net.ListenConfig
-	KeyHandler     *KeyHandler
-	KeysHandler    *KeysHandler
-	IssueHandler   *IssueHandler
-	SignHandler    *SignHandler
-	SwaggerHandler http.Handler `name:"swaggerHandler"`
+	Logger                *zap.Logger
+	CLI                   CLI
+	ListenConfig          *net.ListenConfig
+	KeyHandler            *KeyHandler
+	KeysHandler           *KeysHandler
+	KeyChangesHandler     *KeyChangesHandler
+	IssueHandler          *IssueHandler
+	SignHandler           *SignHandler
+	TokenHandler          *TokenHandler
+	ExportHandler         *ExportHandler
+	VerifyHandler         *VerifyHandler
+	DecodeHandler         *DecodeHandler
+	LogoutHandler         *LogoutHandler
+	InfoHandler           *InfoHandler
+	ServerMetadataHandler *ServerMetadataHandler
+	ReadyHandler          *ReadyHandler
+	AlgorithmsHandler     *AlgorithmsHandler
+	RandomSourceHandler   *RandomSourceHandler
+	PurgeHandler          *PurgeHandler
+	RotateHandler         *RotateHandler
+	PublishHandler        *PublishHandler
+	AdminAuth             *AdminAuth
+	SwaggerHandler        http.Handler `name:"swaggerHandler"`
+	MetricsHandler        http.Handler `name:"metricsHandler"`
 
 	Lifecycle  fx.Lifecycle
 	Shutdowner fx.Shutdowner
 }
 
+// newTLSConfig creates the server's TLS configuration. When a client CA file is
+// configured, clients may present certificates, and any presented certificate
+// must verify against those CAs. This function returns nil if no client CA file
+// is configured.
+func newTLSConfig(cli CLI) (tc *tls.Config, err error) {
+	if len(cli.TLSClientCAFile) == 0 {
+		return
+	}
+
+	var pem []byte
+	pem, err = os.ReadFile(cli.TLSClientCAFile)
+	if err == nil {
+		pool := x509.NewCertPool()
+		if pool.AppendCertsFromPEM(pem) {
+			tc = &tls.Config{
+				ClientCAs:  pool,
+				ClientAuth: tls.VerifyClientCertIfGiven,
+			}
+		} else {
+			err = fmt.Errorf("no certificates found in client CA file [%s]", cli.TLSClientCAFile)
+		}
+	}
+
+	return
+}
+
+// handleTrailingSlash registers the trailing-slash variant of a GET route, which
+// the mux otherwise treats as a different path. Depending on the mode, the variant
+// is either served by the same handler or redirected to the route.
+func handleTrailingSlash(handle func(string, http.Handler), mode, path string, h http.Handler) {
+	if mode == TrailingSlashRedirect {
+		h = http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			target := url.URL{Path: path, RawQuery: request.URL.RawQuery}
+			http.Redirect(response, request, target.String(), http.StatusMovedPermanently)
+		})
+	}
+
+	// the {$} anchor matches only the trailing slash, not the whole subtree
+	handle("GET "+path+"/{$}", h)
+}
+
+// routePaths are the paths of every route that NewServer may register, which are the
+// valid keys of --route-timeout. This must be kept in sync with NewServer.
+var routePaths = []string{
+	"/keys",
+	"/keys/changes",
+	"/key",
+	"/key/{kid}",
+	"/issue",
+	"/sign",
+	"/token",
+	"/admin/export",
+	"/verify",
+	"/decode",
+	"/logout",
+	"/admin/random",
+	"/admin/purge",
+	"/rotate",
+	"/admin/publish",
+	"/info",
+	ServerMetadataPath,
+	"/algorithms",
+	"/readyz",
+	"/swagger/",
+	"/metrics",
+}
+
+// withRouteTimeout decorates the handler for the given route pattern with its configured
+// timeout, if any. Timeouts are keyed by the path of the pattern, e.g. /sign, and a
+// request that exceeds its timeout receives a 503.
+func withRouteTimeout(timeouts map[string]time.Duration, pattern string, h http.Handler) http.Handler {
+	_, path, _ := strings.Cut(pattern, " ")
+
+	// trailing-slash variants share the timeout of their route
+	path = strings.TrimSuffix(path, "/{$}")
+	if timeout, ok := timeouts[path]; ok {
+		h = http.TimeoutHandler(h, timeout, fmt.Sprintf("the request to %s timed out after %s", path, timeout))
+	}
+
+	return h
+}
+
 func NewServer(in ServerIn) (s *http.Server, err error) {
 	s = &http.Server{
 		Addr:              in.CLI.Address,
 		ReadHeaderTimeout: 2 * time.Second,
 	}
 
+	s.TLSConfig, err = newTLSConfig(in.CLI)
+	if err != nil {
+		return
+	}
+
 	mux := http.NewServeMux()
-	mux.Handle("GET /keys", in.KeysHandler)
-	mux.Handle("GET /key", in.KeyHandler)
-	mux.Handle("GET /key/{kid}", in.KeyHandler)
-	mux.Handle("GET /issue", in.IssueHandler)
-	mux.Handle("PUT /sign", in.SignHandler)
-	mux.Handle("GET /swagger/", in.SwaggerHandler)
+	handle := func(pattern string, h http.Handler) {
+		mux.Handle(pattern, withRouteTimeout(in.CLI.RouteTimeout, pattern, h))
+	}
+
+	handle("GET /keys", in.KeysHandler)
+	handle("GET /keys/changes", in.KeyChangesHandler)
+	handle("GET /key", in.KeyHandler)
+	handle("GET /key/{kid}", in.KeyHandler)
+	handleTrailingSlash(handle, in.CLI.TrailingSlash, "/keys", in.KeysHandler)
+	handleTrailingSlash(handle, in.CLI.TrailingSlash, "/key", in.KeyHandler)
+	handle("POST /issue", in.IssueHandler)
+	if !in.CLI.IssuePostOnly {
+		handle("GET /issue", in.IssueHandler)
+	}
+
+	handle("PUT /sign", in.SignHandler)
+	handle("POST /token", in.TokenHandler)
+	handle("GET /admin/export", in.AdminAuth.Then(in.ExportHandler))
+	handle("POST /verify", in.VerifyHandler)
+	handle("POST /decode", in.AdminAuth.Then(in.DecodeHandler))
+	handle("POST /logout", in.AdminAuth.Then(in.LogoutHandler))
+	handle("POST /admin/random", in.AdminAuth.Then(in.RandomSourceHandler))
+	handle("POST /admin/purge", in.AdminAuth.Then(in.PurgeHandler))
+	handle("POST /rotate", in.AdminAuth.Then(in.RotateHandler))
+	handle("POST /admin/publish", in.AdminAuth.Then(in.PublishHandler))
+	handle("GET /info", in.InfoHandler)
+	handle("GET "+ServerMetadataPath, in.ServerMetadataHandler)
+	handle("GET /algorithms", in.AlgorithmsHandler)
+	handle("GET /readyz", in.ReadyHandler)
+	handle("GET /swagger/", in.SwaggerHandler)
+	handle("GET /metrics", in.MetricsHandler)
 	s.Handler = mux
 
 	in.Lifecycle.Append(
 		fx.StartStopHook(
 			func(ctx context.Context) (err error) {
+				scheme := "http"
+				if len(in.CLI.TLSCertFile) > 0 {
+					scheme = "https"
+				}
+
 				var l net.Listener
 				l, err = in.ListenConfig.Listen(ctx, in.CLI.Network, in.CLI.Address)
+
 				if err == nil {
 					s.Addr = l.Addr().String()
 					go func() {
@@ -59,18 +208,30 @@ func NewServer(in ServerIn) (s *http.Server, err error) {
 						in.Logger.Info(
 							"starting server",
 							zap.String("address", s.Addr),
+							zap.String("scheme", scheme),
 							zap.Any(
 								"endpoints",
 								map[string]string{
-									"key":   fmt.Sprintf("http://%s/key", s.Addr),
-									"keys":  fmt.Sprintf("http://%s/keys", s.Addr),
-									"issue": fmt.Sprintf("http://%s/issue", s.Addr),
-									"sign":  fmt.Sprintf("http://%s/sign", s.Addr),
+									"key":        fmt.Sprintf("%s://%s/key", scheme, s.Addr),
+									"keys":       fmt.Sprintf("%s://%s/keys", scheme, s.Addr),
+									"issue":      fmt.Sprintf("%s://%s/issue", scheme, s.Addr),
+									"sign":       fmt.Sprintf("%s://%s/sign", scheme, s.Addr),
+									"token":      fmt.Sprintf("%s://%s/token", scheme, s.Addr),
+									"metrics":    fmt.Sprintf("%s://%s/metrics", scheme, s.Addr),
+									"verify":     fmt.Sprintf("%s://%s/verify", scheme, s.Addr),
+									"info":       fmt.Sprintf("%s://%s/info", scheme, s.Addr),
+									"algorithms": fmt.Sprintf("%s://%s/algorithms", scheme, s.Addr),
 								},
 							),
 						)
 
-						serveErr := s.Serve(l)
+						var serveErr error
+						if scheme == "https" {
+							serveErr = s.ServeTLS(l, in.CLI.TLSCertFile, in.CLI.TLSKeyFile)
+						} else {
+							serveErr = s.Serve(l)
+						}
+
 						if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
 							in.Logger.Error("unable to start server", zap.Error(serveErr))
 						}
@@ -99,8 +260,12 @@ func ProvideServer() fx.Option {
 			NewServer,
 		),
 		fx.Invoke(
-			// force the server to start
-			func(*http.Server) {},
+			// force the server to start, then register the rotator's stop hook
+			// after the server's. fx stops in reverse order, so the rotator
+			// stops before the server drains and no rotation happens during shutdown.
+			func(l fx.Lifecycle, _ *http.Server, r *Rotator) {
+				l.Append(fx.StopHook(r.Stop))
+			},
 		),
 	)
 }
@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	_, caPEM := newTestCertificate(t)
+	tests := []struct {
+		description string
+
+		// contents are the contents of the client CA file. When nil, no client
+		// CA file is configured.
+		contents  []byte
+		expectNil bool
+		expectErr bool
+	}{
+		{
+			description: "no client CA file",
+			expectNil:   true,
+		},
+		{
+			description: "client CA file",
+			contents:    caPEM,
+		},
+		{
+			description: "no certificates",
+			contents:    []byte("not a certificate"),
+			expectNil:   true,
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var cli CLI
+			if tc.contents != nil {
+				cli.TLSClientCAFile = filepath.Join(t.TempDir(), "ca.pem")
+				require.NoError(t, os.WriteFile(cli.TLSClientCAFile, tc.contents, 0o600))
+			}
+
+			config, err := newTLSConfig(cli)
+			if tc.expectErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			if tc.expectNil {
+				assert.Nil(config)
+				return
+			}
+
+			require.NotNil(t, config)
+			assert.Equal(tls.VerifyClientCertIfGiven, config.ClientAuth)
+			assert.NotNil(config.ClientCAs)
+		})
+	}
+}
+
+func TestServerTrailingSlash(t *testing.T) {
+	tests := []struct {
+		description      string
+		args             []string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			description:    "keys",
+			target:         "/keys",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "served keys variant",
+			target:         "/keys/",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "served key variant",
+			target:         "/key/",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:      "redirected keys variant",
+			args:             []string{"--trailing-slash=redirect"},
+			target:           "/keys/",
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "/keys",
+		},
+		{
+			description:      "redirected key variant with a query",
+			args:             []string{"--trailing-slash=redirect"},
+			target:           "/key/?format=pem",
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "/key?format=pem",
+		},
+		{
+			description:    "redirect mode still serves the route",
+			args:           []string{"--trailing-slash=redirect"},
+			target:         "/keys",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "subtree is not matched",
+			target:         "/keys/extra",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newTestServer(t, tc.args...)
+			response := serve(h, http.MethodGet, tc.target, nil)
+			assert.Equal(t, tc.expectedStatus, response.Code)
+			assert.Equal(t, tc.expectedLocation, response.Header().Get("Location"))
+		})
+	}
+}
+
+func TestWithRouteTimeout(t *testing.T) {
+	slow := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		select {
+		case <-request.Context().Done():
+		case <-time.After(time.Second):
+			response.WriteHeader(http.StatusOK)
+		}
+	})
+
+	tests := []struct {
+		description    string
+		timeouts       map[string]time.Duration
+		pattern        string
+		expectedStatus int
+	}{
+		{
+			description:    "no timeout configured",
+			pattern:        "GET /keys",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "another route's timeout",
+			timeouts:       map[string]time.Duration{"/sign": 10 * time.Millisecond},
+			pattern:        "GET /keys",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "timed out",
+			timeouts:       map[string]time.Duration{"/keys": 10 * time.Millisecond},
+			pattern:        "GET /keys",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			description:    "trailing-slash variant",
+			timeouts:       map[string]time.Duration{"/keys": 10 * time.Millisecond},
+			pattern:        "GET /keys/{$}",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := withRouteTimeout(tc.timeouts, tc.pattern, slow)
+			response := serve(h, http.MethodGet, "/keys", nil)
+			require.Equal(t, tc.expectedStatus, response.Code)
+			if tc.expectedStatus == http.StatusServiceUnavailable {
+				assert.Equal(t, "the request to /keys timed out after 10ms", response.Body.String())
+			}
+		})
+	}
+}
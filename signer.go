@@ -4,52 +4,253 @@
 package main
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
 	"github.com/lestrrat-go/jwx/v3/jws"
 	"github.com/lestrrat-go/jwx/v3/jwt"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+const (
+	// MissingCurrentKeyRotate is the missing current key mode in which a Signer that
+	// finds its current key missing from the KeyStore rotates before signing.
+	MissingCurrentKeyRotate = "rotate"
+
+	// MissingCurrentKeyFail is the missing current key mode in which a Signer that
+	// finds its current key missing from the KeyStore refuses to sign.
+	MissingCurrentKeyFail = "fail"
+
+	// MissingCurrentKeyIgnore is the missing current key mode in which a Signer
+	// doesn't check the KeyStore for its current key.
+	MissingCurrentKeyIgnore = "ignore"
+
+	// B64Parameter is the /sign request parameter that, when false, produces an RFC 7797
+	// JWS with an unencoded payload.
+	B64Parameter = "b64"
+
+	// b64Header is the RFC 7797 header that indicates whether the payload is base64url encoded.
+	b64Header = "b64"
+)
+
+var (
+	// ErrCurrentKeyMissing indicates that the current signing key is no longer in the
+	// KeyStore, so tokens signed with it couldn't be verified against the published set.
+	ErrCurrentKeyMissing = errors.New("the current key is missing from the key store")
+
+	// ErrUnencodedPayload indicates that a payload can't be signed with RFC 7797
+	// unencoded payload options.
+	ErrUnencodedPayload = errors.New("the payload cannot be signed unencoded")
+)
+
+// SignerIn defines the dependencies necessary to create a Signer.
+type SignerIn struct {
+	fx.In
+
+	Logger        *zap.Logger
+	KeyAccessor   *KeyAccessor
+	KeyStore      KeyStore
+	Rotator       *Rotator
+	CLI           CLI
+	MultiSignKeys MultiSignKeys `optional:"true"`
+	SignKey       *SignKey      `optional:"true"`
+	Cosigner      *Cosigner     `optional:"true"`
+	IssueKeys     IssueKeys     `optional:"true"`
+}
+
 type Signer struct {
-	logger      *zap.Logger
-	keyAccessor *KeyAccessor
-	typ         string
+	logger        *zap.Logger
+	keyAccessor   *KeyAccessor
+	multiSignKeys MultiSignKeys
+	signKey       *SignKey
+	cosigner      *Cosigner
+	issueKeys     IssueKeys
+	keyType       string
+	typ           string
+	jku           string
+	deterministic bool
+	lowS          bool
+
+	// fullCTY indicates whether /sign payloads carry their full media type as the cty,
+	// rather than the RFC 7515 short form that omits the application/ prefix.
+	fullCTY bool
+
+	// keyStore and rotator are used to check for, and recover from, a current
+	// key that is missing from the KeyStore, according to missingKey.
+	keyStore   KeyStore
+	rotator    *Rotator
+	missingKey string
 }
 
-func NewSigner(l *zap.Logger, keyAccessor *KeyAccessor, cli CLI) (s *Signer, err error) {
+func NewSigner(in SignerIn) (s *Signer, err error) {
 	s = &Signer{
-		logger:      l,
-		keyAccessor: keyAccessor,
-		typ:         cli.Type,
+		logger:        in.Logger,
+		keyAccessor:   in.KeyAccessor,
+		multiSignKeys: in.MultiSignKeys,
+		signKey:       in.SignKey,
+		cosigner:      in.Cosigner,
+		issueKeys:     in.IssueKeys,
+		keyType:       in.CLI.KeyType,
+		typ:           in.CLI.Type,
+		deterministic: in.CLI.DeterministicECDSA,
+		lowS:          in.CLI.LowSECDSA,
+		fullCTY:       in.CLI.SignFullCTY,
+		keyStore:      in.KeyStore,
+		rotator:       in.Rotator,
+		missingKey:    in.CLI.MissingCurrentKey,
+	}
+
+	if len(in.CLI.ExternalURL) > 0 {
+		s.jku, err = url.JoinPath(in.CLI.ExternalURL, "keys")
+	}
+
+	if err != nil {
+		return
 	}
 
 	s.logger.Info("signer",
 		zap.String("typ", s.typ),
+		zap.String("jku", s.jku),
+		zap.Bool("deterministic", s.deterministic),
+		zap.Bool("lowS", s.lowS),
+		zap.Bool("fullCTY", s.fullCTY),
+		zap.Int("multiSign", len(s.multiSignKeys)),
+		zap.Bool("signKey", s.signKey != nil),
+		zap.Bool("cosigner", s.cosigner != nil),
+		zap.Int("issueKeys", len(s.issueKeys)),
+		zap.String("missingCurrentKey", s.missingKey),
 	)
 
 	return
 }
 
+// MultiSign tests if this Signer produces JWS JSON serializations with multiple
+// signatures from SignPayload.
+func (s *Signer) MultiSign() bool {
+	return len(s.multiSignKeys) > 0 || s.cosigner != nil
+}
+
+// signingKey returns the key material that signs with the given key. When deterministic
+// signatures are enabled, EC keys are wrapped so that RFC 6979 nonces are used. When
+// low-S signatures are enabled, EC keys are wrapped so that S is always normalized.
+func (s *Signer) signingKey(k Key) (key any, err error) {
+	key = k.Key
+	if (!s.deterministic && !s.lowS) || k.Key.KeyType() != jwa.EC() {
+		return
+	}
+
+	var raw ecdsa.PrivateKey
+	if err = jwk.Export(k.Key, &raw); err == nil {
+		var signer crypto.Signer = &raw
+		if s.deterministic {
+			signer = deterministicECDSA{key: &raw}
+		}
+
+		if s.lowS {
+			signer = lowSECDSA{signer: signer, n: raw.Curve.Params().N}
+		}
+
+		key = signer
+	}
+
+	return
+}
+
+// nextKey returns the next key from the pool of current signing keys, first making
+// sure that the key is still in the KeyStore unless missing keys are ignored. Errors
+// loading from the KeyStore other than ErrNoSuchKey don't prevent signing.
+func (s *Signer) nextKey() (k Key, err error) {
+	k, err = s.keyAccessor.Next()
+	if err != nil || s.missingKey == MissingCurrentKeyIgnore {
+		return
+	}
+
+	if _, loadErr := s.keyStore.Load(k.KID); !errors.Is(loadErr, ErrNoSuchKey) {
+		return
+	}
+
+	switch s.missingKey {
+	case MissingCurrentKeyFail:
+		err = fmt.Errorf("%w: %s", ErrCurrentKeyMissing, k.KID)
+
+	default:
+		if err = s.rotator.Recover(k.KID); err == nil {
+			k, err = s.keyAccessor.Next()
+		}
+	}
+
+	return
+}
+
 // SignToken returns the compact serialization of the given token signed with
-// the current signing key.
-func (s *Signer) SignToken(t jwt.Token) (signed []byte, err error) {
-	var currentKey Key
-	currentKey, err = s.keyAccessor.Load()
+// the next key from the pool of current signing keys.
+func (s *Signer) SignToken(t jwt.Token) ([]byte, error) {
+	return s.SignTokenWithType(t, s.typ)
+}
+
+// SignTokenWithKeyType is like SignToken, but signs with the current key of the given
+// key type. An empty key type, or the primary key type, selects the next key from the
+// pool of current signing keys. Any other key type must be one of the issue key types.
+func (s *Signer) SignTokenWithKeyType(t jwt.Token, keyType string) ([]byte, error) {
+	if len(keyType) == 0 || keyType == s.keyType {
+		return s.SignToken(t)
+	}
+
+	ak, ok := s.issueKeys.Lookup(keyType)
+	if !ok {
+		return nil, fmt.Errorf("no current key of type %s", keyType)
+	}
+
+	currentKey, err := ak.KeyAccessor.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.signTokenWith(t, s.typ, currentKey)
+}
+
+// SignTokenWithType is like SignToken, but uses the given typ header instead of
+// the configured typ. This is used for special-purpose tokens, such as logout tokens.
+func (s *Signer) SignTokenWithType(t jwt.Token, typ string) ([]byte, error) {
+	currentKey, err := s.nextKey()
+	if err != nil {
+		return nil, err
+	}
 
+	return s.signTokenWith(t, typ, currentKey)
+}
+
+// signTokenWith signs the given token with the given key and typ header.
+func (s *Signer) signTokenWith(t jwt.Token, typ string, currentKey Key) (signed []byte, err error) {
+	var signingKey any
+	signingKey, err = s.signingKey(currentKey)
 	if err == nil {
 		h := jws.NewHeaders()
 		h.Set(jws.KeyIDKey, currentKey.KID)
-		h.Set(jws.TypeKey, s.typ)
+		h.Set(jws.TypeKey, typ)
+		if len(s.jku) > 0 {
+			h.Set(jws.JWKSetURLKey, s.jku)
+		}
+
+		if chain, ok := currentKey.Key.X509CertChain(); ok {
+			h.Set(jws.X509CertChainKey, chain)
+		}
 
 		signed, err = jwt.Sign(
 			t,
 			jwt.WithKey(
 				currentKey.Alg,
-				currentKey.Key,
+				signingKey,
 				jws.WithProtectedHeaders(h),
 			),
 		)
@@ -58,7 +259,14 @@ func (s *Signer) SignToken(t jwt.Token) (signed []byte, err error) {
 	return
 }
 
+// ctyOf returns the cty header for a payload with the given content type. Unless
+// the full content type is configured, the application/ prefix is omitted as
+// RFC 7515 recommends.
 func (s *Signer) ctyOf(contentType string) string {
+	if s.fullCTY {
+		return contentType
+	}
+
 	parts := strings.Split(contentType, "/")
 	if len(parts) == 2 && parts[0] == "application" {
 		return parts[1]
@@ -67,28 +275,97 @@ func (s *Signer) ctyOf(contentType string) string {
 	}
 }
 
+// payloadKeyOption produces the jws signing option for a single key used to sign a payload.
+// For an unencoded payload, the b64 header is false and listed as critical.
+func (s *Signer) payloadKeyOption(contentType string, unencoded bool, k Key) (jws.SignOption, error) {
+	signingKey, err := s.signingKey(k)
+	if err != nil {
+		return nil, err
+	}
+
+	h := jws.NewHeaders()
+	h.Set(jws.KeyIDKey, k.KID)
+	if len(s.jku) > 0 {
+		h.Set(jws.JWKSetURLKey, s.jku)
+	}
+
+	if chain, ok := k.Key.X509CertChain(); ok {
+		h.Set(jws.X509CertChainKey, chain)
+	}
+
+	if len(contentType) > 0 {
+		h.Set(jws.ContentTypeKey, s.ctyOf(contentType))
+	}
+
+	if unencoded {
+		h.Set(b64Header, false)
+		h.Set(jws.CriticalKey, []string{b64Header})
+	}
+
+	return jws.WithKey(
+		k.Alg,
+		signingKey,
+		jws.WithProtectedHeaders(h),
+	), nil
+}
+
 // SignPayload returns the compact serialization of the given payload signed
 // with the current signing key. The contentType value is used to determine the
-// typ attribute in the protected header.
-func (s *Signer) SignPayload(contentType string, p []byte) (signed []byte, err error) {
+// typ attribute in the protected header. If this Signer has a SignKey, that key
+// is used in place of the current signing key.
+//
+// If this Signer has multi-sign keys, the returned JWS is instead a JSON serialization
+// with a signature from the current signing key followed by a signature from each
+// multi-sign key. If this Signer has a Cosigner, the co-signer's signature follows
+// the others, and any co-signer failure fails the whole signing operation.
+//
+// If unencoded is true, the returned JWS is an RFC 7797 compact serialization with an
+// unencoded payload. That requires a payload without any '.' characters, and a Signer
+// without multi-sign keys or a co-signer; otherwise, the error wraps ErrUnencodedPayload.
+func (s *Signer) SignPayload(contentType string, unencoded bool, p []byte) (signed []byte, err error) {
+	switch {
+	case unencoded && s.MultiSign():
+		return nil, fmt.Errorf("%w: unencoded payloads require a compact serialization, which multi-sign keys and co-signers don't produce", ErrUnencodedPayload)
+
+	case unencoded && bytes.IndexByte(p, '.') >= 0:
+		return nil, fmt.Errorf("%w: an unencoded payload must not contain '.'", ErrUnencodedPayload)
+	}
+
 	var currentKey Key
-	currentKey, err = s.keyAccessor.Load()
+	if s.signKey != nil {
+		currentKey, err = s.signKey.KeyAccessor.Load()
+	} else {
+		currentKey, err = s.nextKey()
+	}
 
+	var option jws.SignOption
+	options := make([]jws.SignOption, 0, len(s.multiSignKeys)+2)
 	if err == nil {
-		h := jws.NewHeaders()
-		h.Set(jws.KeyIDKey, currentKey.KID)
-		if len(contentType) > 0 {
-			h.Set(jws.ContentTypeKey, s.ctyOf(contentType))
+		option, err = s.payloadKeyOption(contentType, unencoded, currentKey)
+		options = append(options, option)
+	}
+
+	for i := 0; err == nil && i < len(s.multiSignKeys); i++ {
+		var k Key
+		if k, err = s.multiSignKeys[i].KeyAccessor.Load(); err == nil {
+			option, err = s.payloadKeyOption(contentType, unencoded, k)
+			options = append(options, option)
 		}
+	}
 
-		signed, err = jws.Sign(
-			p,
-			jws.WithKey(
-				currentKey.Alg,
-				currentKey.Key,
-				jws.WithProtectedHeaders(h),
-			),
-		)
+	if err == nil {
+		if s.MultiSign() {
+			options = append(options, jws.WithJSON())
+		}
+
+		signed, err = jws.Sign(p, options...)
+	}
+
+	if err == nil && s.cosigner != nil {
+		var sig CosignSignature
+		if sig, err = s.cosigner.Cosign(p); err == nil {
+			signed, err = appendSignature(signed, sig)
+		}
 	}
 
 	return
@@ -97,15 +374,27 @@ func (s *Signer) SignPayload(contentType string, p []byte) (signed []byte, err e
 // SignHandler accepts an arbitrary payload and signs it with the current
 // signing key.
 type SignHandler struct {
-	logger *zap.Logger
-	signer *Signer
+	logger      *zap.Logger
+	signer      *Signer
+	contentType string
+
+	// allowEmpty indicates whether empty payloads are signed rather than rejected.
+	allowEmpty bool
 }
 
-func NewSignHandler(l *zap.Logger, s *Signer) *SignHandler {
-	return &SignHandler{
-		logger: l,
-		signer: s,
+func NewSignHandler(l *zap.Logger, s *Signer, cli CLI) *SignHandler {
+	sh := &SignHandler{
+		logger:      l,
+		signer:      s,
+		contentType: "application/jose",
+		allowEmpty:  cli.AllowEmptyPayload,
 	}
+
+	if s.MultiSign() {
+		sh.contentType = "application/jose+json"
+	}
+
+	return sh
 }
 
 func (sh *SignHandler) readPayload(request *http.Request) (payload []byte, err error) {
@@ -128,11 +417,35 @@ func (sh *SignHandler) ServeHTTP(response http.ResponseWriter, request *http.Req
 		return
 	}
 
+	if len(payload) == 0 && !sh.allowEmpty {
+		// an empty payload is almost always a client bug, and some verifiers reject it
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte("the payload to sign must not be empty"))
+		return
+	}
+
+	unencoded := request.URL.Query().Get(B64Parameter) == "false"
+
 	var jws []byte
-	if jws, err = sh.signer.SignPayload(request.Header.Get("Content-Type"), payload); err == nil {
-		response.Header().Set("Content-Type", "application/jose")
+	jws, err = sh.signer.SignPayload(request.Header.Get("Content-Type"), unencoded, payload)
+	switch {
+	case err == nil:
+		response.Header().Set("Content-Type", sh.contentType)
 		response.Write(jws)
-	} else {
+
+	case errors.Is(err, ErrUnencodedPayload):
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(err.Error()))
+
+	case errors.Is(err, ErrCosignerFailed):
+		sh.logger.Error("unable to cosign payload", zap.Error(err))
+		response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		response.WriteHeader(http.StatusBadGateway)
+		response.Write([]byte(err.Error()))
+
+	default:
 		sh.logger.Error("unable to sign payload", zap.Error(err))
 		response.WriteHeader(http.StatusInternalServerError)
 	}
@@ -140,6 +453,7 @@ func (sh *SignHandler) ServeHTTP(response http.ResponseWriter, request *http.Req
 
 func ProvideSigner() fx.Option {
 	return fx.Provide(
+		NewCosigner,
 		NewSigner,
 		NewSignHandler,
 	)
@@ -0,0 +1,515 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignHandlerMultiSign(t *testing.T) {
+	tests := []struct {
+		description         string
+		args                []string
+		expectedContentType string
+		expectedSignatures  int
+	}{
+		{
+			description:         "single key",
+			expectedContentType: "application/jose",
+			expectedSignatures:  1,
+		},
+		{
+			description:         "one multi-sign key",
+			args:                []string{"--multi-sign=RSA"},
+			expectedContentType: "application/jose+json",
+			expectedSignatures:  2,
+		},
+		{
+			description:         "two multi-sign keys",
+			args:                []string{"--multi-sign=RSA,OKP"},
+			expectedContentType: "application/jose+json",
+			expectedSignatures:  3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			h := newTestServer(t, tc.args...)
+
+			response := serve(h, http.MethodPut, "/sign", strings.NewReader(`{"hello":"world"}`), "Content-Type: application/json")
+			require.Equal(t, http.StatusOK, response.Code)
+			assert.Equal(tc.expectedContentType, response.Header().Get("Content-Type"))
+
+			msg, err := jws.Parse(response.Body.Bytes())
+			require.NoError(t, err)
+			assert.Equal(`{"hello":"world"}`, string(msg.Payload()))
+			require.Len(t, msg.Signatures(), tc.expectedSignatures)
+
+			set := publishedKeys(t, h)
+			kids := make(map[string]bool)
+			for _, sig := range msg.Signatures() {
+				kid, ok := sig.ProtectedHeaders().KeyID()
+				require.True(t, ok)
+				assert.False(kids[kid], "duplicate kid %s", kid)
+				kids[kid] = true
+
+				published, ok := set.LookupKeyID(kid)
+				require.True(t, ok, "kid %s is not published", kid)
+
+				alg, _ := sig.ProtectedHeaders().Algorithm()
+				_, err := jws.Verify(response.Body.Bytes(), jws.WithKey(alg, published))
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestSignerJWKSetURL(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expectedJKU string
+	}{
+		{
+			description: "no external URL",
+		},
+		{
+			description: "external URL",
+			args:        []string{"--external-url=https://utu.example.com"},
+			expectedJKU: "https://utu.example.com/keys",
+		},
+		{
+			description: "external URL with a trailing slash",
+			args:        []string{"--external-url=https://utu.example.com/"},
+			expectedJKU: "https://utu.example.com/keys",
+		},
+		{
+			description: "external URL with a path",
+			args:        []string{"--external-url=https://example.com/utu"},
+			expectedJKU: "https://example.com/utu/keys",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			h := newTestServer(t, tc.args...)
+
+			signed := []string{
+				issueToken(t, h, ""),
+				serve(h, http.MethodPut, "/sign", strings.NewReader("payload"), "Content-Type: text/plain").Body.String(),
+			}
+
+			for _, s := range signed {
+				header := tokenHeader(t, s)
+				if len(tc.expectedJKU) > 0 {
+					assert.Equal(tc.expectedJKU, header["jku"])
+				} else {
+					assert.NotContains(header, "jku")
+				}
+			}
+		})
+	}
+}
+
+func TestSignKeyType(t *testing.T) {
+	tests := []struct {
+		description      string
+		args             []string
+		expectedIssueAlg string
+		expectedSignAlg  string
+		expectedKeys     int
+	}{
+		{
+			description:      "same key",
+			expectedIssueAlg: "ES256",
+			expectedSignAlg:  "ES256",
+			expectedKeys:     1,
+		},
+		{
+			description:      "RSA sign key",
+			args:             []string{"--sign-key-type=RSA"},
+			expectedIssueAlg: "ES256",
+			expectedSignAlg:  "RS256",
+			expectedKeys:     2,
+		},
+		{
+			description:      "OKP sign key",
+			args:             []string{"--sign-key-type=OKP"},
+			expectedIssueAlg: "ES256",
+			expectedSignAlg:  "EdDSA",
+			expectedKeys:     2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			h := newTestServer(t, tc.args...)
+
+			issued := issueToken(t, h, "")
+			signed := serve(h, http.MethodPut, "/sign", strings.NewReader("payload"), "Content-Type: text/plain").Body.String()
+			assert.Equal(tc.expectedIssueAlg, tokenHeader(t, issued)["alg"])
+			assert.Equal(tc.expectedSignAlg, tokenHeader(t, signed)["alg"])
+			if tc.expectedKeys > 1 {
+				assert.NotEqual(tokenHeader(t, issued)["kid"], tokenHeader(t, signed)["kid"])
+			}
+
+			set := publishedKeys(t, h)
+			assert.Equal(tc.expectedKeys, set.Len())
+			_, err := jws.Verify([]byte(signed), jws.WithKeySet(set, jws.WithInferAlgorithmFromKey(true)))
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestSignHandlerEmptyPayload(t *testing.T) {
+	tests := []struct {
+		description    string
+		args           []string
+		payload        string
+		expectedStatus int
+	}{
+		{
+			description:    "payload",
+			payload:        "payload",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "empty payload",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:    "allowed empty payload",
+			args:           []string{"--allow-empty-payload"},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newTestServer(t, tc.args...)
+			response := serve(h, http.MethodPut, "/sign", strings.NewReader(tc.payload), "Content-Type: text/plain")
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			message, err := jws.Parse(response.Body.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, tc.payload, string(message.Payload()))
+		})
+	}
+}
+
+func TestIssueKeyTypes(t *testing.T) {
+	tests := []struct {
+		description    string
+		args           []string
+		form           string
+		expectedStatus int
+		expectedAlg    string
+	}{
+		{
+			description:    "primary key type",
+			args:           []string{"--issue-key-types=RSA"},
+			expectedStatus: http.StatusOK,
+			expectedAlg:    "ES256",
+		},
+		{
+			description:    "selected primary key type",
+			args:           []string{"--issue-key-types=RSA"},
+			form:           "key_type=EC",
+			expectedStatus: http.StatusOK,
+			expectedAlg:    "ES256",
+		},
+		{
+			description:    "selected issue key type",
+			args:           []string{"--issue-key-types=RSA"},
+			form:           "key_type=RSA",
+			expectedStatus: http.StatusOK,
+			expectedAlg:    "RS256",
+		},
+		{
+			description:    "unconfigured key type",
+			form:           "key_type=RSA",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:    "unknown key type",
+			args:           []string{"--issue-key-types=RSA"},
+			form:           "key_type=DSA",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			h := newTestServer(t, append([]string{"--admin-token=" + testAdminToken}, tc.args...)...)
+			response := serve(h, http.MethodPost, "/issue", strings.NewReader(tc.form))
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+
+			tokenResponse := serve(h, http.MethodPost, "/token", strings.NewReader("grant_type=client_credentials&"+tc.form), testAdminAuth)
+			require.Equal(t, tc.expectedStatus, tokenResponse.Code, tokenResponse.Body.String())
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			token := response.Body.String()
+			assert.Equal(tc.expectedAlg, tokenHeader(t, token)["alg"])
+
+			var tr TokenResponse
+			require.NoError(t, json.Unmarshal(tokenResponse.Body.Bytes(), &tr))
+			assert.Equal(tc.expectedAlg, tokenHeader(t, tr.AccessToken)["alg"])
+
+			// every selectable key is published, so the token verifies
+			_, err := jws.Verify([]byte(token), jws.WithKeySet(publishedKeys(t, h), jws.WithInferAlgorithmFromKey(true)))
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestSignerMissingCurrentKey(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// keep leaves the current key in the key store.
+		keep        bool
+		expectedErr error
+
+		// expectedRotated indicates that signing rotated to a new current key first.
+		expectedRotated bool
+	}{
+		{
+			description: "current key present",
+			keep:        true,
+		},
+		{
+			description:     "rotate",
+			expectedRotated: true,
+		},
+		{
+			description: "fail",
+			args:        []string{"--missing-current-key=fail"},
+			expectedErr: ErrCurrentKeyMissing,
+		},
+		{
+			description: "ignore",
+			args:        []string{"--missing-current-key=ignore"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				s  *Signer
+				i  *Issuer
+				ka *KeyAccessor
+				ks KeyStore
+			)
+
+			startTestApp(t, tc.args, &s, &i, &ka, &ks)
+			current, err := ka.Load()
+			require.NoError(t, err)
+			if !tc.keep {
+				require.NoError(t, ks.Delete(current.KID))
+			}
+
+			ir, err := newTestIssueRequest(i, "")
+			require.NoError(t, err)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			signed, err := s.SignToken(token)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			kid := tokenHeader(t, string(signed))["kid"]
+			if !tc.expectedRotated {
+				assert.Equal(current.KID, kid)
+				return
+			}
+
+			assert.NotEqual(current.KID, kid)
+			_, err = ks.Load(kid.(string))
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestSignerMissingCurrentKeyConcurrent(t *testing.T) {
+	const signers = 8
+	var (
+		s  *Signer
+		i  *Issuer
+		ka *KeyAccessor
+		ks KeyStore
+	)
+
+	startTestApp(t, nil, &s, &i, &ka, &ks)
+	current, err := ka.Load()
+	require.NoError(t, err)
+	require.NoError(t, ks.Delete(current.KID))
+
+	var (
+		wg     sync.WaitGroup
+		signed = make([][]byte, signers)
+	)
+
+	for n := range signers {
+		ir, err := newTestIssueRequest(i, "")
+		require.NoError(t, err)
+		token, err := i.Issue(ir)
+		require.NoError(t, err)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			signed[n], err = s.SignToken(token)
+			assert.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	// each signer that found the key missing recovered to the same, single rotation
+	keys, err := ks.LoadAll()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	for _, token := range signed {
+		assert.Equal(t, keys[0].KID, tokenHeader(t, string(token))["kid"])
+	}
+}
+
+func TestSignHandlerUnencodedPayload(t *testing.T) {
+	tests := []struct {
+		description       string
+		args              []string
+		target            string
+		payload           string
+		expectedStatus    int
+		expectedUnencoded bool
+	}{
+		{
+			description:    "encoded by default",
+			target:         "/sign",
+			payload:        "payload",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "b64 true",
+			target:         "/sign?b64=true",
+			payload:        "payload",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:       "unencoded",
+			target:            "/sign?b64=false",
+			payload:           "payload",
+			expectedStatus:    http.StatusOK,
+			expectedUnencoded: true,
+		},
+		{
+			description:    "unencoded payload with a period",
+			target:         "/sign?b64=false",
+			payload:        "pay.load",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description:    "unencoded with multi-sign keys",
+			args:           []string{"--multi-sign=RSA"},
+			target:         "/sign?b64=false",
+			payload:        "payload",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			h := newTestServer(t, tc.args...)
+			response := serve(h, http.MethodPut, tc.target, strings.NewReader(tc.payload), "Content-Type: text/plain")
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			signed := response.Body.Bytes()
+			parts := strings.Split(string(signed), ".")
+			require.Len(t, parts, 3)
+
+			header := tokenHeader(t, string(signed))
+			if !tc.expectedUnencoded {
+				assert.NotContains(header, "b64")
+				assert.NotEqual(tc.payload, parts[1])
+				return
+			}
+
+			assert.Equal(false, header["b64"])
+			assert.Equal([]any{"b64"}, header["crit"])
+			assert.Equal(tc.payload, parts[1])
+
+			verified, err := jws.Verify(signed, jws.WithKeySet(publishedKeys(t, h), jws.WithInferAlgorithmFromKey(true)))
+			require.NoError(t, err)
+			assert.Equal(tc.payload, string(verified))
+		})
+	}
+}
+
+func TestSignHandlerCTY(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		contentType string
+		expectedCTY string
+	}{
+		{
+			description: "short form",
+			contentType: "application/json",
+			expectedCTY: "json",
+		},
+		{
+			description: "not an application type",
+			contentType: "text/plain",
+			expectedCTY: "text/plain",
+		},
+		{
+			description: "full",
+			args:        []string{"--sign-full-cty"},
+			contentType: "application/json",
+			expectedCTY: "application/json",
+		},
+		{
+			description: "full, not an application type",
+			args:        []string{"--sign-full-cty"},
+			contentType: "text/plain",
+			expectedCTY: "text/plain",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newTestServer(t, tc.args...)
+			response := serve(h, http.MethodPut, "/sign", strings.NewReader(`{"a":1}`), "Content-Type: "+tc.contentType)
+			require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+			assert.Equal(t, tc.expectedCTY, tokenHeader(t, response.Body.String())[jws.ContentTypeKey])
+		})
+	}
+}
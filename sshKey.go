@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// FormatParameter is the /key request parameter that selects the format of the
+	// rendered key. When unset, the key is rendered as a JWK.
+	FormatParameter = "format"
+
+	// KeyFormatSSH is the key format for OpenSSH authorized_keys lines.
+	KeyFormatSSH = "ssh"
+)
+
+var (
+	// ErrNotSSHKey indicates that a key's type cannot be represented as an SSH public key.
+	ErrNotSSHKey = errors.New("the key cannot be represented as an SSH public key")
+)
+
+// MarshalAuthorizedKey renders the public portion of a key as a single OpenSSH
+// authorized_keys line, with the kid as the comment. Only RSA, Ed25519, and EC keys
+// on the P-256, P-384, and P-521 curves can be rendered.
+func MarshalAuthorizedKey(k Key) (line []byte, err error) {
+	var (
+		public jwk.Key
+		raw    any
+		pk     ssh.PublicKey
+	)
+
+	public, err = k.PublicJWK()
+	if err == nil {
+		err = jwk.Export(public, &raw)
+	}
+
+	if err == nil {
+		if pk, err = ssh.NewPublicKey(raw); err != nil {
+			err = fmt.Errorf("%w: %s: %w", ErrNotSSHKey, public.KeyType(), err)
+		}
+	}
+
+	if err == nil {
+		// MarshalAuthorizedKey terminates the line, so the comment goes before the newline
+		line = bytes.TrimSuffix(ssh.MarshalAuthorizedKey(pk), []byte{'\n'})
+		line = append(line, ' ')
+		line = append(line, k.KID...)
+		line = append(line, '\n')
+	}
+
+	return
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestMarshalAuthorizedKey(t *testing.T) {
+	tests := []struct {
+		description  string
+		args         []string
+		expectedType string
+		expectedErr  error
+	}{
+		{
+			description:  "P-256",
+			expectedType: ssh.KeyAlgoECDSA256,
+		},
+		{
+			description:  "P-384",
+			args:         []string{"--key-curve=P-384"},
+			expectedType: ssh.KeyAlgoECDSA384,
+		},
+		{
+			description:  "P-521",
+			args:         []string{"--key-curve=P-521"},
+			expectedType: ssh.KeyAlgoECDSA521,
+		},
+		{
+			description:  "RSA",
+			args:         []string{"--key-type=RSA"},
+			expectedType: ssh.KeyAlgoRSA,
+		},
+		{
+			description:  "OKP",
+			args:         []string{"--key-type=OKP"},
+			expectedType: ssh.KeyAlgoED25519,
+		},
+		{
+			description: "oct",
+			args:        []string{"--key-type=oct"},
+			expectedErr: ErrNoPublicKey,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			k := newTestKey(t, tc.args...)
+			line, err := MarshalAuthorizedKey(k)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			pk, comment, options, rest, err := ssh.ParseAuthorizedKey(line)
+			require.NoError(t, err)
+			assert.Equal(tc.expectedType, pk.Type())
+			assert.Equal(k.KID, comment)
+			assert.Empty(options)
+			assert.Empty(rest)
+
+			// the line holds the same public key as the published JWK
+			public, err := k.PublicJWK()
+			require.NoError(t, err)
+			var raw any
+			require.NoError(t, jwk.Export(public, &raw))
+			expected, err := ssh.NewPublicKey(raw)
+			require.NoError(t, err)
+			assert.Equal(expected.Marshal(), pk.Marshal())
+		})
+	}
+}
+
+func TestKeyHandlerFormat(t *testing.T) {
+	tests := []struct {
+		description         string
+		args                []string
+		target              func(kid string) string
+		expectedStatus      int
+		expectedContentType string
+	}{
+		{
+			description:         "default format",
+			target:              func(string) string { return "/key" },
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/jwk+json",
+		},
+		{
+			description:         "current key as ssh",
+			target:              func(string) string { return "/key?format=ssh" },
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/plain;charset=utf-8",
+		},
+		{
+			description:         "key by kid as ssh",
+			target:              func(kid string) string { return "/key/" + kid + "?format=ssh" },
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/plain;charset=utf-8",
+		},
+		{
+			description:    "symmetric key as ssh",
+			args:           []string{"--key-type=oct"},
+			target:         func(string) string { return "/key?format=ssh" },
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			description:    "unsupported format",
+			target:         func(string) string { return "/key?format=x509" },
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var (
+				s  *http.Server
+				ka *KeyAccessor
+			)
+
+			startTestApp(t, tc.args, &s, &ka)
+			current, err := ka.Load()
+			require.NoError(t, err)
+
+			response := serve(s.Handler, http.MethodGet, tc.target(current.KID), nil)
+			require.Equal(t, tc.expectedStatus, response.Code, response.Body.String())
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(tc.expectedContentType, response.Header().Get("Content-Type"))
+			if tc.expectedContentType == "text/plain;charset=utf-8" {
+				_, comment, _, _, err := ssh.ParseAuthorizedKey(response.Body.Bytes())
+				require.NoError(t, err)
+				assert.Equal(current.KID, comment)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// GrantTypeClientCredentials is the only OAuth grant type supported by TokenHandler.
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// OAuthError is the RFC 6749 error response body.
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// TokenResponse is the RFC 6749 successful access token response.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// TokenHandler is an OAuth 2.0 token endpoint that supports the client_credentials grant.
+type TokenHandler struct {
+	logger    *zap.Logger
+	issuer    *Issuer
+	signer    *Signer
+	adminAuth *AdminAuth
+	notifier  *PostIssueNotifier
+	dpop      *DPoPVerifier
+}
+
+func NewTokenHandler(l *zap.Logger, issuer *Issuer, signer *Signer, adminAuth *AdminAuth, notifier *PostIssueNotifier, dpop *DPoPVerifier) *TokenHandler {
+	return &TokenHandler{
+		logger:    l,
+		issuer:    issuer,
+		signer:    signer,
+		adminAuth: adminAuth,
+		notifier:  notifier,
+		dpop:      dpop,
+	}
+}
+
+// writeJSON writes an uncacheable JSON response, as required for token endpoints.
+func (th *TokenHandler) writeJSON(response http.ResponseWriter, statusCode int, v any) {
+	data, _ := json.Marshal(v)
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Cache-Control", "no-store")
+	response.Header().Set("Pragma", "no-cache")
+	response.WriteHeader(statusCode)
+	response.Write(data)
+}
+
+func (th *TokenHandler) writeError(response http.ResponseWriter, statusCode int, code, description string) {
+	if statusCode == http.StatusUnauthorized {
+		response.Header().Set("WWW-Authenticate", AdminAuthChallenge)
+	}
+
+	th.writeJSON(response, statusCode, OAuthError{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
+
+// ServeHTTP handles form-encoded token requests.
+func (th *TokenHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if err := request.ParseForm(); err != nil {
+		th.writeError(response, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	grantType := request.PostForm.Get("grant_type")
+	switch {
+	case len(grantType) == 0:
+		th.writeError(response, http.StatusBadRequest, "invalid_request", "grant_type is required")
+		return
+
+	case grantType != GrantTypeClientCredentials:
+		th.writeError(response, http.StatusBadRequest, "unsupported_grant_type", "only client_credentials is supported")
+		return
+
+	case !th.adminAuth.Authenticate(request):
+		th.writeError(response, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	ir, err := th.issuer.NewIssueRequest(request)
+	switch {
+	case errors.Is(err, ErrInvalidResource):
+		th.writeError(response, http.StatusBadRequest, "invalid_target", err.Error())
+		return
+
+	case err != nil:
+		th.writeError(response, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	ir.Scope = request.PostForm.Get(ScopeClaim)
+
+	tokenType := "Bearer"
+	if proof := request.Header.Get(DPoPHeader); len(proof) > 0 {
+		// RFC 9449: a token requested with a DPoP proof is bound to the proof's key
+		if ir.DPoPThumbprint, err = th.dpop.Verify(request, proof); err != nil {
+			th.writeError(response, http.StatusBadRequest, "invalid_dpop_proof", err.Error())
+			return
+		}
+
+		tokenType = DPoPTokenType
+	}
+
+	var signed []byte
+	t, err := th.issuer.Issue(ir)
+	if err == nil {
+		signed, err = th.signer.SignTokenWithKeyType(t, ir.KeyType)
+	}
+
+	if err == nil {
+		err = th.issuer.CheckTokenSize(&ir, signed)
+	}
+
+	switch {
+	case err == nil:
+		th.notifier.Notify(t)
+		writeWarnings(response.Header(), ir.Warnings)
+		th.writeJSON(response, http.StatusOK, TokenResponse{
+			AccessToken: string(signed),
+			TokenType:   tokenType,
+			ExpiresIn:   int64(th.issuer.ExpiresIn(ir).Seconds()),
+			Scope:       ir.Scope,
+		})
+
+	case errors.Is(err, ErrInvalidClaims), errors.Is(err, ErrInvalidIssueRequest):
+		th.writeError(response, http.StatusBadRequest, "invalid_request", err.Error())
+
+	case errors.Is(err, ErrNoAudience):
+		th.writeError(response, http.StatusBadRequest, "invalid_target", err.Error())
+
+	default:
+		th.logger.Error("unable to issue token", zap.Error(err))
+		th.writeError(response, http.StatusInternalServerError, "server_error", err.Error())
+	}
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenHandler(t *testing.T) {
+	tests := []struct {
+		description string
+		body        string
+		headers     []string
+
+		expectedStatus int
+		expectedError  string
+		expectedScope  string
+	}{
+		{
+			description:    "missing grant_type",
+			body:           "scope=read",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			description:    "unsupported grant_type",
+			body:           "grant_type=password",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "unsupported_grant_type",
+		},
+		{
+			description:    "no client authentication",
+			body:           "grant_type=client_credentials",
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+		{
+			description:    "wrong client secret",
+			body:           "grant_type=client_credentials",
+			headers:        []string{"Authorization: Bearer wrong"},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+		{
+			description:    "bearer client secret",
+			body:           "grant_type=client_credentials",
+			headers:        []string{testAdminAuth},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "basic client secret with a scope",
+			body:           "grant_type=client_credentials&scope=read+write",
+			headers:        []string{"Authorization: Basic YzpzM2NyZXQ="},
+			expectedStatus: http.StatusOK,
+			expectedScope:  "read write",
+		},
+	}
+
+	h := newTestServer(t, "--admin-token="+testAdminToken)
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			response := serve(h, http.MethodPost, "/token", strings.NewReader(tc.body), tc.headers...)
+			require.Equal(t, tc.expectedStatus, response.Code)
+			assert.Equal("application/json", response.Header().Get("Content-Type"))
+			assert.Equal("no-store", response.Header().Get("Cache-Control"))
+
+			if tc.expectedStatus != http.StatusOK {
+				var oe OAuthError
+				require.NoError(t, json.Unmarshal(response.Body.Bytes(), &oe))
+				assert.Equal(tc.expectedError, oe.Error)
+				if tc.expectedStatus == http.StatusUnauthorized {
+					assert.Equal(AdminAuthChallenge, response.Header().Get("WWW-Authenticate"))
+				}
+
+				return
+			}
+
+			var tr TokenResponse
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &tr))
+			assert.Equal("Bearer", tr.TokenType)
+			assert.Equal(int64(900), tr.ExpiresIn)
+			assert.Equal(tc.expectedScope, tr.Scope)
+
+			claims := tokenClaims(t, tr.AccessToken)
+			assert.Equal("utu", claims["iss"])
+			if len(tc.expectedScope) > 0 {
+				assert.Equal(tc.expectedScope, claims[ScopeClaim])
+			}
+		})
+	}
+}
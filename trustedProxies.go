@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// TrustedProxies is the set of networks whose forwarding headers are honored.
+// Forwarding headers, such as X-Forwarded-For, sent by any other peer are ignored.
+type TrustedProxies []netip.Prefix
+
+// NewTrustedProxies parses CIDRs, e.g. 10.0.0.0/8, into a TrustedProxies. A bare
+// address is treated as a single host.
+func NewTrustedProxies(cidrs []string) (tp TrustedProxies, err error) {
+	tp = make(TrustedProxies, 0, len(cidrs))
+	for i := 0; err == nil && i < len(cidrs); i++ {
+		var p netip.Prefix
+		if strings.Contains(cidrs[i], "/") {
+			p, err = netip.ParsePrefix(cidrs[i])
+		} else {
+			var a netip.Addr
+			a, err = netip.ParseAddr(cidrs[i])
+			p = netip.PrefixFrom(a, a.BitLen())
+		}
+
+		if err == nil {
+			tp = append(tp, p.Masked())
+		} else {
+			err = fmt.Errorf("invalid trusted proxy [%s]: %w", cidrs[i], err)
+		}
+	}
+
+	return
+}
+
+// Trusts tests if the given address, which may carry a port, is a trusted proxy.
+func (tp TrustedProxies) Trusts(addr string) bool {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	a, err := netip.ParseAddr(strings.TrimSpace(addr))
+	if err != nil {
+		return false
+	}
+
+	a = a.Unmap()
+	for _, p := range tp {
+		if p.Contains(a) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TrustsPeer tests if the direct peer of the given request is a trusted proxy.
+func (tp TrustedProxies) TrustsPeer(request *http.Request) bool {
+	return tp.Trusts(request.RemoteAddr)
+}
+
+// ClientIP determines the address of the client that sent the given request.
+// X-Forwarded-For is only consulted when the direct peer is trusted, in which case
+// the rightmost address that isn't itself a trusted proxy is the client.
+func (tp TrustedProxies) ClientIP(request *http.Request) string {
+	peer := request.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+
+	if !tp.Trusts(peer) {
+		return peer
+	}
+
+	var forwarded []string
+	for _, xff := range request.Header.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(xff, ",") {
+			if addr = strings.TrimSpace(addr); len(addr) > 0 {
+				forwarded = append(forwarded, addr)
+			}
+		}
+	}
+
+	client := peer
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		client = forwarded[i]
+		if !tp.Trusts(client) {
+			break
+		}
+	}
+
+	return client
+}
+
+// isForwardingHeader tests if the given canonical header name is one that proxies
+// use to describe the original request.
+func isForwardingHeader(header string) bool {
+	return header == "Forwarded" || strings.HasPrefix(header, "X-Forwarded-")
+}
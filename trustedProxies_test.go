@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTrustedProxies(t *testing.T) {
+	tests := []struct {
+		description string
+		cidrs       []string
+		expectErr   bool
+	}{
+		{description: "none"},
+		{description: "CIDRs", cidrs: []string{"10.0.0.0/8", "2001:db8::/32"}},
+		{description: "bare addresses", cidrs: []string{"10.0.0.1", "::1"}},
+		{description: "invalid CIDR", cidrs: []string{"10.0.0.0/33"}, expectErr: true},
+		{description: "invalid address", cidrs: []string{"proxy.example.com"}, expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			tp, err := NewTrustedProxies(tc.cidrs)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, tp, len(tc.cidrs))
+			}
+		})
+	}
+}
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	tests := []struct {
+		description string
+		cidrs       []string
+		remoteAddr  string
+		forwarded   []string
+		expected    string
+		expectTrust bool
+	}{
+		{
+			description: "no trusted proxies",
+			remoteAddr:  "10.0.0.1:1234",
+			forwarded:   []string{"198.51.100.7"},
+			expected:    "10.0.0.1",
+		},
+		{
+			description: "untrusted peer",
+			cidrs:       []string{"10.0.0.0/8"},
+			remoteAddr:  "192.0.2.1:1234",
+			forwarded:   []string{"198.51.100.7"},
+			expected:    "192.0.2.1",
+		},
+		{
+			description: "trusted peer",
+			cidrs:       []string{"10.0.0.0/8"},
+			remoteAddr:  "10.0.0.1:1234",
+			forwarded:   []string{"198.51.100.7"},
+			expected:    "198.51.100.7",
+			expectTrust: true,
+		},
+		{
+			description: "trusted peer without forwarding headers",
+			cidrs:       []string{"10.0.0.0/8"},
+			remoteAddr:  "10.0.0.1:1234",
+			expected:    "10.0.0.1",
+			expectTrust: true,
+		},
+		{
+			description: "rightmost untrusted address in a chain",
+			cidrs:       []string{"10.0.0.0/8"},
+			remoteAddr:  "10.0.0.1:1234",
+			forwarded:   []string{"203.0.113.9, 198.51.100.7", "10.0.0.2"},
+			expected:    "198.51.100.7",
+			expectTrust: true,
+		},
+		{
+			description: "IPv4-mapped IPv6 peer",
+			cidrs:       []string{"10.0.0.1"},
+			remoteAddr:  "[::ffff:10.0.0.1]:1234",
+			forwarded:   []string{"198.51.100.7"},
+			expected:    "198.51.100.7",
+			expectTrust: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			tp, err := NewTrustedProxies(tc.cidrs)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/issue", nil)
+			request.RemoteAddr = tc.remoteAddr
+			for _, xff := range tc.forwarded {
+				request.Header.Add("X-Forwarded-For", xff)
+			}
+
+			assert.Equal(t, tc.expectTrust, tp.TrustsPeer(request))
+			assert.Equal(t, tc.expected, tp.ClientIP(request))
+		})
+	}
+}
+
+func TestIssuerForwardingHeaderClaims(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		remoteAddr  string
+		expected    any
+	}{
+		{
+			description: "untrusted peer",
+			args:        []string{"--trusted-proxies=10.0.0.0/8"},
+			remoteAddr:  "192.0.2.1:1234",
+		},
+		{
+			description: "trusted peer",
+			args:        []string{"--trusted-proxies=10.0.0.0/8"},
+			remoteAddr:  "10.0.0.1:1234",
+			expected:    "utu.example.com",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			i, err := newTestIssuer(t, append([]string{"--header-claim=X-Forwarded-Host=forwarded_host", "--header-claim=X-Tenant=tenant"}, tc.args...)...)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/issue", nil)
+			request.RemoteAddr = tc.remoteAddr
+			request.Header.Set("X-Forwarded-Host", "utu.example.com")
+			request.Header.Set("X-Tenant", "acme")
+
+			ir, err := i.NewIssueRequest(request)
+			require.NoError(t, err)
+			token, err := i.Issue(ir)
+			require.NoError(t, err)
+
+			claims := tokenMap(t, token)
+			assert.Equal(t, "acme", claims["tenant"])
+			assert.Equal(t, tc.expected, claims["forwarded_host"])
+		})
+	}
+}
@@ -0,0 +1,309 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrTokenRevoked indicates that a token's jti has been revoked, or that its sid
+	// or sub was revoked after the token was issued.
+	ErrTokenRevoked = errors.New("the token has been revoked")
+
+	// ErrTokenTooOld indicates that a token was issued longer ago than the maximum
+	// token age, regardless of its exp.
+	ErrTokenTooOld = errors.New("the token is older than the maximum token age")
+)
+
+// revocationID produces the BlacklistStore id for revoking a claim value.
+func revocationID(claim, value string) string {
+	return claim + ":" + value
+}
+
+// Verifier verifies tokens against the published key set and the revocations
+// recorded in the BlacklistStore.
+type Verifier struct {
+	logger    *zap.Logger
+	keyStore  KeyStore
+	blacklist BlacklistStore
+	now       func() time.Time
+
+	// claimMap is the Issuer's claim map, which determines the names of the claims
+	// that the Verifier checks.
+	claimMap map[string]string
+
+	// maxTokenAge, when positive, is the oldest iat that a token may have.
+	maxTokenAge time.Duration
+}
+
+func NewVerifier(l *zap.Logger, keyStore KeyStore, blacklist BlacklistStore, cli CLI) *Verifier {
+	return &Verifier{
+		logger:      l,
+		keyStore:    keyStore,
+		blacklist:   blacklist,
+		now:         time.Now,
+		claimMap:    cli.ClaimMap,
+		maxTokenAge: cli.MaxTokenAge,
+	}
+}
+
+func (v *Verifier) publishedSet() (set jwk.Set, err error) {
+	var keys []Key
+	keys, err = v.keyStore.LoadAll()
+	if err == nil {
+		set, err = NewPublicSet(v.logger, keys...)
+	}
+
+	return
+}
+
+// stringClaim returns a string claim of the token, honoring the claim map.
+func (v *Verifier) stringClaim(t jwt.Token, name string) (value string) {
+	t.Get(mappedClaimName(v.claimMap, name), &value)
+	return
+}
+
+// checkTimes validates the time claims that the claim map renames, since jwx only
+// validates time claims under their registered names.
+func (v *Verifier) checkTimes(t jwt.Token) error {
+	now := v.now()
+	for _, name := range [...]string{jwt.ExpirationKey, jwt.NotBeforeKey, jwt.IssuedAtKey} {
+		if _, remapped := v.claimMap[name]; !remapped {
+			continue
+		}
+
+		value, ok := mappedTimeClaim(t, v.claimMap, name)
+		switch {
+		case !ok:
+			// like jwx, absent time claims aren't required
+
+		case name == jwt.ExpirationKey && !now.Before(value):
+			return jwt.TokenExpiredError()
+
+		case name == jwt.NotBeforeKey && now.Before(value):
+			return jwt.TokenNotYetValidError()
+
+		case name == jwt.IssuedAtKey && now.Before(value):
+			return jwt.InvalidIssuedAtError()
+		}
+	}
+
+	return nil
+}
+
+// checkRevoked returns ErrTokenRevoked if the token's jti has been revoked, or if its sid
+// or sub was revoked no earlier than the token's iat. Revoking a sid or sub revokes the
+// tokens issued before the revocation, but not those issued after it.
+func (v *Verifier) checkRevoked(t jwt.Token) error {
+	if jti := v.stringClaim(t, jwt.JwtIDKey); len(jti) > 0 {
+		revoked, err := v.blacklist.Contains(revocationID(jwt.JwtIDKey, jti))
+		switch {
+		case err != nil:
+			return err
+
+		case revoked:
+			return fmt.Errorf("%w: %s=%s", ErrTokenRevoked, jwt.JwtIDKey, jti)
+		}
+	}
+
+	iat, hasIAT := mappedTimeClaim(t, v.claimMap, jwt.IssuedAtKey)
+	for _, name := range [...]string{SessionIDClaim, jwt.SubjectKey} {
+		value := v.stringClaim(t, name)
+		if len(value) == 0 {
+			continue
+		}
+
+		// a token without an iat may have been issued at any time, so it is always revoked
+		revokedAt, revoked, err := v.blacklist.AddedAt(revocationID(name, value))
+		switch {
+		case err != nil:
+			return err
+
+		case revoked && (!hasIAT || !iat.After(revokedAt)):
+			return fmt.Errorf("%w: %s=%s", ErrTokenRevoked, name, value)
+		}
+	}
+
+	return nil
+}
+
+// checkAge returns ErrTokenTooOld if the token was issued longer ago than the maximum
+// token age. When a maximum token age is configured, tokens without an iat are too old,
+// since their age is unknown.
+func (v *Verifier) checkAge(t jwt.Token) error {
+	if v.maxTokenAge <= 0 {
+		return nil
+	}
+
+	iat, ok := mappedTimeClaim(t, v.claimMap, jwt.IssuedAtKey)
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: the token has no iat", ErrTokenTooOld)
+
+	case v.now().Sub(iat) > v.maxTokenAge:
+		return fmt.Errorf("%w: issued at %s", ErrTokenTooOld, iat.UTC().Format(time.RFC3339))
+
+	default:
+		return nil
+	}
+}
+
+// Verify parses and validates the given compact token. The signature must verify
+// with a key in the published set, the time claims must be valid, the token must
+// not exceed any maximum token age, and none of its revocable claims may be revoked.
+// Claims are looked up under the names given by the claim map.
+func (v *Verifier) Verify(token []byte) (t jwt.Token, err error) {
+	var set jwk.Set
+	set, err = v.publishedSet()
+	if err == nil {
+		t, err = jwt.Parse(
+			bytes.TrimSpace(token),
+			jwt.WithKeySet(set, jws.WithInferAlgorithmFromKey(true)),
+			jwt.WithValidate(true),
+		)
+	}
+
+	if err == nil {
+		err = v.checkTimes(t)
+	}
+
+	if err == nil {
+		err = v.checkAge(t)
+	}
+
+	if err == nil {
+		err = v.checkRevoked(t)
+	}
+
+	return
+}
+
+// VerifyHandler verifies a compact token supplied as the request body.
+type VerifyHandler struct {
+	logger   *zap.Logger
+	verifier *Verifier
+
+	// slots, when set, bounds the number of concurrent verifications, since
+	// verification is CPU-bound and could otherwise starve issuance.
+	slots chan struct{}
+
+	// queueTimeout is how long a request waits for a slot before receiving a 429.
+	queueTimeout time.Duration
+}
+
+func NewVerifyHandler(l *zap.Logger, verifier *Verifier, cli CLI) *VerifyHandler {
+	vh := &VerifyHandler{
+		logger:       l,
+		verifier:     verifier,
+		queueTimeout: cli.VerifyQueueTimeout,
+	}
+
+	if cli.VerifyConcurrency > 0 {
+		vh.slots = make(chan struct{}, cli.VerifyConcurrency)
+	}
+
+	return vh
+}
+
+// acquire waits for a verification slot, returning false if none became available
+// within the queue timeout or before the request was canceled. When this method
+// returns true, the caller must invoke release.
+func (vh *VerifyHandler) acquire(request *http.Request) bool {
+	if vh.slots == nil {
+		return true
+	}
+
+	select {
+	case vh.slots <- struct{}{}:
+		return true
+
+	default:
+		if vh.queueTimeout <= 0 {
+			return false
+		}
+	}
+
+	timer := time.NewTimer(vh.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case vh.slots <- struct{}{}:
+		return true
+
+	case <-timer.C:
+		return false
+
+	case <-request.Context().Done():
+		return false
+	}
+}
+
+func (vh *VerifyHandler) release() {
+	if vh.slots != nil {
+		<-vh.slots
+	}
+}
+
+// ServeHTTP responds with the token's claims as JSON if the token verifies.
+// A token that fails verification results in a 401. When the concurrency limit
+// is reached, and no slot frees up within the queue timeout, this handler responds
+// with a 429.
+func (vh *VerifyHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if !vh.acquire(request) {
+		response.Header().Set("Retry-After", "1")
+		response.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	defer vh.release()
+
+	var (
+		t    jwt.Token
+		data []byte
+	)
+
+	token, err := io.ReadAll(request.Body)
+	if err == nil {
+		t, err = vh.verifier.Verify(token)
+		if err != nil {
+			vh.logger.Debug("token failed verification", zap.Error(err))
+			response.Header().Set("Content-Type", "text/plain;charset=utf-8")
+			response.WriteHeader(http.StatusUnauthorized)
+			response.Write([]byte(err.Error()))
+			return
+		}
+	}
+
+	if err == nil {
+		data, err = json.Marshal(t)
+	}
+
+	if err == nil {
+		writeBody(response, "application/json", data)
+	} else {
+		vh.logger.Error("unable to verify token", zap.Error(err))
+		response.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func ProvideVerifier() fx.Option {
+	return fx.Provide(
+		NewVerifier,
+		NewVerifyHandler,
+		NewDecodeHandler,
+	)
+}
@@ -0,0 +1,269 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestVerifierRemappedTimes(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// issuedAt is when, relative to now, the token is issued.
+		issuedAt  time.Duration
+		expectErr bool
+	}{
+		{
+			description: "valid",
+		},
+		{
+			description: "valid with remapped times",
+			args:        []string{"--claim-map=exp=expires_at", "--claim-map=iat=issued_at"},
+		},
+		{
+			description: "expired",
+			issuedAt:    -time.Hour,
+			expectErr:   true,
+		},
+		{
+			description: "expired with a remapped exp",
+			args:        []string{"--claim-map=exp=expires_at"},
+			issuedAt:    -time.Hour,
+			expectErr:   true,
+		},
+		{
+			description: "issued in the future with a remapped iat",
+			args:        []string{"--claim-map=iat=issued_at", "--claim-map=exp=expires_at"},
+			issuedAt:    time.Minute,
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var (
+				i *Issuer
+				s *Signer
+				v *Verifier
+			)
+
+			startTestApp(t, tc.args, &i, &s, &v)
+			i.now = func() time.Time { return time.Now().Add(tc.issuedAt) }
+
+			token, err := i.Issue(IssueRequest{})
+			require.NoError(t, err)
+			signed, err := s.SignToken(token)
+			require.NoError(t, err)
+
+			_, err = v.Verify(signed)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifierMaxTokenAge(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// issuedAt is when, relative to now, the token is issued. When omitIAT is
+		// set, the token has no iat at all.
+		issuedAt    time.Duration
+		omitIAT     bool
+		expectedErr error
+	}{
+		{
+			description: "no limit",
+			issuedAt:    -10 * time.Minute,
+		},
+		{
+			description: "within the limit",
+			args:        []string{"--max-token-age=5m"},
+			issuedAt:    -4 * time.Minute,
+		},
+		{
+			description: "older than the limit",
+			args:        []string{"--max-token-age=5m"},
+			issuedAt:    -10 * time.Minute,
+			expectedErr: ErrTokenTooOld,
+		},
+		{
+			description: "no iat without a limit",
+			omitIAT:     true,
+		},
+		{
+			description: "no iat with a limit",
+			args:        []string{"--max-token-age=5m"},
+			omitIAT:     true,
+			expectedErr: ErrTokenTooOld,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var (
+				i *Issuer
+				s *Signer
+				v *Verifier
+			)
+
+			startTestApp(t, tc.args, &i, &s, &v)
+			i.now = func() time.Time { return time.Now().Add(tc.issuedAt) }
+
+			token, err := i.Issue(IssueRequest{})
+			require.NoError(t, err)
+			if tc.omitIAT {
+				require.NoError(t, token.Remove(jwt.IssuedAtKey))
+			}
+
+			signed, err := s.SignToken(token)
+			require.NoError(t, err)
+
+			_, err = v.Verify(signed)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyHandlerAcquire(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+
+		// inUse is the number of slots already taken before acquiring.
+		inUse int
+
+		// releaseAfter, when positive, frees a slot after that long.
+		releaseAfter time.Duration
+		canceled     bool
+		expected     bool
+	}{
+		{
+			description: "unlimited",
+			expected:    true,
+		},
+		{
+			description: "free slot",
+			args:        []string{"--verify-concurrency=2"},
+			inUse:       1,
+			expected:    true,
+		},
+		{
+			description: "full without a queue timeout",
+			args:        []string{"--verify-concurrency=1"},
+			inUse:       1,
+			expected:    false,
+		},
+		{
+			description:  "full until a slot is released",
+			args:         []string{"--verify-concurrency=1", "--verify-queue-timeout=10s"},
+			inUse:        1,
+			releaseAfter: 10 * time.Millisecond,
+			expected:     true,
+		},
+		{
+			description: "full past the queue timeout",
+			args:        []string{"--verify-concurrency=1", "--verify-queue-timeout=10ms"},
+			inUse:       1,
+			expected:    false,
+		},
+		{
+			description: "canceled while queued",
+			args:        []string{"--verify-concurrency=1", "--verify-queue-timeout=10s"},
+			inUse:       1,
+			canceled:    true,
+			expected:    false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			vh := NewVerifyHandler(zap.NewNop(), nil, newTestCLI(t, tc.args...))
+			for range tc.inUse {
+				require.True(t, vh.acquire(httptest.NewRequest(http.MethodPost, "/verify", nil)))
+			}
+
+			if tc.releaseAfter > 0 {
+				time.AfterFunc(tc.releaseAfter, vh.release)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.canceled {
+				cancel()
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/verify", nil).WithContext(ctx)
+			assert.Equal(t, tc.expected, vh.acquire(request))
+		})
+	}
+}
+
+func TestVerifyHandlerTooManyRequests(t *testing.T) {
+	vh := NewVerifyHandler(zap.NewNop(), nil, newTestCLI(t, "--verify-concurrency=1"))
+	require.True(t, vh.acquire(httptest.NewRequest(http.MethodPost, "/verify", nil)))
+
+	response := serve(vh, http.MethodPost, "/verify", strings.NewReader("token"))
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "1", response.Header().Get("Retry-After"))
+
+	// with a free slot, the request reaches verification
+	h := newTestServer(t, "--verify-concurrency=1")
+	response = serve(h, http.MethodPost, "/verify", strings.NewReader(issueToken(t, h, "")))
+	assert.Equal(t, http.StatusOK, response.Code, response.Body.String())
+}
+
+func TestVerifyHandlerKeyTypes(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		expectedAlg string
+	}{
+		{
+			description: "EC",
+			expectedAlg: "ES256",
+		},
+		{
+			description: "RSA",
+			args:        []string{"--key-type=RSA"},
+			expectedAlg: "RS256",
+		},
+		{
+			description: "OKP",
+			args:        []string{"--key-type=OKP"},
+			expectedAlg: "EdDSA",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newTestServer(t, tc.args...)
+			token := issueToken(t, h, "")
+			assert.Equal(t, tc.expectedAlg, tokenHeader(t, token)["alg"])
+
+			response := serve(h, http.MethodPost, "/verify", strings.NewReader(token))
+			assert.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		})
+	}
+}